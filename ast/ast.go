@@ -9,6 +9,26 @@ import (
 type Node interface {
 	TokenLiteral() string
 	String() string
+	// Pos is the position of the first character of the node and End
+	// the position just past its last one. Both are zero for nodes
+	// that were not produced by the parser.
+	Pos() token.Position
+	End() token.Position
+}
+
+// Span records the source range of a node. It is embedded in every
+// node and filled in by the parser.
+type Span struct {
+	From token.Position `json:"from"`
+	To   token.Position `json:"to"`
+}
+
+func (s *Span) Pos() token.Position { return s.From }
+func (s *Span) End() token.Position { return s.To }
+
+// SetSpan sets the range covered by the node.
+func (s *Span) SetSpan(from, to token.Position) {
+	s.From, s.To = from, to
 }
 
 type Statment interface {
@@ -22,7 +42,11 @@ type Expression interface {
 }
 
 type Program struct {
-	Statments []Statment
+	Statments []Statment `json:"statements"`
+	// Comments holds the comments of the source in order when the lexer
+	// was created with lexer.WithComments. They are not part of the
+	// tree, Walk and Modify do not visit them.
+	Comments []*Comment `json:"comments"`
 }
 
 func (p *Program) TokenLiteral() string {
@@ -32,6 +56,20 @@ func (p *Program) TokenLiteral() string {
 	return ""
 }
 
+func (p *Program) Pos() token.Position {
+	if len(p.Statments) > 0 {
+		return p.Statments[0].Pos()
+	}
+	return token.Position{}
+}
+
+func (p *Program) End() token.Position {
+	if len(p.Statments) > 0 {
+		return p.Statments[len(p.Statments)-1].End()
+	}
+	return token.Position{}
+}
+
 func (p *Program) String() string {
 	buf := strings.Builder{}
 	for _, v := range p.Statments {
@@ -40,22 +78,44 @@ func (p *Program) String() string {
 	return buf.String()
 }
 
+// Comment is a // line comment, its literal is the comment text
+// including the slashes.
+type Comment struct {
+	Token token.Token `json:"token"`
+	Span  `json:"span"`
+}
+
+func (c *Comment) TokenLiteral() string { return c.Token.Literal }
+func (c *Comment) String() string       { return c.Token.Literal }
+
 type LetStatment struct {
-	Token token.Token
-	Name  *Identifier
-	Value Expression
+	Token token.Token `json:"token"`
+	Span  `json:"span"`
+	Name  *Identifier `json:"name"`
+	// Type is the optional annotation in let x: int = 5, nil if absent.
+	Type  TypeExpr   `json:"type"`
+	Value Expression `json:"value"`
 }
 
 func (*LetStatment) statementNode()         {}
 func (l *LetStatment) TokenLiteral() string { return l.Token.Literal }
 func (l *LetStatment) String() string {
-	return fmt.Sprintf("%s %s = %s;",
-		l.TokenLiteral(), l.Name.Value, l.Value.String())
+	return fmt.Sprintf("%s %s%s = %s;",
+		l.TokenLiteral(), l.Name.Value, annotation(l.Type), l.Value.String())
+}
+
+// annotation returns ": T" for a type annotation, "" for none.
+func annotation(t TypeExpr) string {
+	if t == nil {
+		return ""
+	}
+	return ": " + t.String()
 }
 
 type Identifier struct {
-	Token token.Token
-	Value string
+	Token token.Token `json:"token"`
+	Span  `json:"span"`
+	Value string `json:"value"`
 }
 
 func (*Identifier) expressionNode()        {}
@@ -65,8 +125,9 @@ func (i *Identifier) String() string {
 }
 
 type ReturnStatment struct {
-	Token       token.Token
-	ReturnValue Expression
+	Token       token.Token `json:"token"`
+	Span        `json:"span"`
+	ReturnValue Expression `json:"returnValue"`
 }
 
 func (r *ReturnStatment) statementNode()       {}
@@ -77,8 +138,9 @@ func (r *ReturnStatment) String() string {
 }
 
 type ExpressionStatment struct {
-	Token      token.Token
-	Expression Expression
+	Token      token.Token `json:"token"`
+	Span       `json:"span"`
+	Expression Expression `json:"expression"`
 }
 
 func (e *ExpressionStatment) statementNode()       {}
@@ -91,8 +153,9 @@ func (e *ExpressionStatment) String() string {
 }
 
 type IntegerLiteral struct {
-	Token token.Token
-	Value int64
+	Token token.Token `json:"token"`
+	Span  `json:"span"`
+	Value int64 `json:"value"`
 }
 
 func (i *IntegerLiteral) expressionNode()      {}
@@ -101,10 +164,21 @@ func (i *IntegerLiteral) String() string {
 	return i.Token.Literal
 }
 
+type StringLiteral struct {
+	Token token.Token `json:"token"`
+	Span  `json:"span"`
+	Value string `json:"value"`
+}
+
+func (s *StringLiteral) expressionNode()      {}
+func (s *StringLiteral) TokenLiteral() string { return s.Token.Literal }
+func (s *StringLiteral) String() string       { return s.Token.Literal }
+
 type PrefixExpression struct {
-	Token    token.Token
-	Operator string
-	Right    Expression
+	Token    token.Token `json:"token"`
+	Span     `json:"span"`
+	Operator string     `json:"operator"`
+	Right    Expression `json:"right"`
 }
 
 func (pe *PrefixExpression) expressionNode()      {}
@@ -119,10 +193,11 @@ func (pe *PrefixExpression) String() string {
 }
 
 type InfixExpression struct {
-	Token    token.Token
-	Left     Expression
-	Operator string
-	Right    Expression
+	Token    token.Token `json:"token"`
+	Span     `json:"span"`
+	Left     Expression `json:"left"`
+	Operator string     `json:"operator"`
+	Right    Expression `json:"right"`
 }
 
 func (ie *InfixExpression) expressionNode()      {}
@@ -133,9 +208,67 @@ func (ie *InfixExpression) String() string {
 		ie.Left, ie.Operator, ie.Right)
 }
 
+type PostfixExpression struct {
+	Token    token.Token `json:"token"`
+	Span     `json:"span"`
+	Left     Expression `json:"left"`
+	Operator string     `json:"operator"`
+}
+
+func (pe *PostfixExpression) expressionNode()      {}
+func (pe *PostfixExpression) TokenLiteral() string { return pe.Token.Literal }
+func (pe *PostfixExpression) String() string {
+	return fmt.Sprintf("(%s%s)", pe.Left, pe.Operator)
+}
+
+type AssignExpression struct {
+	Token    token.Token `json:"token"`
+	Span     `json:"span"`
+	Target   Expression `json:"target"`
+	Operator string     `json:"operator"`
+	Value    Expression `json:"value"`
+}
+
+func (ae *AssignExpression) expressionNode()      {}
+func (ae *AssignExpression) TokenLiteral() string { return ae.Token.Literal }
+func (ae *AssignExpression) String() string {
+	return fmt.Sprintf("(%s%s%s)", ae.Target, ae.Operator, ae.Value)
+}
+
+type IndexExpression struct {
+	Token token.Token `json:"token"`
+	Span  `json:"span"`
+	Left  Expression `json:"left"`
+	Index Expression `json:"index"`
+}
+
+func (ie *IndexExpression) expressionNode()      {}
+func (ie *IndexExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IndexExpression) String() string {
+	return fmt.Sprintf("(%s[%s])", ie.Left, ie.Index)
+}
+
+type CallExpression struct {
+	Token     token.Token `json:"token"`
+	Span      `json:"span"`
+	Function  Expression   `json:"function"`
+	Arguments []Expression `json:"arguments"`
+}
+
+func (ce *CallExpression) expressionNode()      {}
+func (ce *CallExpression) TokenLiteral() string { return ce.Token.Literal }
+func (ce *CallExpression) String() string {
+	args := make([]string, 0, len(ce.Arguments))
+	for _, a := range ce.Arguments {
+		args = append(args, a.String())
+	}
+	return fmt.Sprintf("%s(%s)", ce.Function, strings.Join(args, ", "))
+}
+
 type Boolean struct {
-	Token token.Token
-	Value bool
+	Token token.Token `json:"token"`
+	Span  `json:"span"`
+	Value bool `json:"value"`
 }
 
 func (b *Boolean) expressionNode()      {}
@@ -143,8 +276,216 @@ func (b *Boolean) TokenLiteral() string { return b.Token.Literal }
 func (b *Boolean) String() string       { return b.Token.Literal }
 
 type IfExpression struct {
-	Token       token.Token
-	Condition   Expression
-	Consequence *BlockStatment
-	Alternative *BlockStatment
+	Token       token.Token `json:"token"`
+	Span        `json:"span"`
+	Condition   Expression     `json:"condition"`
+	Consequence *BlockStatment `json:"consequence"`
+	Alternative *BlockStatment `json:"alternative"`
+}
+
+func (ie *IfExpression) expressionNode()      {}
+func (ie *IfExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IfExpression) String() string {
+	buf := strings.Builder{}
+	buf.WriteString("if")
+	buf.WriteString(ie.Condition.String())
+	buf.WriteString(" ")
+	buf.WriteString(ie.Consequence.String())
+	if ie.Alternative != nil {
+		buf.WriteString("else ")
+		buf.WriteString(ie.Alternative.String())
+	}
+	return buf.String()
+}
+
+type FunctionLiteral struct {
+	Token      token.Token `json:"token"`
+	Span       `json:"span"`
+	Parameters []*Identifier `json:"parameters"`
+	// ParamTypes is either empty or holds the annotation of each
+	// parameter, nil for the ones without.
+	ParamTypes []TypeExpr `json:"paramTypes"`
+	// ReturnType is the annotation after ->, nil if absent.
+	ReturnType TypeExpr       `json:"returnType"`
+	Body       *BlockStatment `json:"body"`
+}
+
+func (fl *FunctionLiteral) expressionNode()      {}
+func (fl *FunctionLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FunctionLiteral) String() string {
+	params := make([]string, 0, len(fl.Parameters))
+	for i, p := range fl.Parameters {
+		params = append(params, p.String()+annotation(fl.ParamType(i)))
+	}
+	buf := strings.Builder{}
+	buf.WriteString(fl.TokenLiteral())
+	buf.WriteString("(")
+	buf.WriteString(strings.Join(params, ", "))
+	buf.WriteString(") ")
+	if fl.ReturnType != nil {
+		buf.WriteString("-> " + fl.ReturnType.String() + " ")
+	}
+	buf.WriteString(fl.Body.String())
+	return buf.String()
+}
+
+// ParamType returns the annotation of the i'th parameter, or nil.
+func (fl *FunctionLiteral) ParamType(i int) TypeExpr {
+	if i < len(fl.ParamTypes) {
+		return fl.ParamTypes[i]
+	}
+	return nil
+}
+
+// TypeExpr is a type annotation.
+type TypeExpr interface {
+	Node
+	typeNode()
+}
+
+// NamedType is a type written as a name, such as int.
+type NamedType struct {
+	Token token.Token `json:"token"`
+	Span  `json:"span"`
+	Name  string `json:"name"`
+}
+
+func (*NamedType) typeNode()               {}
+func (nt *NamedType) TokenLiteral() string { return nt.Token.Literal }
+func (nt *NamedType) String() string       { return nt.Name }
+
+// FunctionType is the type of a function, fn(int, int) -> int.
+type FunctionType struct {
+	Token  token.Token `json:"token"`
+	Span   `json:"span"`
+	Params []TypeExpr `json:"params"`
+	// Result is nil for fn(int) without a result type.
+	Result TypeExpr `json:"result"`
+}
+
+func (*FunctionType) typeNode()               {}
+func (ft *FunctionType) TokenLiteral() string { return ft.Token.Literal }
+func (ft *FunctionType) String() string {
+	params := make([]string, 0, len(ft.Params))
+	for _, p := range ft.Params {
+		params = append(params, p.String())
+	}
+	s := fmt.Sprintf("%s(%s)", ft.TokenLiteral(), strings.Join(params, ", "))
+	if ft.Result != nil {
+		s += " -> " + ft.Result.String()
+	}
+	return s
+}
+
+type BlockStatment struct {
+	Token     token.Token `json:"token"`
+	Span      `json:"span"`
+	Statments []Statment `json:"statements"`
+}
+
+func (bs *BlockStatment) statementNode()       {}
+func (bs *BlockStatment) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BlockStatment) String() string {
+	buf := strings.Builder{}
+	for _, s := range bs.Statments {
+		buf.WriteString(s.String())
+	}
+	return buf.String()
+}
+
+type WhileStatment struct {
+	Token     token.Token `json:"token"`
+	Span      `json:"span"`
+	Label     *Identifier    `json:"label"`
+	Condition Expression     `json:"condition"`
+	Body      *BlockStatment `json:"body"`
+}
+
+func (ws *WhileStatment) statementNode()       {}
+func (ws *WhileStatment) TokenLiteral() string { return ws.Token.Literal }
+func (ws *WhileStatment) String() string {
+	return fmt.Sprintf("%swhile %s %s", labelString(ws.Label), ws.Condition, ws.Body)
+}
+
+// ForStatment is the C-style for (init; cond; post) loop. Any of the
+// three clauses may be nil.
+type ForStatment struct {
+	Token     token.Token `json:"token"`
+	Span      `json:"span"`
+	Label     *Identifier    `json:"label"`
+	Init      Statment       `json:"init"`
+	Condition Expression     `json:"condition"`
+	Post      Expression     `json:"post"`
+	Body      *BlockStatment `json:"body"`
+}
+
+func (fs *ForStatment) statementNode()       {}
+func (fs *ForStatment) TokenLiteral() string { return fs.Token.Literal }
+func (fs *ForStatment) String() string {
+	clauses := make([]string, 3)
+	if fs.Init != nil {
+		clauses[0] = strings.TrimSuffix(fs.Init.String(), ";")
+	}
+	if fs.Condition != nil {
+		clauses[1] = fs.Condition.String()
+	}
+	if fs.Post != nil {
+		clauses[2] = fs.Post.String()
+	}
+	return fmt.Sprintf("%sfor (%s) %s", labelString(fs.Label), strings.Join(clauses, "; "), fs.Body)
+}
+
+type ForInStatment struct {
+	Token    token.Token `json:"token"`
+	Span     `json:"span"`
+	Label    *Identifier    `json:"label"`
+	Variable *Identifier    `json:"variable"`
+	Iterable Expression     `json:"iterable"`
+	Body     *BlockStatment `json:"body"`
+}
+
+func (fs *ForInStatment) statementNode()       {}
+func (fs *ForInStatment) TokenLiteral() string { return fs.Token.Literal }
+func (fs *ForInStatment) String() string {
+	return fmt.Sprintf("%sfor (%s in %s) %s", labelString(fs.Label), fs.Variable, fs.Iterable, fs.Body)
+}
+
+// BreakStatment leaves the innermost loop, or the loop named by Label.
+type BreakStatment struct {
+	Token token.Token `json:"token"`
+	Span  `json:"span"`
+	Label *Identifier `json:"label"`
+}
+
+func (bs *BreakStatment) statementNode()       {}
+func (bs *BreakStatment) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BreakStatment) String() string {
+	if bs.Label != nil {
+		return fmt.Sprintf("%s %s;", bs.TokenLiteral(), bs.Label)
+	}
+	return bs.TokenLiteral() + ";"
+}
+
+// ContinueStatment starts the next iteration of the innermost loop,
+// or of the loop named by Label.
+type ContinueStatment struct {
+	Token token.Token `json:"token"`
+	Span  `json:"span"`
+	Label *Identifier `json:"label"`
+}
+
+func (cs *ContinueStatment) statementNode()       {}
+func (cs *ContinueStatment) TokenLiteral() string { return cs.Token.Literal }
+func (cs *ContinueStatment) String() string {
+	if cs.Label != nil {
+		return fmt.Sprintf("%s %s;", cs.TokenLiteral(), cs.Label)
+	}
+	return cs.TokenLiteral() + ";"
+}
+
+func labelString(label *Identifier) string {
+	if label == nil {
+		return ""
+	}
+	return label.Value + ": "
 }
@@ -0,0 +1,133 @@
+package ast
+
+import (
+	"reflect"
+
+	"gointer/token"
+)
+
+var (
+	tokenType   = reflect.TypeOf(token.Token{})
+	spanType    = reflect.TypeOf(Span{})
+	programType = reflect.TypeOf(Program{})
+)
+
+type equalConfig struct {
+	ignoreTokens bool
+}
+
+type EqualOption func(*equalConfig)
+
+// IgnoreTokens makes Equal skip the tokens of nodes, so that a tree
+// built by hand without tokens equals the parsed one.
+func IgnoreTokens() EqualOption {
+	return func(c *equalConfig) {
+		c.ignoreTokens = true
+	}
+}
+
+// Equal reports whether a and b are trees of the same shape with the
+// same values. Positions and spans are not compared, nor are the
+// comments of a Program, and tokens only by type and literal.
+func Equal(a, b Node, opts ...EqualOption) bool {
+	c := equalConfig{}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c.equalNodes(reflect.ValueOf(a), reflect.ValueOf(b))
+}
+
+func (c *equalConfig) equalNodes(a, b reflect.Value) bool {
+	if a.Kind() == reflect.Interface {
+		a = a.Elem()
+	}
+	if b.Kind() == reflect.Interface {
+		b = b.Elem()
+	}
+	if !a.IsValid() || a.IsNil() || !b.IsValid() || b.IsNil() {
+		return (!a.IsValid() || a.IsNil()) && (!b.IsValid() || b.IsNil())
+	}
+	if a.Type() != b.Type() {
+		return false
+	}
+	a, b = a.Elem(), b.Elem()
+	for i := 0; i < a.NumField(); i++ {
+		if a.Type() == programType && a.Type().Field(i).Name == "Comments" {
+			continue
+		}
+		if !c.equalFields(a.Field(i), b.Field(i)) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *equalConfig) equalFields(a, b reflect.Value) bool {
+	switch {
+	case a.Type() == spanType:
+		return true
+	case a.Type() == tokenType:
+		if c.ignoreTokens {
+			return true
+		}
+		at, bt := a.Interface().(token.Token), b.Interface().(token.Token)
+		return at.Type == bt.Type && at.Literal == bt.Literal
+	case a.Type().Implements(nodeType):
+		return c.equalNodes(a, b)
+	case a.Kind() == reflect.Slice:
+		if a.Len() != b.Len() {
+			return false
+		}
+		for i := 0; i < a.Len(); i++ {
+			if !c.equalFields(a.Index(i), b.Index(i)) {
+				return false
+			}
+		}
+		return true
+	default:
+		return a.Equal(b)
+	}
+}
+
+// Clone returns a deep copy of node that shares no nodes with it.
+func Clone[T Node](node T) T {
+	v := cloneValue(reflect.ValueOf(&node).Elem())
+	return v.Interface().(T)
+}
+
+func cloneValue(v reflect.Value) reflect.Value {
+	switch {
+	case v.Kind() == reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type()).Elem()
+		c.Set(cloneValue(v.Elem()))
+		return c
+	case v.Kind() == reflect.Pointer:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type().Elem())
+		c.Elem().Set(cloneValue(v.Elem()))
+		return c
+	case v.Kind() == reflect.Struct:
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			c.Field(i).Set(cloneValue(v.Field(i)))
+		}
+		return c
+	case v.Kind() == reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(cloneValue(v.Index(i)))
+		}
+		return c
+	default:
+		return v
+	}
+}
@@ -0,0 +1,100 @@
+package ast
+
+import (
+	"testing"
+
+	"gointer/token"
+)
+
+func TestEqualSamples(t *testing.T) {
+	for name, n := range nodeSamples() {
+		if !Equal(n, nodeSamples()[name]) {
+			t.Errorf("%s does not equal itself", name)
+		}
+		if !Equal(n, Clone(n)) {
+			t.Errorf("%s does not equal its clone", name)
+		}
+	}
+	samples := nodeSamples()
+	if Equal(samples["Identifier"], samples["IntegerLiteral"]) {
+		t.Errorf("Identifier equals IntegerLiteral")
+	}
+}
+
+func TestEqual(t *testing.T) {
+	moved := infix(ident("a"), "+", intLit(1))
+	moved.SetSpan(token.Position{Offset: 4, Line: 2, Column: 1}, token.Position{Offset: 9, Line: 2, Column: 6})
+	moved.Left.(*Identifier).Token.Pos = token.Position{Offset: 4, Line: 2, Column: 1}
+
+	bare := &InfixExpression{
+		Left:     &Identifier{Value: "a"},
+		Operator: "+",
+		Right:    &IntegerLiteral{Value: 1},
+	}
+
+	tests := []struct {
+		a, b     Node
+		opts     []EqualOption
+		expected bool
+	}{
+		{infix(ident("a"), "+", intLit(1)), moved, nil, true},
+		{infix(ident("a"), "+", intLit(1)), infix(ident("a"), "+", intLit(2)), nil, false},
+		{infix(ident("a"), "+", intLit(1)), infix(ident("b"), "+", intLit(1)), nil, false},
+		{infix(ident("a"), "+", intLit(1)), infix(ident("a"), "-", intLit(1)), nil, false},
+		{infix(ident("a"), "+", intLit(1)), bare, nil, false},
+		{infix(ident("a"), "+", intLit(1)), bare, []EqualOption{IgnoreTokens()}, true},
+		{&ExpressionStatment{}, &ExpressionStatment{}, nil, true},
+		{&ExpressionStatment{Expression: ident("a")}, &ExpressionStatment{}, nil, false},
+		{block(exprStmt(ident("a"))), block(exprStmt(ident("a")), exprStmt(ident("a"))), nil, false},
+		{
+			&Program{Statments: []Statment{exprStmt(ident("a"))}},
+			&Program{
+				Statments: []Statment{exprStmt(ident("a"))},
+				Comments:  []*Comment{{Token: token.Token{Type: token.COMMENT, Literal: "// a"}}},
+			},
+			nil, true,
+		},
+	}
+
+	for i, tt := range tests {
+		if got := Equal(tt.a, tt.b, tt.opts...); got != tt.expected {
+			t.Errorf("tests[%d] - Equal(%s, %s) = %t, want %t", i, tt.a, tt.b, got, tt.expected)
+		}
+	}
+}
+
+// TestCloneShares fails when Clone copies a pointer to a node instead
+// of the node.
+func TestCloneShares(t *testing.T) {
+	for name, n := range nodeSamples() {
+		seen := map[Node]bool{}
+		Inspect(n, func(n Node) bool {
+			if n != nil {
+				seen[n] = true
+			}
+			return true
+		})
+		Inspect(Clone(n), func(n Node) bool {
+			if n != nil && seen[n] {
+				t.Errorf("clone of %s shares %T %s", name, n, n)
+			}
+			return true
+		})
+	}
+}
+
+func TestCloneType(t *testing.T) {
+	var e Expression = infix(ident("a"), "+", intLit(1))
+	c := Clone(e)
+	c.(*InfixExpression).Left.(*Identifier).Value = "b"
+	if e.String() != "(a+1)" || c.String() != "(b+1)" {
+		t.Errorf("clone is not independent. original=%s, clone=%s", e, c)
+	}
+
+	block := block(exprStmt(ident("a")))
+	var cb *BlockStatment = Clone(block)
+	cb.Statments = append(cb.Statments, exprStmt(ident("b")))
+	if len(block.Statments) != 1 {
+		t.Errorf("clone shares the statement list")
+	}
+}
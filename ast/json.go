@@ -0,0 +1,207 @@
+package ast
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// nodeKinds maps the "kind" of a JSON encoded node to a node of its
+// type. The kinds are spelled out rather than taken from the type names,
+// so that the format does not change when a type is renamed. Every node
+// type must be listed here to round-trip through JSON.
+var nodeKinds = map[string]Node{
+	"Program":             &Program{},
+	"Comment":             &Comment{},
+	"LetStatement":        &LetStatment{},
+	"ReturnStatement":     &ReturnStatment{},
+	"ExpressionStatement": &ExpressionStatment{},
+	"BlockStatement":      &BlockStatment{},
+	"WhileStatement":      &WhileStatment{},
+	"ForStatement":        &ForStatment{},
+	"ForInStatement":      &ForInStatment{},
+	"BreakStatement":      &BreakStatment{},
+	"ContinueStatement":   &ContinueStatment{},
+	"Identifier":          &Identifier{},
+	"IntegerLiteral":      &IntegerLiteral{},
+	"StringLiteral":       &StringLiteral{},
+	"Boolean":             &Boolean{},
+	"PrefixExpression":    &PrefixExpression{},
+	"InfixExpression":     &InfixExpression{},
+	"PostfixExpression":   &PostfixExpression{},
+	"AssignExpression":    &AssignExpression{},
+	"IndexExpression":     &IndexExpression{},
+	"CallExpression":      &CallExpression{},
+	"IfExpression":        &IfExpression{},
+	"FunctionLiteral":     &FunctionLiteral{},
+	"NamedType":           &NamedType{},
+	"FunctionType":        &FunctionType{},
+}
+
+var (
+	// nodeTypes maps kinds to node types and kinds the other way.
+	nodeTypes = map[string]reflect.Type{}
+	kinds     = map[reflect.Type]string{}
+)
+
+func init() {
+	for kind, n := range nodeKinds {
+		t := reflect.TypeOf(n).Elem()
+		nodeTypes[kind] = t
+		kinds[t] = kind
+	}
+}
+
+var nodeType = reflect.TypeOf((*Node)(nil)).Elem()
+
+// MarshalJSON encodes a syntax tree as JSON. Each node is an object
+// whose "kind" names its type, followed by its fields in declaration
+// order under the keys of their json tags: "token", "span", child
+// nodes as objects, lists of nodes as arrays and unset children as
+// null.
+func MarshalJSON(node Node) ([]byte, error) {
+	buf := bytes.Buffer{}
+	if err := encodeNode(&buf, reflect.ValueOf(node)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON decodes a tree encoded with MarshalJSON.
+func UnmarshalJSON(data []byte) (Node, error) {
+	v, err := decodeNode(data)
+	if err != nil {
+		return nil, err
+	}
+	if !v.IsValid() {
+		return nil, nil
+	}
+	return v.Interface().(Node), nil
+}
+
+func encodeNode(buf *bytes.Buffer, v reflect.Value) error {
+	if v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	if !v.IsValid() || v.IsNil() {
+		buf.WriteString("null")
+		return nil
+	}
+	s := v.Elem()
+	kind, ok := kinds[s.Type()]
+	if !ok {
+		return fmt.Errorf("ast: cannot encode node type %s", v.Type())
+	}
+	fmt.Fprintf(buf, `{"kind":%q`, kind)
+	for i := 0; i < s.NumField(); i++ {
+		f := s.Type().Field(i)
+		key := fieldKey(f)
+		if key == "" {
+			return fmt.Errorf("ast: field %s.%s has no json key", kind, f.Name)
+		}
+		fmt.Fprintf(buf, ",%q:", key)
+		if err := encodeField(buf, s.Field(i)); err != nil {
+			return err
+		}
+	}
+	buf.WriteString("}")
+	return nil
+}
+
+func encodeField(buf *bytes.Buffer, f reflect.Value) error {
+	switch {
+	case f.Type().Implements(nodeType):
+		return encodeNode(buf, f)
+	case f.Kind() == reflect.Slice && f.Type().Elem().Implements(nodeType):
+		if f.IsNil() {
+			buf.WriteString("null")
+			return nil
+		}
+		buf.WriteString("[")
+		for i := 0; i < f.Len(); i++ {
+			if i > 0 {
+				buf.WriteString(",")
+			}
+			if err := encodeNode(buf, f.Index(i)); err != nil {
+				return err
+			}
+		}
+		buf.WriteString("]")
+		return nil
+	default:
+		data, err := json.Marshal(f.Interface())
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+		return nil
+	}
+}
+
+// decodeNode returns a pointer to the decoded node, or the zero Value
+// for null.
+func decodeNode(data []byte) (reflect.Value, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return reflect.Value{}, err
+	}
+	if fields == nil {
+		return reflect.Value{}, nil
+	}
+	var kind string
+	if err := json.Unmarshal(fields["kind"], &kind); err != nil {
+		return reflect.Value{}, fmt.Errorf("ast: node without kind: %w", err)
+	}
+	t, ok := nodeTypes[kind]
+	if !ok {
+		return reflect.Value{}, fmt.Errorf("ast: unknown node kind %q", kind)
+	}
+	v := reflect.New(t)
+	for i := 0; i < t.NumField(); i++ {
+		raw, ok := fields[fieldKey(t.Field(i))]
+		if !ok {
+			continue
+		}
+		if err := decodeField(v.Elem().Field(i), raw); err != nil {
+			return reflect.Value{}, fmt.Errorf("ast: %s.%s: %w", kind, fieldKey(t.Field(i)), err)
+		}
+	}
+	return v, nil
+}
+
+func decodeField(f reflect.Value, raw json.RawMessage) error {
+	switch {
+	case f.Type().Implements(nodeType):
+		n, err := decodeNode(raw)
+		if err != nil || !n.IsValid() {
+			return err
+		}
+		if !n.Type().AssignableTo(f.Type()) {
+			return fmt.Errorf("cannot use %s as %s", n.Type(), f.Type())
+		}
+		f.Set(n)
+		return nil
+	case f.Kind() == reflect.Slice && f.Type().Elem().Implements(nodeType):
+		var elems []json.RawMessage
+		if err := json.Unmarshal(raw, &elems); err != nil || elems == nil {
+			return err
+		}
+		s := reflect.MakeSlice(f.Type(), len(elems), len(elems))
+		for i, elem := range elems {
+			if err := decodeField(s.Index(i), elem); err != nil {
+				return err
+			}
+		}
+		f.Set(s)
+		return nil
+	default:
+		return json.Unmarshal(raw, f.Addr().Interface())
+	}
+}
+
+// fieldKey is the JSON key of a node field, given by its json tag, or
+// "" if it has none.
+func fieldKey(f reflect.StructField) string {
+	return f.Tag.Get("json")
+}
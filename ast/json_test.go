@@ -0,0 +1,96 @@
+package ast
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"gointer/token"
+)
+
+// TestJSONRoundTrip fails when a node type, or a field of one, does not
+// survive MarshalJSON and UnmarshalJSON unchanged.
+func TestJSONRoundTrip(t *testing.T) {
+	for name, n := range nodeSamples() {
+		if s, ok := n.(interface{ SetSpan(from, to token.Position) }); ok {
+			s.SetSpan(
+				token.Position{Offset: 1, Line: 1, Column: 2},
+				token.Position{Offset: 9, Line: 2, Column: 3},
+			)
+		}
+		data, err := MarshalJSON(n)
+		if err != nil {
+			t.Errorf("MarshalJSON(%s) failed: %v", name, err)
+			continue
+		}
+		got, err := UnmarshalJSON(data)
+		if err != nil {
+			t.Errorf("UnmarshalJSON(%s) failed: %v", name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, n) {
+			t.Errorf("%s changed in round trip.\ngot=%s\nwant=%s", name, got, n)
+		}
+		if got.String() != n.String() {
+			t.Errorf("%s.String() changed in round trip. got=%q, want=%q", name, got.String(), n.String())
+		}
+	}
+}
+
+func TestMarshalJSON(t *testing.T) {
+	n := &ReturnStatment{
+		Token: token.Token{Type: token.RETURN, Literal: "return"},
+		ReturnValue: &Identifier{
+			Token: token.Token{Type: token.IDENT, Literal: "x"},
+			Value: "x",
+		},
+	}
+	data, err := MarshalJSON(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`{"kind":"ReturnStatement","token":{"type":"return","literal":"return",`,
+		`"span":{"from":{"offset":0,"line":0,"column":0},`,
+		`"returnValue":{"kind":"Identifier",`,
+		`"value":"x"}}`,
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("MarshalJSON output does not contain %s. got=%s", want, data)
+		}
+	}
+}
+
+// TestJSONKeys fails when a node field has no json key, or shares one
+// with another field, since the keys are the wire format.
+func TestJSONKeys(t *testing.T) {
+	for kind, typ := range nodeTypes {
+		seen := map[string]bool{"kind": true}
+		for i := 0; i < typ.NumField(); i++ {
+			f := typ.Field(i)
+			key := fieldKey(f)
+			if key == "" || seen[key] {
+				t.Errorf("%s.%s has json key %q, want a unique one", kind, f.Name, key)
+			}
+			seen[key] = true
+		}
+	}
+}
+
+func TestUnmarshalJSONErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`{"kind":"Frobnicate"}`, `unknown node kind "Frobnicate"`},
+		{`{"value":"x"}`, `node without kind`},
+		{`{"kind":"LetStatement","name":{"kind":"IntegerLiteral"}}`, `LetStatement.name: cannot use *ast.IntegerLiteral as *ast.Identifier`},
+		{`{"kind":"Program","statements":[{"kind":"Identifier"}]}`, `cannot use *ast.Identifier as ast.Statment`},
+	}
+	for _, tt := range tests {
+		_, err := UnmarshalJSON([]byte(tt.input))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("UnmarshalJSON(%s) error = %v, want %q", tt.input, err, tt.want)
+		}
+	}
+}
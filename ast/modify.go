@@ -0,0 +1,132 @@
+package ast
+
+import (
+	"fmt"
+	"reflect"
+)
+
+type ModifierFunc func(Node) Node
+
+// Modify rebuilds node bottom-up: the children of node are replaced by
+// the result of modifying them first, then modifier is called on node
+// itself and its result returned. Nodes are updated in place, so
+// tokens and positions of untouched nodes are preserved.
+//
+// The identifiers that declare a name, the name of a let, the variable
+// of a for-in loop and the parameters of a function, and the labels of
+// loops, break and continue are names rather than expressions, and are
+// not passed to modifier. So a modifier that replaces identifiers with
+// their values leaves the declarations alone.
+//
+// A modifier that returns nil for a statement in a statement list
+// removes it. Returning a node that does not fit the field it came
+// from, such as a statement where an expression is expected, panics.
+func Modify(node Node, modifier ModifierFunc) Node {
+	switch n := node.(type) {
+	case *Program:
+		n.Statments = modifyStatments(n.Statments, modifier)
+	case *BlockStatment:
+		n.Statments = modifyStatments(n.Statments, modifier)
+	case *LetStatment:
+		n.Type = modifyAs[TypeExpr](n.Type, modifier)
+		n.Value = modifyAs[Expression](n.Value, modifier)
+	case *ReturnStatment:
+		n.ReturnValue = modifyAs[Expression](n.ReturnValue, modifier)
+	case *ExpressionStatment:
+		n.Expression = modifyAs[Expression](n.Expression, modifier)
+	case *WhileStatment:
+		n.Condition = modifyAs[Expression](n.Condition, modifier)
+		n.Body = modifyAs[*BlockStatment](n.Body, modifier)
+	case *ForStatment:
+		n.Init = modifyAs[Statment](n.Init, modifier)
+		n.Condition = modifyAs[Expression](n.Condition, modifier)
+		n.Post = modifyAs[Expression](n.Post, modifier)
+		n.Body = modifyAs[*BlockStatment](n.Body, modifier)
+	case *ForInStatment:
+		n.Iterable = modifyAs[Expression](n.Iterable, modifier)
+		n.Body = modifyAs[*BlockStatment](n.Body, modifier)
+	case *BreakStatment, *ContinueStatment:
+		// only a label
+
+	case *Identifier, *IntegerLiteral, *StringLiteral, *Boolean, *Comment:
+		// leaves
+	case *PrefixExpression:
+		n.Right = modifyAs[Expression](n.Right, modifier)
+	case *InfixExpression:
+		n.Left = modifyAs[Expression](n.Left, modifier)
+		n.Right = modifyAs[Expression](n.Right, modifier)
+	case *PostfixExpression:
+		n.Left = modifyAs[Expression](n.Left, modifier)
+	case *AssignExpression:
+		n.Target = modifyAs[Expression](n.Target, modifier)
+		n.Value = modifyAs[Expression](n.Value, modifier)
+	case *IndexExpression:
+		n.Left = modifyAs[Expression](n.Left, modifier)
+		n.Index = modifyAs[Expression](n.Index, modifier)
+	case *CallExpression:
+		n.Function = modifyAs[Expression](n.Function, modifier)
+		for i, a := range n.Arguments {
+			n.Arguments[i] = modifyAs[Expression](a, modifier)
+		}
+	case *IfExpression:
+		n.Condition = modifyAs[Expression](n.Condition, modifier)
+		n.Consequence = modifyAs[*BlockStatment](n.Consequence, modifier)
+		n.Alternative = modifyAs[*BlockStatment](n.Alternative, modifier)
+	case *FunctionLiteral:
+		for i, t := range n.ParamTypes {
+			n.ParamTypes[i] = modifyAs[TypeExpr](t, modifier)
+		}
+		n.ReturnType = modifyAs[TypeExpr](n.ReturnType, modifier)
+		n.Body = modifyAs[*BlockStatment](n.Body, modifier)
+
+	case *NamedType:
+		// leaf
+	case *FunctionType:
+		for i, p := range n.Params {
+			n.Params[i] = modifyAs[TypeExpr](p, modifier)
+		}
+		n.Result = modifyAs[TypeExpr](n.Result, modifier)
+
+	default:
+		panic(fmt.Sprintf("ast.Modify: unexpected node type %T", n))
+	}
+	return modifier(node)
+}
+
+func modifyStatments(stmts []Statment, modifier ModifierFunc) []Statment {
+	out := stmts[:0]
+	for _, s := range stmts {
+		if s := modifyAs[Statment](s, modifier); s != nil {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// modifyAs modifies a child held in a field of type T. Unset fields
+// stay unset and a nil result clears the field.
+func modifyAs[T Node](child T, modifier ModifierFunc) T {
+	var zero T
+	if isNil(child) {
+		return zero
+	}
+	result := Modify(child, modifier)
+	if isNil(result) {
+		return zero
+	}
+	t, ok := result.(T)
+	if !ok {
+		panic(fmt.Sprintf("ast.Modify: modifier replaced %T with %T", child, result))
+	}
+	return t
+}
+
+// isNil reports whether n is nil or a typed nil such as a nil
+// *BlockStatment stored in a Node.
+func isNil(n Node) bool {
+	if n == nil {
+		return true
+	}
+	v := reflect.ValueOf(n)
+	return v.Kind() == reflect.Pointer && v.IsNil()
+}
@@ -0,0 +1,179 @@
+package ast
+
+import (
+	gtoken "gointer/token"
+	"testing"
+)
+
+func TestModifyIdentity(t *testing.T) {
+	for name, n := range nodeSamples() {
+		before := n.String()
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Errorf("Modify(%s) panicked: %v", name, r)
+				}
+			}()
+			if got := Modify(n, func(n Node) Node { return n }); got != n {
+				t.Errorf("Modify(%s) returned a different node", name)
+			}
+		}()
+		if after := n.String(); after != before {
+			t.Errorf("Modify(%s) changed %q to %q", name, before, after)
+		}
+	}
+}
+
+func TestModify(t *testing.T) {
+	one := func() Expression { return intLit(1) }
+	two := func() Expression { return intLit(2) }
+
+	turnOneIntoTwo := func(node Node) Node {
+		integer, ok := node.(*IntegerLiteral)
+		if !ok || integer.Value != 1 {
+			return node
+		}
+		integer.Value = 2
+		integer.Token.Literal = "2"
+		return integer
+	}
+
+	tests := []struct {
+		input    Node
+		expected Node
+	}{
+		{one(), two()},
+		{
+			&Program{Statments: []Statment{exprStmt(one())}},
+			&Program{Statments: []Statment{exprStmt(two())}},
+		},
+		{infix(one(), "+", two()), infix(two(), "+", two())},
+		{infix(two(), "+", one()), infix(two(), "+", two())},
+		{
+			&PrefixExpression{Operator: "-", Right: one()},
+			&PrefixExpression{Operator: "-", Right: two()},
+		},
+		{
+			&IndexExpression{Left: one(), Index: one()},
+			&IndexExpression{Left: two(), Index: two()},
+		},
+		{
+			&IfExpression{Condition: one(), Consequence: block(exprStmt(one())), Alternative: block(exprStmt(one()))},
+			&IfExpression{Condition: two(), Consequence: block(exprStmt(two())), Alternative: block(exprStmt(two()))},
+		},
+		{
+			&ReturnStatment{ReturnValue: one()},
+			&ReturnStatment{ReturnValue: two()},
+		},
+		{
+			&LetStatment{Name: ident("x"), Value: one()},
+			&LetStatment{Name: ident("x"), Value: two()},
+		},
+		{
+			&CallExpression{Function: ident("f"), Arguments: []Expression{one(), one()}},
+			&CallExpression{Function: ident("f"), Arguments: []Expression{two(), two()}},
+		},
+		{
+			&ForStatment{Condition: infix(ident("i"), "<", one()), Body: block(exprStmt(one()))},
+			&ForStatment{Condition: infix(ident("i"), "<", two()), Body: block(exprStmt(two()))},
+		},
+	}
+	for _, tt := range tests {
+		modified := Modify(tt.input, turnOneIntoTwo)
+		if modified.String() != tt.expected.String() {
+			t.Errorf("not equal. got=%q, want=%q", modified, tt.expected)
+		}
+	}
+}
+
+func TestModifyReplacesNodes(t *testing.T) {
+	// desugar x += v into x = x + v
+	pos := gtoken.Position{Offset: 7, Line: 2, Column: 3}
+	target := ident("x")
+	target.Token.Pos = pos
+	prog := &Program{Statments: []Statment{
+		exprStmt(&AssignExpression{Target: target, Operator: "+=", Value: intLit(1)}),
+	}}
+	Modify(prog, func(n Node) Node {
+		assign, ok := n.(*AssignExpression)
+		if !ok || assign.Operator != "+=" {
+			return n
+		}
+		return &AssignExpression{
+			Token:    assign.Token,
+			Target:   assign.Target,
+			Operator: "=",
+			Value:    infix(assign.Target, "+", assign.Value),
+		}
+	})
+	if got := prog.String(); got != "(x=(x+1))" {
+		t.Fatalf("expected=%q got=%q", "(x=(x+1))", got)
+	}
+	assign := prog.Statments[0].(*ExpressionStatment).Expression.(*AssignExpression)
+	if assign.Target.(*Identifier).Token.Pos != pos {
+		t.Fatalf("target position lost, got=%v", assign.Target.(*Identifier).Token.Pos)
+	}
+}
+
+func TestModifySkipsDeclarations(t *testing.T) {
+	// let x = 1; outer: for (c in s) { fn(p) { x + p + c }; break outer; }
+	// with every identifier replaced by 7 keeps the declared names; the
+	// nodes have no keyword tokens, which String leaves out
+	prog := &Program{Statments: []Statment{
+		&LetStatment{Name: ident("x"), Value: intLit(1)},
+		&ForInStatment{
+			Label:    ident("outer"),
+			Variable: ident("c"),
+			Iterable: ident("s"),
+			Body: block(
+				exprStmt(&FunctionLiteral{
+					Parameters: []*Identifier{ident("p")},
+					Body:       block(exprStmt(infix(infix(ident("x"), "+", ident("p")), "+", ident("c")))),
+				}),
+				&BreakStatment{Token: gtoken.Token{Type: gtoken.BREAK, Literal: "break"}, Label: ident("outer")},
+			),
+		},
+	}}
+	Modify(prog, func(n Node) Node {
+		if _, ok := n.(*Identifier); ok {
+			return intLit(7)
+		}
+		return n
+	})
+	expected := " x = 1;outer: for (c in 7) (p) ((7+7)+7)break outer;"
+	if got := prog.String(); got != expected {
+		t.Fatalf("expected=%q got=%q", expected, got)
+	}
+}
+
+func TestModifyRemovesStatments(t *testing.T) {
+	prog := &Program{Statments: []Statment{
+		exprStmt(ident("a")),
+		&BreakStatment{Token: gtoken.Token{Type: gtoken.BREAK, Literal: "break"}},
+		exprStmt(ident("b")),
+	}}
+	Modify(prog, func(n Node) Node {
+		if _, ok := n.(*BreakStatment); ok {
+			return nil
+		}
+		return n
+	})
+	if got := prog.String(); got != "ab" {
+		t.Fatalf("expected=%q got=%q", "ab", got)
+	}
+}
+
+func TestModifyWrongType(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("Modify did not panic when replacing a block with an expression")
+		}
+	}()
+	stmt := &WhileStatment{Condition: ident("c"), Body: block()}
+	Modify(stmt, func(n Node) Node {
+		if _, ok := n.(*BlockStatment); ok {
+			return ident("x")
+		}
+		return n
+	})
+}
@@ -0,0 +1,87 @@
+package ast
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Label describes a single node as its type name followed by its
+// quoted token literal, e.g. `InfixExpression "+"`.
+func Label(node Node) string {
+	kind := strings.TrimPrefix(fmt.Sprintf("%T", node), "*ast.")
+	if _, ok := node.(*Program); ok {
+		return kind
+	}
+	return kind + " " + strconv.Quote(node.TokenLiteral())
+}
+
+// FprintTree writes node to w as an indented tree, one node per line
+// with its children indented by two spaces below it.
+func FprintTree(w io.Writer, node Node) error {
+	buf := strings.Builder{}
+	depth := 0
+	Inspect(node, func(n Node) bool {
+		if n == nil {
+			depth--
+			return false
+		}
+		buf.WriteString(strings.Repeat("  ", depth))
+		buf.WriteString(Label(n))
+		buf.WriteString("\n")
+		depth++
+		return true
+	})
+	_, err := io.WriteString(w, buf.String())
+	return err
+}
+
+// FprintSexpr writes node to w as a single S-expression, in which each
+// node is a list of its label and its children.
+func FprintSexpr(w io.Writer, node Node) error {
+	buf := strings.Builder{}
+	Inspect(node, func(n Node) bool {
+		if n == nil {
+			buf.WriteString(")")
+			return false
+		}
+		if buf.Len() > 0 {
+			buf.WriteString(" ")
+		}
+		buf.WriteString("(")
+		buf.WriteString(Label(n))
+		return true
+	})
+	buf.WriteString("\n")
+	_, err := io.WriteString(w, buf.String())
+	return err
+}
+
+// FprintDot writes node to w as a Graphviz digraph with one vertex per
+// node and an edge from each node to each of its children.
+func FprintDot(w io.Writer, node Node) error {
+	buf := strings.Builder{}
+	buf.WriteString("digraph ast {\n")
+	buf.WriteString("\tnode [shape=box, fontname=monospace];\n")
+
+	var parents []int
+	id := 0
+	Inspect(node, func(n Node) bool {
+		if n == nil {
+			parents = parents[:len(parents)-1]
+			return false
+		}
+		label := strings.Replace(Label(n), " ", "\n", 1)
+		fmt.Fprintf(&buf, "\tn%d [label=%s];\n", id, strconv.Quote(label))
+		if len(parents) > 0 {
+			fmt.Fprintf(&buf, "\tn%d -> n%d;\n", parents[len(parents)-1], id)
+		}
+		parents = append(parents, id)
+		id++
+		return true
+	})
+	buf.WriteString("}\n")
+	_, err := io.WriteString(w, buf.String())
+	return err
+}
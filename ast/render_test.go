@@ -0,0 +1,74 @@
+package ast
+
+import (
+	"strings"
+	"testing"
+
+	"gointer/token"
+)
+
+func renderSample() Node {
+	return &Program{Statments: []Statment{
+		&LetStatment{
+			Token: token.Token{Type: token.LET, Literal: "let"},
+			Name:  ident("x"),
+			Value: infix(intLit(1), "+", &StringLiteral{
+				Token: token.Token{Type: token.STRING, Literal: `a"b`},
+				Value: `a"b`,
+			}),
+		},
+	}}
+}
+
+func TestFprintTree(t *testing.T) {
+	expected := `Program
+  LetStatment "let"
+    Identifier "x"
+    InfixExpression "+"
+      IntegerLiteral "1"
+      StringLiteral "a\"b"
+`
+	buf := strings.Builder{}
+	if err := FprintTree(&buf, renderSample()); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != expected {
+		t.Errorf("wrong tree.\ngot=%s\nwant=%s", buf.String(), expected)
+	}
+}
+
+func TestFprintSexpr(t *testing.T) {
+	expected := `(Program (LetStatment "let" (Identifier "x") (InfixExpression "+" (IntegerLiteral "1") (StringLiteral "a\"b"))))` + "\n"
+	buf := strings.Builder{}
+	if err := FprintSexpr(&buf, renderSample()); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != expected {
+		t.Errorf("wrong S-expression.\ngot=%s\nwant=%s", buf.String(), expected)
+	}
+}
+
+func TestFprintDot(t *testing.T) {
+	expected := `digraph ast {
+	node [shape=box, fontname=monospace];
+	n0 [label="Program"];
+	n1 [label="LetStatment\n\"let\""];
+	n0 -> n1;
+	n2 [label="Identifier\n\"x\""];
+	n1 -> n2;
+	n3 [label="InfixExpression\n\"+\""];
+	n1 -> n3;
+	n4 [label="IntegerLiteral\n\"1\""];
+	n3 -> n4;
+	n5 [label="StringLiteral\n\"a\\\"b\""];
+	n3 -> n5;
+}
+`
+	buf := strings.Builder{}
+	if err := FprintDot(&buf, renderSample()); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != expected {
+		t.Errorf("wrong dot output.\ngot=%s\nwant=%s", buf.String(), expected)
+	}
+}
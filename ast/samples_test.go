@@ -0,0 +1,192 @@
+package ast
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	gtoken "gointer/token"
+)
+
+func ident(name string) *Identifier {
+	return &Identifier{Token: gtoken.Token{Type: gtoken.IDENT, Literal: name}, Value: name}
+}
+
+func intLit(v int64) *IntegerLiteral {
+	lit := strconv.FormatInt(v, 10)
+	return &IntegerLiteral{Token: gtoken.Token{Type: gtoken.INT, Literal: lit}, Value: v}
+}
+
+func infix(left Expression, op string, right Expression) *InfixExpression {
+	return &InfixExpression{
+		Token:    gtoken.Token{Type: gtoken.TokenType(op), Literal: op},
+		Left:     left,
+		Operator: op,
+		Right:    right,
+	}
+}
+
+func exprStmt(e Expression) *ExpressionStatment {
+	return &ExpressionStatment{Token: gtoken.Token{Type: gtoken.IDENT, Literal: e.TokenLiteral()}, Expression: e}
+}
+
+func namedType(name string) *NamedType {
+	return &NamedType{Token: gtoken.Token{Type: gtoken.IDENT, Literal: name}, Name: name}
+}
+
+func block(stmts ...Statment) *BlockStatment {
+	return &BlockStatment{Token: gtoken.Token{Type: gtoken.LBRACE, Literal: "{"}, Statments: stmts}
+}
+
+// nodeSamples returns one instance of every node type with all of its
+// child fields set, keyed by type name.
+func nodeSamples() map[string]Node {
+	samples := []Node{
+		&Program{
+			Statments: []Statment{exprStmt(ident("a")), exprStmt(intLit(1))},
+			Comments:  []*Comment{{Token: gtoken.Token{Type: gtoken.COMMENT, Literal: "// a"}}},
+		},
+		&Comment{Token: gtoken.Token{Type: gtoken.COMMENT, Literal: "// note"}},
+		&LetStatment{Token: gtoken.Token{Type: gtoken.LET, Literal: "let"}, Name: ident("x"), Type: namedType("int"), Value: intLit(5)},
+		&ReturnStatment{Token: gtoken.Token{Type: gtoken.RETURN, Literal: "return"}, ReturnValue: ident("x")},
+		exprStmt(infix(ident("a"), "+", ident("b"))),
+		block(exprStmt(ident("a")), exprStmt(ident("b"))),
+		&WhileStatment{
+			Token:     gtoken.Token{Type: gtoken.WHILE, Literal: "while"},
+			Label:     ident("outer"),
+			Condition: ident("c"),
+			Body:      block(exprStmt(ident("a"))),
+		},
+		&ForStatment{
+			Token:     gtoken.Token{Type: gtoken.FOR, Literal: "for"},
+			Label:     ident("outer"),
+			Init:      &LetStatment{Token: gtoken.Token{Type: gtoken.LET, Literal: "let"}, Name: ident("i"), Value: intLit(0)},
+			Condition: infix(ident("i"), "<", intLit(10)),
+			Post:      &PostfixExpression{Token: gtoken.Token{Type: gtoken.INCR, Literal: "++"}, Left: ident("i"), Operator: "++"},
+			Body:      block(exprStmt(ident("i"))),
+		},
+		&ForInStatment{
+			Token:    gtoken.Token{Type: gtoken.FOR, Literal: "for"},
+			Label:    ident("outer"),
+			Variable: ident("x"),
+			Iterable: ident("xs"),
+			Body:     block(exprStmt(ident("x"))),
+		},
+		&BreakStatment{Token: gtoken.Token{Type: gtoken.BREAK, Literal: "break"}, Label: ident("outer")},
+		&ContinueStatment{Token: gtoken.Token{Type: gtoken.CONTINUE, Literal: "continue"}, Label: ident("outer")},
+		ident("x"),
+		intLit(42),
+		&StringLiteral{Token: gtoken.Token{Type: gtoken.STRING, Literal: "hi"}, Value: "hi"},
+		&Boolean{Token: gtoken.Token{Type: gtoken.TRUE, Literal: "true"}, Value: true},
+		&PrefixExpression{Token: gtoken.Token{Type: gtoken.MINUS, Literal: "-"}, Operator: "-", Right: intLit(1)},
+		infix(intLit(1), "*", intLit(2)),
+		&PostfixExpression{Token: gtoken.Token{Type: gtoken.BANG, Literal: "!"}, Left: ident("n"), Operator: "!"},
+		&AssignExpression{Token: gtoken.Token{Type: gtoken.PLUS_ASSIGN, Literal: "+="}, Target: ident("x"), Operator: "+=", Value: intLit(1)},
+		&IndexExpression{Token: gtoken.Token{Type: gtoken.LBRACKET, Literal: "["}, Left: ident("a"), Index: intLit(0)},
+		&CallExpression{Token: gtoken.Token{Type: gtoken.LPAREN, Literal: "("}, Function: ident("f"), Arguments: []Expression{ident("a"), intLit(2)}},
+		&IfExpression{
+			Token:       gtoken.Token{Type: gtoken.IF, Literal: "if"},
+			Condition:   ident("c"),
+			Consequence: block(exprStmt(ident("a"))),
+			Alternative: block(exprStmt(ident("b"))),
+		},
+		&FunctionLiteral{
+			Token:      gtoken.Token{Type: gtoken.FUNCTION, Literal: "fn"},
+			Parameters: []*Identifier{ident("a"), ident("b")},
+			ParamTypes: []TypeExpr{namedType("int"), namedType("int")},
+			ReturnType: namedType("int"),
+			Body:       block(exprStmt(infix(ident("a"), "+", ident("b")))),
+		},
+		namedType("int"),
+		&FunctionType{
+			Token:  gtoken.Token{Type: gtoken.FUNCTION, Literal: "fn"},
+			Params: []TypeExpr{namedType("int"), namedType("bool")},
+			Result: namedType("int"),
+		},
+	}
+	m := make(map[string]Node, len(samples))
+	for _, n := range samples {
+		m[reflect.TypeOf(n).Elem().Name()] = n
+	}
+	return m
+}
+
+// declaredNodeTypes returns the names of all node types declared in
+// the package source: Program, Comment and every type with a
+// statementNode, expressionNode or typeNode method.
+func declaredNodeTypes(t *testing.T) []string {
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{"Program", "Comment"}
+	fset := token.NewFileSet()
+	for _, name := range files {
+		if strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		src, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f, err := parser.ParseFile(fset, name, src, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, decl := range f.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv == nil {
+				continue
+			}
+			if fn.Name.Name != "statementNode" && fn.Name.Name != "expressionNode" && fn.Name.Name != "typeNode" {
+				continue
+			}
+			recv := fn.Recv.List[0].Type
+			if star, ok := recv.(*ast.StarExpr); ok {
+				recv = star.X
+			}
+			names = append(names, recv.(*ast.Ident).Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestNodeSamplesComplete(t *testing.T) {
+	samples := nodeSamples()
+	for _, name := range declaredNodeTypes(t) {
+		if _, ok := samples[name]; !ok {
+			t.Errorf("nodeSamples has no %s, add one with all child fields set", name)
+		}
+	}
+}
+
+// childFieldCount counts the node values held in the fields of n,
+// leaving out Program.Comments which are not children.
+func childFieldCount(n Node) int {
+	nodeType := reflect.TypeOf((*Node)(nil)).Elem()
+	v := reflect.ValueOf(n).Elem()
+	count := 0
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Field(i)
+		if v.Type().Field(i).Name == "Comments" {
+			continue
+		}
+		switch {
+		case f.Type().Implements(nodeType):
+			if !f.IsNil() {
+				count++
+			}
+		case f.Kind() == reflect.Slice && f.Type().Elem().Implements(nodeType):
+			count += f.Len()
+		}
+	}
+	return count
+}
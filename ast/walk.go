@@ -0,0 +1,225 @@
+package ast
+
+import "fmt"
+
+// A Visitor's Visit method is invoked for each node encountered by Walk.
+// If the result visitor w is not nil, Walk visits each of the children
+// of node with the visitor w, followed by a call of w.Visit(nil).
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses an AST in depth-first order: It starts by calling
+// v.Visit(node); node must not be nil. If the visitor w returned by
+// v.Visit(node) is not nil, Walk is invoked recursively with visitor
+// w for each of the non-nil children of node, followed by a call of
+// w.Visit(nil).
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+	for _, child := range Children(node) {
+		Walk(v, child)
+	}
+	v.Visit(nil)
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses an AST in depth-first order: It starts by calling
+// f(node); node must not be nil. If f returns true, Inspect invokes f
+// recursively for each of the non-nil children of node, followed by a
+// call of f(nil).
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
+
+// Children returns the non-nil direct children of node in source
+// order. It panics on node types it does not know, so a new node type
+// cannot silently be skipped by Walk.
+func Children(node Node) []Node {
+	var children []Node
+	add := func(n Node) {
+		children = append(children, n)
+	}
+
+	switch n := node.(type) {
+	case *Program:
+		for _, s := range n.Statments {
+			add(s)
+		}
+	case *BlockStatment:
+		for _, s := range n.Statments {
+			add(s)
+		}
+	case *LetStatment:
+		if n.Name != nil {
+			add(n.Name)
+		}
+		if n.Type != nil {
+			add(n.Type)
+		}
+		if n.Value != nil {
+			add(n.Value)
+		}
+	case *ReturnStatment:
+		if n.ReturnValue != nil {
+			add(n.ReturnValue)
+		}
+	case *ExpressionStatment:
+		if n.Expression != nil {
+			add(n.Expression)
+		}
+	case *WhileStatment:
+		if n.Label != nil {
+			add(n.Label)
+		}
+		if n.Condition != nil {
+			add(n.Condition)
+		}
+		if n.Body != nil {
+			add(n.Body)
+		}
+	case *ForStatment:
+		if n.Label != nil {
+			add(n.Label)
+		}
+		if n.Init != nil {
+			add(n.Init)
+		}
+		if n.Condition != nil {
+			add(n.Condition)
+		}
+		if n.Post != nil {
+			add(n.Post)
+		}
+		if n.Body != nil {
+			add(n.Body)
+		}
+	case *ForInStatment:
+		if n.Label != nil {
+			add(n.Label)
+		}
+		if n.Variable != nil {
+			add(n.Variable)
+		}
+		if n.Iterable != nil {
+			add(n.Iterable)
+		}
+		if n.Body != nil {
+			add(n.Body)
+		}
+	case *BreakStatment:
+		if n.Label != nil {
+			add(n.Label)
+		}
+	case *ContinueStatment:
+		if n.Label != nil {
+			add(n.Label)
+		}
+
+	case *Identifier, *IntegerLiteral, *StringLiteral, *Boolean, *Comment:
+		// leaves
+	case *PrefixExpression:
+		if n.Right != nil {
+			add(n.Right)
+		}
+	case *InfixExpression:
+		if n.Left != nil {
+			add(n.Left)
+		}
+		if n.Right != nil {
+			add(n.Right)
+		}
+	case *PostfixExpression:
+		if n.Left != nil {
+			add(n.Left)
+		}
+	case *AssignExpression:
+		if n.Target != nil {
+			add(n.Target)
+		}
+		if n.Value != nil {
+			add(n.Value)
+		}
+	case *IndexExpression:
+		if n.Left != nil {
+			add(n.Left)
+		}
+		if n.Index != nil {
+			add(n.Index)
+		}
+	case *CallExpression:
+		if n.Function != nil {
+			add(n.Function)
+		}
+		for _, a := range n.Arguments {
+			add(a)
+		}
+	case *IfExpression:
+		if n.Condition != nil {
+			add(n.Condition)
+		}
+		if n.Consequence != nil {
+			add(n.Consequence)
+		}
+		if n.Alternative != nil {
+			add(n.Alternative)
+		}
+	case *FunctionLiteral:
+		for i, p := range n.Parameters {
+			add(p)
+			if t := n.ParamType(i); t != nil {
+				add(t)
+			}
+		}
+		if n.ReturnType != nil {
+			add(n.ReturnType)
+		}
+		if n.Body != nil {
+			add(n.Body)
+		}
+
+	case *NamedType:
+		// leaf
+	case *FunctionType:
+		for _, p := range n.Params {
+			if p != nil {
+				add(p)
+			}
+		}
+		if n.Result != nil {
+			add(n.Result)
+		}
+
+	default:
+		panic(fmt.Sprintf("ast.Children: unexpected node type %T", n))
+	}
+	return children
+}
+
+// PathEnclosing returns the nodes under root whose span contains the
+// byte offset, outermost first, so that an editor can find the node
+// under the cursor as the last element. Nodes without a position are
+// skipped along with their children.
+func PathEnclosing(root Node, offset int) []Node {
+	var path []Node
+	Inspect(root, func(n Node) bool {
+		if n == nil {
+			return false
+		}
+		if !n.Pos().IsValid() || offset < n.Pos().Offset || offset >= n.End().Offset {
+			return false
+		}
+		path = append(path, n)
+		return true
+	})
+	return path
+}
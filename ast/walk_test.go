@@ -0,0 +1,99 @@
+package ast
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// TestChildrenExhaustive fails when a node type, or a child field of
+// one, is added without Children knowing about it.
+func TestChildrenExhaustive(t *testing.T) {
+	for name, n := range nodeSamples() {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Errorf("Children(%s) panicked: %v", name, r)
+				}
+			}()
+			if got, want := len(Children(n)), childFieldCount(n); got != want {
+				t.Errorf("Children(%s) returned %d nodes, %s has %d child nodes", name, got, name, want)
+			}
+		}()
+	}
+}
+
+func TestWalk(t *testing.T) {
+	samples := nodeSamples()
+	prog := &Program{Statments: []Statment{
+		samples["ForStatment"].(Statment),
+		exprStmt(samples["IfExpression"].(Expression)),
+	}}
+
+	var trace []string
+	Inspect(prog, func(n Node) bool {
+		if n == nil {
+			trace = append(trace, "end")
+			return false
+		}
+		trace = append(trace, strings.TrimPrefix(fmt.Sprintf("%T", n), "*ast."))
+		return true
+	})
+	expected := strings.Join([]string{
+		"Program",
+		"ForStatment",
+		"Identifier", "end",
+		"LetStatment", "Identifier", "end", "IntegerLiteral", "end", "end",
+		"InfixExpression", "Identifier", "end", "IntegerLiteral", "end", "end",
+		"PostfixExpression", "Identifier", "end", "end",
+		"BlockStatment", "ExpressionStatment", "Identifier", "end", "end", "end",
+		"end",
+		"ExpressionStatment",
+		"IfExpression",
+		"Identifier", "end",
+		"BlockStatment", "ExpressionStatment", "Identifier", "end", "end", "end",
+		"BlockStatment", "ExpressionStatment", "Identifier", "end", "end", "end",
+		"end",
+		"end",
+		"end",
+	}, " ")
+	if got := strings.Join(trace, " "); got != expected {
+		t.Fatalf("walk order wrong\nexpected=%s\ngot=     %s", expected, got)
+	}
+}
+
+func TestInspectPrune(t *testing.T) {
+	prog := &Program{Statments: []Statment{
+		exprStmt(infix(ident("a"), "+", infix(ident("b"), "*", ident("c")))),
+	}}
+	var idents []string
+	Inspect(prog, func(n Node) bool {
+		switch n := n.(type) {
+		case *Identifier:
+			idents = append(idents, n.Value)
+		case *InfixExpression:
+			// do not descend into products
+			return n.Operator != "*"
+		}
+		return true
+	})
+	if strings.Join(idents, ",") != "a" {
+		t.Fatalf("expected only a to be visited, got=%v", idents)
+	}
+}
+
+func TestChildrenUnknownNode(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("Children did not panic on an unknown node")
+		}
+	}()
+	Children(&unknownNode{})
+}
+
+type unknownNode struct {
+	Span
+}
+
+func (*unknownNode) TokenLiteral() string { return "" }
+func (*unknownNode) String() string       { return "" }
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"gointer/ast"
+	"gointer/lexer"
+	"gointer/optimize"
+	"gointer/parser"
+	"io"
+	"os"
+)
+
+// runAST implements "gointer ast [flags] [file]", which parses a
+// program and prints its syntax tree, optimized with -optimize.
+func runAST(args []string) int {
+	fs := flag.NewFlagSet("gointer ast", flag.ExitOnError)
+	common := addCommonFlags(fs)
+	trace := fs.Bool("trace", false, "trace the parse functions to stderr")
+	format := fs.String("format", "string", "output `format`: string, tree, sexpr, dot or json")
+	asJSON := fs.Bool("json", false, "print the syntax tree as JSON, same as --format=json")
+	opt := addOptimizeFlags(fs, false, "print the syntax tree after optimization")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: gointer ast [flags] [file]\n")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if *asJSON {
+		*format = "json"
+	}
+	var render func(io.Writer, ast.Node) error
+	switch *format {
+	case "string":
+		render = printStatments
+	case "tree":
+		render = ast.FprintTree
+	case "sexpr":
+		render = ast.FprintSexpr
+	case "dot":
+		render = ast.FprintDot
+	case "json":
+		render = printJSON
+	default:
+		fmt.Fprintf(os.Stderr, "gointer ast: unknown format %q\n", *format)
+		return 2
+	}
+
+	optOpts, err := opt.options()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gointer ast: %v\n", err)
+		return 2
+	}
+
+	lexOpts, parseOpts, err := common.options()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gointer ast: %v\n", err)
+		return 2
+	}
+	if *trace {
+		parseOpts = append(parseOpts, parser.WithTrace(os.Stderr))
+	}
+
+	name := fs.Arg(0)
+	in, err := openInput(name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gointer ast: %v\n", err)
+		return 1
+	}
+	defer in.Close()
+
+	l := lexer.NewReader(in, lexOpts...)
+	p := parser.New(l, parseOpts...)
+	program := p.ParseProgram()
+	if err := l.Err(); err != nil {
+		fmt.Fprintf(os.Stderr, "gointer ast: %v\n", err)
+		return 1
+	}
+	if errs := p.Errors(); len(errs) != 0 {
+		printErrors(name, errs)
+		return 1
+	}
+	if *opt.enabled {
+		program = optimize.Run(program, optOpts...)
+	}
+	if err := render(os.Stdout, program); err != nil {
+		fmt.Fprintf(os.Stderr, "gointer ast: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// printStatments prints each top-level statement of a program on its
+// own line.
+func printStatments(w io.Writer, node ast.Node) error {
+	for _, stmt := range node.(*ast.Program).Statments {
+		if _, err := fmt.Fprintln(w, stmt.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// printJSON prints the indented JSON encoding of node.
+func printJSON(w io.Writer, node ast.Node) error {
+	data, err := ast.MarshalJSON(node)
+	if err != nil {
+		return err
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, data, "", "  "); err != nil {
+		return err
+	}
+	out.WriteByte('\n')
+	_, err = out.WriteTo(w)
+	return err
+}
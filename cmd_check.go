@@ -0,0 +1,91 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"gointer/ast"
+	"gointer/lexer"
+	"gointer/parser"
+	"gointer/resolver"
+	"gointer/types"
+	"os"
+)
+
+// runCheck implements "gointer check [flags] [files...]", which reports
+// type errors without running the program. Without files it checks
+// stdin. With -infer the types of unannotated code are inferred too and
+// the type of every top-level let is printed.
+func runCheck(args []string) int {
+	fs := flag.NewFlagSet("gointer check", flag.ExitOnError)
+	common := addCommonFlags(fs)
+	infer := fs.Bool("infer", false, "infer the types of unannotated code and print top-level signatures")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: gointer check [flags] [files...]\n")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	lexOpts, parseOpts, err := common.options()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gointer check: %v\n", err)
+		return 2
+	}
+	lang, _ := common.language()
+
+	names := fs.Args()
+	if len(names) == 0 {
+		names = []string{"-"}
+	}
+	status := 0
+	for _, name := range names {
+		in, err := openInput(name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "gointer check: %v\n", err)
+			status = 1
+			continue
+		}
+		l := lexer.NewReader(in, lexOpts...)
+		p := parser.New(l, parseOpts...)
+		program := p.ParseProgram()
+		in.Close()
+		if err := l.Err(); err != nil {
+			fmt.Fprintf(os.Stderr, "gointer check: %v\n", err)
+			status = 1
+			continue
+		}
+		if errs := p.Errors(); len(errs) != 0 {
+			printErrors(name, errs)
+			status = 1
+			continue
+		}
+		info, errs := resolver.Resolve(program, resolver.WithLang(lang))
+		if len(errs) != 0 {
+			printErrors(name, errs)
+			status = 1
+			continue
+		}
+		check := types.Check
+		if *infer {
+			check = types.Infer
+		}
+		typeInfo, errs := check(program, info, types.WithLang(lang))
+		if len(errs) != 0 {
+			printErrors(name, errs)
+			status = 1
+			continue
+		}
+		if *infer {
+			printSignatures(program, typeInfo)
+		}
+	}
+	return status
+}
+
+// printSignatures prints the type of each top-level let of program.
+func printSignatures(program *ast.Program, info *types.Info) {
+	for _, s := range program.Statments {
+		if let, ok := s.(*ast.LetStatment); ok {
+			fmt.Printf("%s: %s\n", let.Name.Value, types.Format(info.TypeOf(let.Name)))
+		}
+	}
+}
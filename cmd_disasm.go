@@ -0,0 +1,32 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"gointer/compiler"
+	"os"
+)
+
+// runDisasm implements "gointer disasm [flags] [file]", which compiles
+// a program and prints the bytecode the virtual machine would run,
+// optimized unless -optimize=false.
+func runDisasm(args []string) int {
+	fs := flag.NewFlagSet("gointer disasm", flag.ExitOnError)
+	common := addCommonFlags(fs)
+	opt := addOptimizeFlags(fs, true, "optimize the program before compiling it")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: gointer disasm [flags] [file]\n")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	bytecode, status := compileInput("gointer disasm", fs.Arg(0), common, opt)
+	if bytecode == nil {
+		return status
+	}
+	if err := compiler.Disassemble(os.Stdout, bytecode); err != nil {
+		fmt.Fprintf(os.Stderr, "gointer disasm: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"gointer/format"
+	"gointer/lexer"
+	"gointer/parser"
+	"io"
+	"os"
+)
+
+// runFmt implements "gointer fmt [flags] [files...]", which prints
+// programs in canonical form. Without files it formats stdin.
+func runFmt(args []string) int {
+	fs := flag.NewFlagSet("gointer fmt", flag.ExitOnError)
+	common := addCommonFlags(fs)
+	write := fs.Bool("w", false, "write the result to the file instead of stdout")
+	showDiff := fs.Bool("d", false, "print a diff instead of the formatted source")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: gointer fmt [flags] [files...]\n")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	lexOpts, parseOpts, err := common.options()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gointer fmt: %v\n", err)
+		return 2
+	}
+	keywords, _ := common.keywords()
+	lexOpts = append(lexOpts, lexer.WithComments())
+
+	names := fs.Args()
+	if len(names) == 0 {
+		if *write {
+			fmt.Fprintf(os.Stderr, "gointer fmt: cannot use -w with standard input\n")
+			return 2
+		}
+		names = []string{"-"}
+	}
+
+	status := 0
+	for _, name := range names {
+		in, err := openInput(name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "gointer fmt: %v\n", err)
+			status = 1
+			continue
+		}
+		src, err := io.ReadAll(in)
+		in.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "gointer fmt: %v\n", err)
+			status = 1
+			continue
+		}
+
+		p := parser.New(lexer.New(string(src), lexOpts...), parseOpts...)
+		program := p.ParseProgram()
+		if errs := p.Errors(); len(errs) != 0 {
+			printErrors(name, errs)
+			status = 1
+			continue
+		}
+		var out bytes.Buffer
+		if err := format.Fprint(&out, program, format.WithKeywords(keywords)); err != nil {
+			fmt.Fprintf(os.Stderr, "gointer fmt: %v\n", err)
+			status = 1
+			continue
+		}
+
+		if *showDiff {
+			os.Stdout.Write(unifiedDiff(name, src, out.Bytes()))
+		}
+		switch {
+		case *write:
+			if bytes.Equal(src, out.Bytes()) {
+				continue
+			}
+			if err := os.WriteFile(name, out.Bytes(), 0o644); err != nil {
+				fmt.Fprintf(os.Stderr, "gointer fmt: %v\n", err)
+				status = 1
+			}
+		case !*showDiff:
+			out.WriteTo(os.Stdout)
+		}
+	}
+	return status
+}
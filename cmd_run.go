@@ -0,0 +1,137 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"gointer/ast"
+	"gointer/compiler"
+	"gointer/evaluator"
+	"gointer/lexer"
+	"gointer/object"
+	"gointer/optimize"
+	"gointer/parser"
+	"gointer/resolver"
+	"gointer/vm"
+	"os"
+)
+
+// A runner runs a program, with the vm or the evaluator.
+type runner interface {
+	Run() error
+	// Result is the value of the program once it ran.
+	Result() object.Object
+}
+
+// runRun implements "gointer run [flags] [file]", which runs a program
+// and prints its value unless it is null. --engine selects what runs
+// it, so that the engines can be compared.
+func runRun(args []string) int {
+	fs := flag.NewFlagSet("gointer run", flag.ExitOnError)
+	common := addCommonFlags(fs)
+	engine := fs.String("engine", "vm", "`engine` that runs the program: vm or eval")
+	opt := addOptimizeFlags(fs, true, "optimize the program before running it")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: gointer run [flags] [file]\n")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	var r runner
+	lang, _ := common.language()
+	name := fs.Arg(0)
+	switch *engine {
+	case "vm":
+		bytecode, status := compileInput("gointer run", name, common, opt)
+		if bytecode == nil {
+			return status
+		}
+		r = vm.New(bytecode, vm.WithLang(lang))
+	case "eval":
+		program, info, status := resolveInput("gointer run", name, common, opt)
+		if program == nil {
+			return status
+		}
+		r = evaluator.New(program, info, evaluator.WithLang(lang))
+	default:
+		fmt.Fprintf(os.Stderr, "gointer run: unknown engine %q\n", *engine)
+		return 2
+	}
+
+	if err := r.Run(); err != nil {
+		printErrors(name, []string{err.Error()})
+		return 1
+	}
+	if result := r.Result(); result != object.NULL {
+		fmt.Println(result.Inspect())
+	}
+	return 0
+}
+
+// compileInput compiles the named file, or stdin, for the command cmd.
+// On failure it reports the errors and returns a nil bytecode with the
+// exit status.
+func compileInput(cmd, name string, common *commonFlags, opt *optimizeFlags) (*compiler.Bytecode, int) {
+	program, info, status := resolveInput(cmd, name, common, opt)
+	if program == nil {
+		return nil, status
+	}
+	lang, _ := common.language()
+	bytecode, errs := compiler.Compile(program, info, compiler.WithLang(lang))
+	if len(errs) != 0 {
+		printErrors(name, errs)
+		return nil, 1
+	}
+	return bytecode, 0
+}
+
+// resolveInput parses and resolves the named file, or stdin, for the
+// command cmd, and optimizes it as opt says. On failure it reports the
+// errors and returns a nil program with the exit status.
+func resolveInput(cmd, name string, common *commonFlags, opt *optimizeFlags) (*ast.Program, *resolver.Info, int) {
+	lexOpts, parseOpts, err := common.options()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", cmd, err)
+		return nil, nil, 2
+	}
+	optOpts, err := opt.options()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", cmd, err)
+		return nil, nil, 2
+	}
+	lang, _ := common.language()
+
+	in, err := openInput(name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", cmd, err)
+		return nil, nil, 1
+	}
+	defer in.Close()
+
+	l := lexer.NewReader(in, lexOpts...)
+	p := parser.New(l, parseOpts...)
+	program := p.ParseProgram()
+	if err := l.Err(); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", cmd, err)
+		return nil, nil, 1
+	}
+	if errs := p.Errors(); len(errs) != 0 {
+		printErrors(name, errs)
+		return nil, nil, 1
+	}
+	info, errs := resolver.Resolve(program, resolver.WithLang(lang))
+	if len(errs) != 0 {
+		printErrors(name, errs)
+		return nil, nil, 1
+	}
+	if *opt.enabled {
+		// the program was resolved before, so that the errors in the
+		// code the optimizer removes are still reported
+		program = optimize.Run(program, optOpts...)
+		info, errs = resolver.Resolve(program, resolver.WithLang(lang))
+		if len(errs) != 0 {
+			printErrors(name, errs)
+			return nil, nil, 1
+		}
+	}
+	return program, info, 0
+}
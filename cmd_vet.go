@@ -0,0 +1,94 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"gointer/lexer"
+	"gointer/parser"
+	"gointer/resolver"
+	"gointer/vet"
+	"os"
+	"strings"
+)
+
+// runVet implements "gointer vet [flags] [files...]", which reports
+// suspicious code. Without files it checks stdin.
+func runVet(args []string) int {
+	fs := flag.NewFlagSet("gointer vet", flag.ExitOnError)
+	common := addCommonFlags(fs)
+	disable := fs.String("disable", "", "comma separated `ids` of checks to turn off")
+	list := fs.Bool("list", false, "list the available checks and exit")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: gointer vet [flags] [files...]\n")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	if *list {
+		for _, c := range vet.Checks() {
+			fmt.Printf("%-12s %-8s %s\n", c.ID, c.Severity, c.Doc)
+		}
+		return 0
+	}
+
+	lexOpts, parseOpts, err := common.options()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gointer vet: %v\n", err)
+		return 2
+	}
+	lang, _ := common.language()
+	lexOpts = append(lexOpts, lexer.WithComments())
+	vetOpts := []vet.Option{vet.WithLang(lang)}
+	if *disable != "" {
+		ids := strings.Split(*disable, ",")
+		for _, id := range ids {
+			if _, ok := vet.Lookup(id); !ok {
+				fmt.Fprintf(os.Stderr, "gointer vet: unknown check %q\n", id)
+				return 2
+			}
+		}
+		vetOpts = append(vetOpts, vet.Disable(ids...))
+	}
+
+	names := fs.Args()
+	if len(names) == 0 {
+		names = []string{"-"}
+	}
+	status := 0
+	for _, name := range names {
+		in, err := openInput(name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "gointer vet: %v\n", err)
+			status = 1
+			continue
+		}
+		l := lexer.NewReader(in, lexOpts...)
+		p := parser.New(l, parseOpts...)
+		program := p.ParseProgram()
+		in.Close()
+		if err := l.Err(); err != nil {
+			fmt.Fprintf(os.Stderr, "gointer vet: %v\n", err)
+			status = 1
+			continue
+		}
+		if errs := p.Errors(); len(errs) != 0 {
+			printErrors(name, errs)
+			status = 1
+			continue
+		}
+		info, errs := resolver.Resolve(program, resolver.WithLang(lang))
+		if len(errs) != 0 {
+			printErrors(name, errs)
+			status = 1
+			continue
+		}
+		diags := vet.Run(program, info, vetOpts...)
+		for _, d := range diags {
+			printErrors(name, []string{d.String()})
+		}
+		if len(diags) != 0 {
+			status = 1
+		}
+	}
+	return status
+}
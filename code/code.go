@@ -0,0 +1,296 @@
+// Package code defines the bytecode instructions the compiler emits and
+// the virtual machine runs. An instruction is an opcode byte followed
+// by its operands in big-endian order.
+package code
+
+import (
+	"encoding/binary"
+	"fmt"
+	"gointer/token"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type Instructions []byte
+
+type Opcode byte
+
+const (
+	// OpConstant pushes the constant with the operand index.
+	OpConstant Opcode = iota
+	OpPop
+	// OpResult pops the value of an expression statement outside of
+	// functions, which is the value of the program so far.
+	OpResult
+	OpDup
+
+	OpTrue
+	OpFalse
+	OpNull
+
+	// The binary operators pop the right operand, then the left one,
+	// and push the result.
+	OpAdd
+	OpSub
+	OpMul
+	OpDiv
+	OpMod
+	OpPow
+	OpBitAnd
+	OpBitOr
+	OpBitXor
+	OpShl
+	OpShr
+	OpEqual
+	OpNotEqual
+	OpGreaterThan
+	OpGreaterEqual
+	OpLessThan
+	OpLessEqual
+
+	OpMinus
+	OpBang
+	OpBitNot
+
+	// The jumps take the absolute offset of their target. The
+	// conditional ones pop the value they test.
+	OpJump
+	OpJumpNotTruthy
+	OpJumpTruthy
+
+	OpGetGlobal
+	OpSetGlobal
+	OpGetLocal
+	OpSetLocal
+	// OpNewCell stores a new cell holding null in a local. Locals that
+	// closures capture live in cells, which OpGetCell and OpSetCell read
+	// and write through.
+	OpNewCell
+	OpGetCell
+	OpSetCell
+	// OpGetFree and OpSetFree read and write the cells captured by the
+	// current closure, OpFreeCell pushes the cell itself.
+	OpGetFree
+	OpSetFree
+	OpFreeCell
+	OpGetBuiltin
+
+	OpIndex
+	// OpIter replaces the value on top of the stack by an iterator over
+	// it. OpIterNext pushes the next value of the iterator below the top
+	// or, when it is done, jumps to its operand.
+	OpIter
+	OpIterNext
+
+	// OpClosure makes a closure of the function constant in its first
+	// operand, capturing as many cells from the stack as the second.
+	OpClosure
+	OpCall
+	OpReturnValue
+	OpReturn
+)
+
+// A Definition describes an opcode for printing and decoding.
+type Definition struct {
+	Name string
+	// OperandWidths are the sizes in bytes of the operands.
+	OperandWidths []int
+}
+
+var definitions = map[Opcode]*Definition{
+	OpConstant: {"OpConstant", []int{2}},
+	OpPop:      {"OpPop", []int{}},
+	OpResult:   {"OpResult", []int{}},
+	OpDup:      {"OpDup", []int{}},
+
+	OpTrue:  {"OpTrue", []int{}},
+	OpFalse: {"OpFalse", []int{}},
+	OpNull:  {"OpNull", []int{}},
+
+	OpAdd:          {"OpAdd", []int{}},
+	OpSub:          {"OpSub", []int{}},
+	OpMul:          {"OpMul", []int{}},
+	OpDiv:          {"OpDiv", []int{}},
+	OpMod:          {"OpMod", []int{}},
+	OpPow:          {"OpPow", []int{}},
+	OpBitAnd:       {"OpBitAnd", []int{}},
+	OpBitOr:        {"OpBitOr", []int{}},
+	OpBitXor:       {"OpBitXor", []int{}},
+	OpShl:          {"OpShl", []int{}},
+	OpShr:          {"OpShr", []int{}},
+	OpEqual:        {"OpEqual", []int{}},
+	OpNotEqual:     {"OpNotEqual", []int{}},
+	OpGreaterThan:  {"OpGreaterThan", []int{}},
+	OpGreaterEqual: {"OpGreaterEqual", []int{}},
+	OpLessThan:     {"OpLessThan", []int{}},
+	OpLessEqual:    {"OpLessEqual", []int{}},
+
+	OpMinus:  {"OpMinus", []int{}},
+	OpBang:   {"OpBang", []int{}},
+	OpBitNot: {"OpBitNot", []int{}},
+
+	OpJump:          {"OpJump", []int{2}},
+	OpJumpNotTruthy: {"OpJumpNotTruthy", []int{2}},
+	OpJumpTruthy:    {"OpJumpTruthy", []int{2}},
+
+	OpGetGlobal:  {"OpGetGlobal", []int{2}},
+	OpSetGlobal:  {"OpSetGlobal", []int{2}},
+	OpGetLocal:   {"OpGetLocal", []int{1}},
+	OpSetLocal:   {"OpSetLocal", []int{1}},
+	OpNewCell:    {"OpNewCell", []int{1}},
+	OpGetCell:    {"OpGetCell", []int{1}},
+	OpSetCell:    {"OpSetCell", []int{1}},
+	OpGetFree:    {"OpGetFree", []int{1}},
+	OpSetFree:    {"OpSetFree", []int{1}},
+	OpFreeCell:   {"OpFreeCell", []int{1}},
+	OpGetBuiltin: {"OpGetBuiltin", []int{1}},
+
+	OpIndex:    {"OpIndex", []int{}},
+	OpIter:     {"OpIter", []int{}},
+	OpIterNext: {"OpIterNext", []int{2}},
+
+	OpClosure:     {"OpClosure", []int{2, 1}},
+	OpCall:        {"OpCall", []int{1}},
+	OpReturnValue: {"OpReturnValue", []int{}},
+	OpReturn:      {"OpReturn", []int{}},
+}
+
+// Lookup returns the definition of op.
+func Lookup(op byte) (*Definition, error) {
+	def, ok := definitions[Opcode(op)]
+	if !ok {
+		return nil, fmt.Errorf("opcode %d undefined", op)
+	}
+	return def, nil
+}
+
+func (op Opcode) String() string {
+	if def, ok := definitions[op]; ok {
+		return def.Name
+	}
+	return fmt.Sprintf("Opcode(%d)", byte(op))
+}
+
+// Make encodes the instruction op with the given operands. It returns
+// nil for an undefined opcode.
+func Make(op Opcode, operands ...int) []byte {
+	def, ok := definitions[op]
+	if !ok {
+		return nil
+	}
+	length := 1 + width(def)
+	instruction := make([]byte, length)
+	instruction[0] = byte(op)
+	offset := 1
+	for i, o := range operands {
+		switch def.OperandWidths[i] {
+		case 2:
+			binary.BigEndian.PutUint16(instruction[offset:], uint16(o))
+		case 1:
+			instruction[offset] = byte(o)
+		}
+		offset += def.OperandWidths[i]
+	}
+	return instruction
+}
+
+// ReadOperands decodes the operands of an instruction of def from the
+// start of ins, and returns them with the number of bytes they take.
+func ReadOperands(def *Definition, ins Instructions) ([]int, int) {
+	operands := make([]int, len(def.OperandWidths))
+	offset := 0
+	for i, w := range def.OperandWidths {
+		switch w {
+		case 2:
+			operands[i] = int(ReadUint16(ins[offset:]))
+		case 1:
+			operands[i] = int(ReadUint8(ins[offset:]))
+		}
+		offset += w
+	}
+	return operands, offset
+}
+
+// ReadUint16 decodes a two byte operand at the start of ins.
+func ReadUint16(ins Instructions) uint16 {
+	return binary.BigEndian.Uint16(ins)
+}
+
+// ReadUint8 decodes a one byte operand at the start of ins.
+func ReadUint8(ins Instructions) uint8 {
+	return ins[0]
+}
+
+// A Line gives the source position of the instructions from Offset up
+// to the next Line.
+type Line struct {
+	Offset int
+	Pos    token.Position
+}
+
+// A LineTable maps instructions to the source they were compiled from.
+// Its lines are sorted by offset.
+type LineTable []Line
+
+// Lookup returns the position of the instruction at offset, or the
+// zero position if the table does not cover it.
+func (t LineTable) Lookup(offset int) token.Position {
+	i := sort.Search(len(t), func(i int) bool { return t[i].Offset > offset })
+	if i == 0 {
+		return token.Position{}
+	}
+	return t[i-1].Pos
+}
+
+func (ins Instructions) String() string {
+	var out strings.Builder
+	Fprint(&out, ins, nil)
+	return out.String()
+}
+
+// Fprint writes the disassembly of ins to w, an instruction per line
+// with its offset, name and operands. If note is not nil, what it
+// returns for an instruction, such as the value of the constant it
+// loads, is printed after it.
+func Fprint(w io.Writer, ins Instructions, note func(op Opcode, operands []int) string) error {
+	for i := 0; i < len(ins); {
+		def, err := Lookup(ins[i])
+		if err != nil {
+			if _, err := fmt.Fprintf(w, "%04d ERROR: %s\n", i, err); err != nil {
+				return err
+			}
+			i++
+			continue
+		}
+		if len(ins[i+1:]) < width(def) {
+			_, err := fmt.Fprintf(w, "%04d ERROR: %s truncated\n", i, def.Name)
+			return err
+		}
+		operands, read := ReadOperands(def, ins[i+1:])
+		line := def.Name
+		for _, o := range operands {
+			line += " " + strconv.Itoa(o)
+		}
+		if note != nil {
+			if n := note(Opcode(ins[i]), operands); n != "" {
+				line = fmt.Sprintf("%-24s ; %s", line, n)
+			}
+		}
+		if _, err := fmt.Fprintf(w, "%04d %s\n", i, line); err != nil {
+			return err
+		}
+		i += 1 + read
+	}
+	return nil
+}
+
+// width returns the number of bytes the operands of def take.
+func width(def *Definition) int {
+	n := 0
+	for _, w := range def.OperandWidths {
+		n += w
+	}
+	return n
+}
@@ -0,0 +1,102 @@
+package code
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestMake(t *testing.T) {
+	tests := []struct {
+		op       Opcode
+		operands []int
+		expected []byte
+	}{
+		{OpConstant, []int{65534}, []byte{byte(OpConstant), 255, 254}},
+		{OpAdd, []int{}, []byte{byte(OpAdd)}},
+		{OpGetLocal, []int{255}, []byte{byte(OpGetLocal), 255}},
+		{OpClosure, []int{65534, 255}, []byte{byte(OpClosure), 255, 254, 255}},
+	}
+	for _, tt := range tests {
+		if got := Make(tt.op, tt.operands...); !bytes.Equal(got, tt.expected) {
+			t.Errorf("Make(%s, %v) = %v, want %v", tt.op, tt.operands, got, tt.expected)
+		}
+	}
+}
+
+func TestReadOperands(t *testing.T) {
+	tests := []struct {
+		op        Opcode
+		operands  []int
+		bytesRead int
+	}{
+		{OpConstant, []int{65535}, 2},
+		{OpGetLocal, []int{255}, 1},
+		{OpClosure, []int{65535, 255}, 3},
+		{OpPop, []int{}, 0},
+	}
+	for _, tt := range tests {
+		ins := Make(tt.op, tt.operands...)
+		def, err := Lookup(byte(tt.op))
+		if err != nil {
+			t.Fatalf("Lookup(%s): %v", tt.op, err)
+		}
+		operands, n := ReadOperands(def, ins[1:])
+		if n != tt.bytesRead || !reflect.DeepEqual(operands, tt.operands) {
+			t.Errorf("ReadOperands(%s) = %v, %d, want %v, %d", tt.op, operands, n, tt.operands, tt.bytesRead)
+		}
+	}
+}
+
+func TestLookupUndefined(t *testing.T) {
+	if _, err := Lookup(255); err == nil {
+		t.Errorf("Lookup(255) did not fail")
+	}
+}
+
+func TestInstructionsString(t *testing.T) {
+	ins := Instructions{}
+	for _, i := range [][]byte{
+		Make(OpAdd),
+		Make(OpGetLocal, 1),
+		Make(OpConstant, 2),
+		Make(OpConstant, 65535),
+		Make(OpClosure, 65535, 255),
+		{255},
+		Make(OpJump, 1)[:2],
+	} {
+		ins = append(ins, i...)
+	}
+	expected := `0000 OpAdd
+0001 OpGetLocal 1
+0003 OpConstant 2
+0006 OpConstant 65535
+0009 OpClosure 65535 255
+0013 ERROR: opcode 255 undefined
+0014 ERROR: OpJump truncated
+`
+	if got := ins.String(); got != expected {
+		t.Errorf("instructions wrongly formatted.\ngot=%q\nwant=%q", got, expected)
+	}
+}
+
+func TestLineTable(t *testing.T) {
+	lines := LineTable{{Offset: 0}, {Offset: 4}, {Offset: 9}}
+	for i := range lines {
+		lines[i].Pos.Line = i + 1
+	}
+	tests := []struct {
+		offset int
+		line   int
+	}{
+		{0, 1}, {3, 1}, {4, 2}, {8, 2}, {9, 3}, {100, 3},
+	}
+	for _, tt := range tests {
+		if got := lines.Lookup(tt.offset).Line; got != tt.line {
+			t.Errorf("Lookup(%d) line = %d, want %d", tt.offset, got, tt.line)
+		}
+	}
+	if got := (LineTable{}).Lookup(0); got.IsValid() {
+		t.Errorf("empty table gave %s", got)
+	}
+}
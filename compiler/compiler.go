@@ -0,0 +1,670 @@
+// Package compiler lowers a resolved program into bytecode for the
+// virtual machine.
+//
+// Every variable gets a slot of its own: variables declared outside of
+// functions are globals, the others are locals of the function that
+// declares them. Locals that closures capture are kept in cells, so
+// that the function and its closures share them.
+package compiler
+
+import (
+	"fmt"
+	"gointer/ast"
+	"gointer/code"
+	"gointer/i18n"
+	"gointer/object"
+	"gointer/resolver"
+	"math"
+)
+
+// Bytecode is the compiled program.
+type Bytecode struct {
+	Instructions code.Instructions
+	Constants    []object.Object
+	NumGlobals   int
+	// Lines gives the source positions of Instructions.
+	Lines code.LineTable
+}
+
+// A scope holds what is compiled for a function, or for the program
+// when fn is nil.
+type scope struct {
+	parent       *scope
+	fn           *ast.FunctionLiteral
+	instructions code.Instructions
+	lines        code.LineTable
+	numLocals    int
+	// depth is the number of values the instructions so far leave on
+	// the stack, above the locals.
+	depth int
+	// free lists the variables of enclosing functions the function
+	// uses, in the order of the cells of its closures.
+	free []*resolver.Object
+}
+
+// A loop is a loop being compiled, with the jumps out of it that are
+// patched once its end is known.
+type loop struct {
+	label string
+	// depth is the stack depth the jumps out of the loop must leave,
+	// which counts the iterator of a for-in loop.
+	depth     int
+	breaks    []int
+	continues []int
+}
+
+type compiler struct {
+	info      *resolver.Info
+	constants []object.Object
+	globals   map[*resolver.Object]int
+	locals    map[*resolver.Object]int
+	// owner maps variables to the function that declares them, nil for
+	// globals.
+	owner    map[*resolver.Object]*ast.FunctionLiteral
+	captured map[*resolver.Object]bool
+	scope    *scope
+	loops    []*loop
+	// node is the node being compiled, whose position the
+	// instructions emitted get.
+	node   ast.Node
+	lang   i18n.Lang
+	errors []string
+}
+
+type Option func(*compiler)
+
+// WithLang selects the language errors are reported in.
+func WithLang(lang i18n.Lang) Option {
+	return func(c *compiler) {
+		c.lang = lang
+	}
+}
+
+// Compile compiles program, which info must be the resolution of, and
+// returns the result along with the errors found, each prefixed with
+// line:column.
+func Compile(program *ast.Program, info *resolver.Info, opts ...Option) (*Bytecode, []string) {
+	c := &compiler{
+		info:     info,
+		globals:  map[*resolver.Object]int{},
+		locals:   map[*resolver.Object]int{},
+		owner:    map[*resolver.Object]*ast.FunctionLiteral{},
+		captured: map[*resolver.Object]bool{},
+		scope:    &scope{},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.findCaptured(program, nil)
+	for _, s := range program.Statments {
+		c.topLevel(s)
+	}
+	return &Bytecode{
+		Instructions: c.scope.instructions,
+		Constants:    c.constants,
+		NumGlobals:   len(c.globals),
+		Lines:        c.scope.lines,
+	}, c.errors
+}
+
+func (c *compiler) error(n ast.Node, msg i18n.MessageID, args ...any) {
+	c.errors = append(c.errors, fmt.Sprintf("%s: %s", n.Pos(), i18n.Sprintf(c.lang, msg, args...)))
+}
+
+// findCaptured records the function that declares each variable and
+// which variables are used by functions nested in it. fn is the
+// function node is in.
+func (c *compiler) findCaptured(node ast.Node, fn *ast.FunctionLiteral) {
+	switch n := node.(type) {
+	case *ast.FunctionLiteral:
+		for _, p := range n.Parameters {
+			c.owner[c.info.Defs[p]] = n
+		}
+		fn = n
+	case *ast.LetStatment:
+		c.owner[c.info.Defs[n.Name]] = fn
+	case *ast.ForInStatment:
+		c.owner[c.info.Defs[n.Variable]] = fn
+	case *ast.Identifier:
+		if obj, ok := c.info.Uses[n]; ok {
+			if owner := c.owner[obj]; owner != nil && owner != fn {
+				c.captured[obj] = true
+			}
+		}
+	}
+	for _, child := range ast.Children(node) {
+		c.findCaptured(child, fn)
+	}
+}
+
+func (c *compiler) emit(op code.Opcode, operands ...int) int {
+	pos := len(c.scope.instructions)
+	if c.node != nil {
+		lines := c.scope.lines
+		if at := c.node.Pos(); len(lines) == 0 || lines[len(lines)-1].Pos != at {
+			c.scope.lines = append(lines, code.Line{Offset: pos, Pos: at})
+		}
+	}
+	c.scope.instructions = append(c.scope.instructions, code.Make(op, operands...)...)
+	c.scope.depth += stackEffect(op, operands)
+	return pos
+}
+
+// stackEffect returns how many values op pushes minus how many it pops
+// when it does not jump. OpIterNext pushes nothing when it jumps out of
+// its loop, which leaves the loop at the depth it started at anyway.
+func stackEffect(op code.Opcode, operands []int) int {
+	switch op {
+	case code.OpConstant, code.OpDup, code.OpTrue, code.OpFalse, code.OpNull,
+		code.OpGetGlobal, code.OpGetLocal, code.OpGetCell, code.OpGetFree,
+		code.OpFreeCell, code.OpGetBuiltin, code.OpIterNext:
+		return 1
+	case code.OpPop, code.OpResult, code.OpJumpNotTruthy, code.OpJumpTruthy,
+		code.OpSetGlobal, code.OpSetLocal, code.OpSetCell, code.OpSetFree,
+		code.OpIndex, code.OpReturnValue,
+		code.OpAdd, code.OpSub, code.OpMul, code.OpDiv, code.OpMod, code.OpPow,
+		code.OpBitAnd, code.OpBitOr, code.OpBitXor, code.OpShl, code.OpShr,
+		code.OpEqual, code.OpNotEqual, code.OpGreaterThan, code.OpGreaterEqual,
+		code.OpLessThan, code.OpLessEqual:
+		return -1
+	case code.OpClosure:
+		return 1 - operands[1]
+	case code.OpCall:
+		return -operands[0]
+	}
+	return 0
+}
+
+// at makes n the node being compiled until the function it returns is
+// called.
+func (c *compiler) at(n ast.Node) func() {
+	prev := c.node
+	c.node = n
+	return func() { c.node = prev }
+}
+
+// pos returns the offset of the next instruction.
+func (c *compiler) pos() int {
+	return len(c.scope.instructions)
+}
+
+// patch sets the target of the jump at pos to target.
+func (c *compiler) patch(pos, target int) {
+	op := code.Opcode(c.scope.instructions[pos])
+	copy(c.scope.instructions[pos:], code.Make(op, target))
+}
+
+func (c *compiler) addConstant(n ast.Node, obj object.Object) int {
+	if len(c.constants) > math.MaxUint16 {
+		c.error(n, i18n.TooManyConstants, math.MaxUint16+1)
+		return 0
+	}
+	c.constants = append(c.constants, obj)
+	return len(c.constants) - 1
+}
+
+// declare allocates the slot of the variable id declares.
+func (c *compiler) declare(id *ast.Identifier) *resolver.Object {
+	obj := c.info.Defs[id]
+	if c.owner[obj] == nil {
+		c.globals[obj] = len(c.globals)
+		return obj
+	}
+	if c.scope.numLocals > math.MaxUint8 {
+		c.error(id, i18n.TooManyLocals, math.MaxUint8+1)
+	}
+	c.locals[obj] = c.scope.numLocals
+	c.scope.numLocals++
+	return obj
+}
+
+// freeIndex returns the index of the cell of obj among the ones the
+// closures of s capture.
+func freeIndex(s *scope, obj *resolver.Object) int {
+	for i, free := range s.free {
+		if free == obj {
+			return i
+		}
+	}
+	s.free = append(s.free, obj)
+	return len(s.free) - 1
+}
+
+// load pushes the value of the variable id refers to.
+func (c *compiler) load(id *ast.Identifier) {
+	obj, ok := c.info.Uses[id]
+	if !ok {
+		c.error(id, i18n.UndefinedName, id.Value)
+		return
+	}
+	switch owner := c.owner[obj]; {
+	case obj.Kind == resolver.Builtin:
+		i, ok := object.LookupBuiltin(obj.Name)
+		if !ok {
+			c.error(id, i18n.UndefinedName, id.Value)
+			return
+		}
+		c.emit(code.OpGetBuiltin, i)
+	case owner == nil:
+		c.emit(code.OpGetGlobal, c.globals[obj])
+	case owner != c.scope.fn:
+		c.emit(code.OpGetFree, freeIndex(c.scope, obj))
+	case c.captured[obj]:
+		c.emit(code.OpGetCell, c.locals[obj])
+	default:
+		c.emit(code.OpGetLocal, c.locals[obj])
+	}
+}
+
+// store pops the value on top of the stack into obj.
+func (c *compiler) store(obj *resolver.Object) {
+	switch owner := c.owner[obj]; {
+	case owner == nil:
+		c.emit(code.OpSetGlobal, c.globals[obj])
+	case owner != c.scope.fn:
+		c.emit(code.OpSetFree, freeIndex(c.scope, obj))
+	case c.captured[obj]:
+		c.emit(code.OpSetCell, c.locals[obj])
+	default:
+		c.emit(code.OpSetLocal, c.locals[obj])
+	}
+}
+
+// newCell gives obj, just declared, a new cell if closures capture it.
+func (c *compiler) newCell(obj *resolver.Object) {
+	if c.captured[obj] && c.owner[obj] != nil {
+		c.emit(code.OpNewCell, c.locals[obj])
+	}
+}
+
+// topLevel compiles a statement of the program itself, which sets the
+// value of the program: the value of an expression statement, or null
+// for the other statements but return, which ends the program.
+func (c *compiler) topLevel(s ast.Statment) {
+	if s, ok := s.(*ast.ExpressionStatment); ok {
+		defer c.at(s)()
+		c.expr(s.Expression)
+		c.emit(code.OpResult)
+		return
+	}
+	c.stmt(s)
+	if _, ok := s.(*ast.ReturnStatment); !ok {
+		defer c.at(s)()
+		c.emit(code.OpNull)
+		c.emit(code.OpResult)
+	}
+}
+
+func (c *compiler) stmts(list []ast.Statment) {
+	for _, s := range list {
+		c.stmt(s)
+	}
+}
+
+func (c *compiler) stmt(s ast.Statment) {
+	defer c.at(s)()
+	switch s := s.(type) {
+	case *ast.LetStatment:
+		obj := c.declare(s.Name)
+		// the cell exists before the value, so that a closure in it
+		// can refer to the variable
+		c.newCell(obj)
+		if fn, ok := s.Value.(*ast.FunctionLiteral); ok {
+			c.function(fn, s.Name.Value)
+		} else {
+			c.expr(s.Value)
+		}
+		c.store(obj)
+	case *ast.ReturnStatment:
+		if s.ReturnValue != nil {
+			c.expr(s.ReturnValue)
+		} else {
+			c.emit(code.OpNull)
+		}
+		c.emit(code.OpReturnValue)
+	case *ast.ExpressionStatment:
+		c.expr(s.Expression)
+		c.emit(code.OpPop)
+	case *ast.BlockStatment:
+		c.stmts(s.Statments)
+	case *ast.WhileStatment:
+		l := c.pushLoop(s.Label)
+		start := c.pos()
+		c.expr(s.Condition)
+		exit := c.emit(code.OpJumpNotTruthy, 9999)
+		c.stmt(s.Body)
+		c.emit(code.OpJump, start)
+		c.popLoop(l, start, c.pos())
+		c.patch(exit, c.pos())
+	case *ast.ForStatment:
+		if s.Init != nil {
+			c.stmt(s.Init)
+		}
+		l := c.pushLoop(s.Label)
+		start := c.pos()
+		exit := -1
+		if s.Condition != nil {
+			c.expr(s.Condition)
+			exit = c.emit(code.OpJumpNotTruthy, 9999)
+		}
+		c.stmt(s.Body)
+		post := c.pos()
+		if s.Post != nil {
+			c.expr(s.Post)
+			c.emit(code.OpPop)
+		}
+		c.emit(code.OpJump, start)
+		c.popLoop(l, post, c.pos())
+		if exit >= 0 {
+			c.patch(exit, c.pos())
+		}
+	case *ast.ForInStatment:
+		c.expr(s.Iterable)
+		c.emit(code.OpIter)
+		obj := c.declare(s.Variable)
+		l := c.pushLoop(s.Label)
+		next := c.emit(code.OpIterNext, 9999)
+		c.newCell(obj)
+		c.store(obj)
+		c.stmt(s.Body)
+		c.emit(code.OpJump, next)
+		c.popLoop(l, next, c.pos())
+		c.patch(next, c.pos())
+		// the iterator
+		c.emit(code.OpPop)
+	case *ast.BreakStatment:
+		l := c.leaveLoops(s.Label)
+		l.breaks = append(l.breaks, c.jumpOut(l))
+	case *ast.ContinueStatment:
+		l := c.leaveLoops(s.Label)
+		l.continues = append(l.continues, c.jumpOut(l))
+	default:
+		panic(fmt.Sprintf("compiler: unexpected node type %T", s))
+	}
+}
+
+func (c *compiler) pushLoop(label *ast.Identifier) *loop {
+	l := &loop{depth: c.scope.depth}
+	if label != nil {
+		l.label = label.Value
+	}
+	c.loops = append(c.loops, l)
+	return l
+}
+
+// popLoop patches the jumps of l, the innermost loop, to next and end.
+func (c *compiler) popLoop(l *loop, next, end int) {
+	for _, pos := range l.breaks {
+		c.patch(pos, end)
+	}
+	for _, pos := range l.continues {
+		c.patch(pos, next)
+	}
+	c.loops = c.loops[:len(c.loops)-1]
+}
+
+// leaveLoops returns the loop with the given label, the innermost one
+// if label is nil. The parser made sure it exists.
+func (c *compiler) leaveLoops(label *ast.Identifier) *loop {
+	for i := len(c.loops) - 1; i >= 0; i-- {
+		if l := c.loops[i]; label == nil || l.label == label.Value {
+			return l
+		}
+	}
+	panic("compiler: break or continue outside of a loop")
+}
+
+// jumpOut emits a jump out of the body of l, to be patched, and returns
+// its position. It first pops what the jump leaves on the stack: the
+// operands of the expressions it is in, and the iterators of the for-in
+// loops inside l.
+func (c *compiler) jumpOut(l *loop) int {
+	depth := c.scope.depth
+	for c.scope.depth > l.depth {
+		c.emit(code.OpPop)
+	}
+	pos := c.emit(code.OpJump, 9999)
+	// what follows is only reached without jumping
+	c.scope.depth = depth
+	return pos
+}
+
+// block pushes the value of b: the value of its last statement if that
+// is an expression, null otherwise.
+func (c *compiler) block(b *ast.BlockStatment) {
+	if b == nil || len(b.Statments) == 0 {
+		c.emit(code.OpNull)
+		return
+	}
+	c.stmts(b.Statments[:len(b.Statments)-1])
+	last := b.Statments[len(b.Statments)-1]
+	if s, ok := last.(*ast.ExpressionStatment); ok && s.Expression != nil {
+		c.expr(s.Expression)
+		return
+	}
+	c.stmt(last)
+	c.emit(code.OpNull)
+}
+
+var infixOps = map[string]code.Opcode{
+	"+":  code.OpAdd,
+	"-":  code.OpSub,
+	"*":  code.OpMul,
+	"/":  code.OpDiv,
+	"%":  code.OpMod,
+	"**": code.OpPow,
+	"&":  code.OpBitAnd,
+	"|":  code.OpBitOr,
+	"^":  code.OpBitXor,
+	"<<": code.OpShl,
+	">>": code.OpShr,
+	"==": code.OpEqual,
+	"!=": code.OpNotEqual,
+	">":  code.OpGreaterThan,
+	">=": code.OpGreaterEqual,
+	"<":  code.OpLessThan,
+	"<=": code.OpLessEqual,
+}
+
+var prefixOps = map[string]code.Opcode{
+	"-": code.OpMinus,
+	"!": code.OpBang,
+	"~": code.OpBitNot,
+}
+
+func (c *compiler) expr(e ast.Expression) {
+	defer c.at(e)()
+	switch e := e.(type) {
+	case *ast.IntegerLiteral:
+		c.emit(code.OpConstant, c.addConstant(e, &object.Integer{Value: e.Value}))
+	case *ast.StringLiteral:
+		c.emit(code.OpConstant, c.addConstant(e, &object.String{Value: e.Value}))
+	case *ast.Boolean:
+		if e.Value {
+			c.emit(code.OpTrue)
+		} else {
+			c.emit(code.OpFalse)
+		}
+	case *ast.Identifier:
+		c.load(e)
+	case *ast.PrefixExpression:
+		c.expr(e.Right)
+		op, ok := prefixOps[e.Operator]
+		if !ok {
+			c.error(e, i18n.UnknownOperator, e.Operator)
+			return
+		}
+		c.emit(op)
+	case *ast.InfixExpression:
+		c.infix(e)
+	case *ast.PostfixExpression:
+		op, ok := map[string]code.Opcode{"++": code.OpAdd, "--": code.OpSub}[e.Operator]
+		if !ok {
+			c.error(e, i18n.UnknownOperator, e.Operator)
+			return
+		}
+		// x++ is the value of x before the increment
+		c.update(e.Left, func() {
+			c.emit(code.OpDup)
+			c.emit(code.OpConstant, c.addConstant(e, &object.Integer{Value: 1}))
+			c.emit(op)
+		}, false)
+	case *ast.AssignExpression:
+		if e.Operator == "=" {
+			if obj := c.assignee(e.Target); obj != nil {
+				c.expr(e.Value)
+				c.emit(code.OpDup)
+				c.store(obj)
+			}
+			return
+		}
+		op, ok := infixOps[e.Operator[:len(e.Operator)-1]]
+		if !ok {
+			c.error(e, i18n.UnknownOperator, e.Operator)
+			return
+		}
+		c.update(e.Target, func() {
+			c.expr(e.Value)
+			c.emit(op)
+		}, true)
+	case *ast.IndexExpression:
+		c.expr(e.Left)
+		c.expr(e.Index)
+		c.emit(code.OpIndex)
+	case *ast.CallExpression:
+		c.expr(e.Function)
+		for _, a := range e.Arguments {
+			c.expr(a)
+		}
+		c.emit(code.OpCall, len(e.Arguments))
+	case *ast.IfExpression:
+		c.expr(e.Condition)
+		jumpElse := c.emit(code.OpJumpNotTruthy, 9999)
+		depth := c.scope.depth
+		c.block(e.Consequence)
+		jumpEnd := c.emit(code.OpJump, 9999)
+		// the alternative starts out without the value of the
+		// consequence
+		c.scope.depth = depth
+		c.patch(jumpElse, c.pos())
+		if e.Alternative != nil {
+			c.block(e.Alternative)
+		} else {
+			c.emit(code.OpNull)
+		}
+		c.patch(jumpEnd, c.pos())
+	case *ast.FunctionLiteral:
+		c.function(e, "")
+	default:
+		panic(fmt.Sprintf("compiler: unexpected node type %T", e))
+	}
+}
+
+// update compiles an assignment to target that computes the new value
+// from the old one on top of the stack with compute. The value of the
+// assignment is the new value if result is set, the old one otherwise.
+func (c *compiler) update(target ast.Expression, compute func(), result bool) {
+	obj := c.assignee(target)
+	if obj == nil {
+		return
+	}
+	c.load(target.(*ast.Identifier))
+	compute()
+	if result {
+		c.emit(code.OpDup)
+	}
+	c.store(obj)
+}
+
+// assignee returns the variable target assigns to, or reports why it
+// cannot be assigned to and returns nil.
+func (c *compiler) assignee(target ast.Expression) *resolver.Object {
+	id, ok := target.(*ast.Identifier)
+	if !ok {
+		// there is nothing to assign to an index of yet
+		c.error(target, i18n.InvalidAssignTarget, target)
+		return nil
+	}
+	obj, ok := c.info.Uses[id]
+	switch {
+	case !ok:
+		c.error(id, i18n.UndefinedName, id.Value)
+		return nil
+	case obj.Kind == resolver.Builtin:
+		c.error(id, i18n.AssignBuiltin, id.Value)
+		return nil
+	}
+	return obj
+}
+
+func (c *compiler) infix(e *ast.InfixExpression) {
+	switch e.Operator {
+	case "&&", "||":
+		// a && b jumps to false as soon as an operand is falsy, a || b
+		// to true as soon as one is truthy
+		jump, short, long := code.OpJumpNotTruthy, code.OpFalse, code.OpTrue
+		if e.Operator == "||" {
+			jump, short, long = code.OpJumpTruthy, code.OpTrue, code.OpFalse
+		}
+		c.expr(e.Left)
+		first := c.emit(jump, 9999)
+		c.expr(e.Right)
+		second := c.emit(jump, 9999)
+		depth := c.scope.depth
+		c.emit(long)
+		end := c.emit(code.OpJump, 9999)
+		c.scope.depth = depth
+		c.patch(first, c.pos())
+		c.patch(second, c.pos())
+		c.emit(short)
+		c.patch(end, c.pos())
+		return
+	}
+	op, ok := infixOps[e.Operator]
+	if !ok {
+		c.error(e, i18n.UnknownOperator, e.Operator)
+		return
+	}
+	c.expr(e.Left)
+	c.expr(e.Right)
+	c.emit(op)
+}
+
+// function compiles fn, bound to a variable called name if not empty,
+// and pushes a closure of it.
+func (c *compiler) function(fn *ast.FunctionLiteral, name string) {
+	defer c.at(fn)()
+	c.scope = &scope{parent: c.scope, fn: fn}
+	loops := c.loops
+	c.loops = nil
+	for _, p := range fn.Parameters {
+		obj := c.declare(p)
+		if c.captured[obj] {
+			c.emit(code.OpGetLocal, c.locals[obj])
+			c.emit(code.OpNewCell, c.locals[obj])
+			c.emit(code.OpSetCell, c.locals[obj])
+		}
+	}
+	c.block(fn.Body)
+	c.emit(code.OpReturnValue)
+
+	s := c.scope
+	c.scope = s.parent
+	c.loops = loops
+	compiled := &object.CompiledFunction{
+		Instructions:  s.instructions,
+		NumLocals:     s.numLocals,
+		NumParameters: len(fn.Parameters),
+		Name:          name,
+		Lines:         s.lines,
+	}
+	for _, obj := range s.free {
+		if c.owner[obj] == c.scope.fn {
+			// the cell itself, not its value
+			c.emit(code.OpGetLocal, c.locals[obj])
+		} else {
+			c.emit(code.OpFreeCell, freeIndex(c.scope, obj))
+		}
+	}
+	c.emit(code.OpClosure, c.addConstant(fn, compiled), len(s.free))
+}
@@ -0,0 +1,422 @@
+package compiler
+
+import (
+	"bytes"
+	"gointer/code"
+	"gointer/lexer"
+	"gointer/object"
+	"gointer/parser"
+	"gointer/resolver"
+	"reflect"
+	"testing"
+)
+
+func compile(t *testing.T, input string) (*Bytecode, []string) {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if errs := p.Errors(); len(errs) != 0 {
+		t.Fatalf("parse errors in %q: %v", input, errs)
+	}
+	info, errs := resolver.Resolve(program)
+	if len(errs) != 0 {
+		t.Fatalf("resolve errors in %q: %v", input, errs)
+	}
+	return Compile(program, info)
+}
+
+func concat(ins ...[]byte) code.Instructions {
+	var out code.Instructions
+	for _, i := range ins {
+		out = append(out, i...)
+	}
+	return out
+}
+
+// function builds the constant a function literal compiles to.
+func function(numLocals, numParams int, name string, ins ...[]byte) *object.CompiledFunction {
+	return &object.CompiledFunction{
+		Instructions:  concat(ins...),
+		NumLocals:     numLocals,
+		NumParameters: numParams,
+		Name:          name,
+	}
+}
+
+// withoutLines returns constants with the line tables of functions
+// removed, which the tests check separately.
+func withoutLines(constants []object.Object) []object.Object {
+	var out []object.Object
+	for _, c := range constants {
+		if fn, ok := c.(*object.CompiledFunction); ok {
+			cp := *fn
+			cp.Lines = nil
+			c = &cp
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+func integer(v int64) *object.Integer {
+	return &object.Integer{Value: v}
+}
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		input        string
+		constants    []object.Object
+		instructions code.Instructions
+	}{
+		{
+			"1 + 2; -3",
+			[]object.Object{integer(1), integer(2), integer(3)},
+			concat(
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpAdd),
+				code.Make(code.OpResult),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpMinus),
+				code.Make(code.OpResult),
+			),
+		},
+		{
+			"1 < 2; !true",
+			[]object.Object{integer(1), integer(2)},
+			concat(
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpLessThan),
+				code.Make(code.OpResult),
+				code.Make(code.OpTrue),
+				code.Make(code.OpBang),
+				code.Make(code.OpResult),
+			),
+		},
+		{
+			"true && false",
+			nil,
+			concat(
+				code.Make(code.OpTrue),
+				code.Make(code.OpJumpNotTruthy, 12),
+				code.Make(code.OpFalse),
+				code.Make(code.OpJumpNotTruthy, 12),
+				code.Make(code.OpTrue),
+				code.Make(code.OpJump, 13),
+				code.Make(code.OpFalse),
+				code.Make(code.OpResult),
+			),
+		},
+		{
+			"if (true) { 10 }; 20",
+			[]object.Object{integer(10), integer(20)},
+			concat(
+				code.Make(code.OpTrue),
+				code.Make(code.OpJumpNotTruthy, 10),
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpJump, 11),
+				code.Make(code.OpNull),
+				code.Make(code.OpResult),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpResult),
+			),
+		},
+		{
+			"let x = 1; let y = x; y = 2",
+			[]object.Object{integer(1), integer(2)},
+			concat(
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpNull),
+				code.Make(code.OpResult),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpSetGlobal, 1),
+				code.Make(code.OpNull),
+				code.Make(code.OpResult),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpDup),
+				code.Make(code.OpSetGlobal, 1),
+				code.Make(code.OpResult),
+			),
+		},
+		{
+			"let x = 1; x += 2; x++",
+			[]object.Object{integer(1), integer(2), integer(1)},
+			concat(
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpNull),
+				code.Make(code.OpResult),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpAdd),
+				code.Make(code.OpDup),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpResult),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpDup),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpAdd),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpResult),
+			),
+		},
+		{
+			"while (true) { break; }",
+			nil,
+			concat(
+				code.Make(code.OpTrue),
+				code.Make(code.OpJumpNotTruthy, 10),
+				code.Make(code.OpJump, 10),
+				code.Make(code.OpJump, 0),
+				code.Make(code.OpNull),
+				code.Make(code.OpResult),
+			),
+		},
+		{
+			`for (c in "ab") { continue; }`,
+			[]object.Object{&object.String{Value: "ab"}},
+			concat(
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpIter),
+				code.Make(code.OpIterNext, 16),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpJump, 4),
+				code.Make(code.OpJump, 4),
+				code.Make(code.OpPop),
+				code.Make(code.OpNull),
+				code.Make(code.OpResult),
+			),
+		},
+		{
+			`len("a")`,
+			[]object.Object{&object.String{Value: "a"}},
+			concat(
+				code.Make(code.OpGetBuiltin, 0),
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpCall, 1),
+				code.Make(code.OpResult),
+			),
+		},
+		{
+			"let add = fn(a, b) { let c = a + b; c }; add(1, 2)",
+			[]object.Object{
+				function(3, 2, "add",
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpGetLocal, 1),
+					code.Make(code.OpAdd),
+					code.Make(code.OpSetLocal, 2),
+					code.Make(code.OpGetLocal, 2),
+					code.Make(code.OpReturnValue),
+				),
+				integer(1),
+				integer(2),
+			},
+			concat(
+				code.Make(code.OpClosure, 0, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpNull),
+				code.Make(code.OpResult),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpCall, 2),
+				code.Make(code.OpResult),
+			),
+		},
+		{
+			"fn() { }",
+			[]object.Object{
+				function(0, 0, "",
+					code.Make(code.OpNull),
+					code.Make(code.OpReturnValue),
+				),
+			},
+			concat(
+				code.Make(code.OpClosure, 0, 0),
+				code.Make(code.OpResult),
+			),
+		},
+	}
+	for _, tt := range tests {
+		bytecode, errs := compile(t, tt.input)
+		if len(errs) != 0 {
+			t.Errorf("Compile(%q) errors: %q", tt.input, errs)
+			continue
+		}
+		if !reflect.DeepEqual(bytecode.Instructions, tt.instructions) {
+			t.Errorf("Compile(%q) instructions wrong.\ngot= %v\nwant=%v", tt.input, bytecode.Instructions, tt.instructions)
+		}
+		if !reflect.DeepEqual(withoutLines(bytecode.Constants), tt.constants) {
+			t.Errorf("Compile(%q) constants wrong.\ngot= %v\nwant=%v", tt.input, bytecode.Constants, tt.constants)
+		}
+	}
+}
+
+func TestClosures(t *testing.T) {
+	tests := []struct {
+		input     string
+		constants []object.Object
+	}{
+		{
+			// a captured parameter is moved into a cell on entry
+			"fn(a) { fn(b) { a + b } }",
+			[]object.Object{
+				function(1, 1, "",
+					code.Make(code.OpGetFree, 0),
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpAdd),
+					code.Make(code.OpReturnValue),
+				),
+				function(1, 1, "",
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpNewCell, 0),
+					code.Make(code.OpSetCell, 0),
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpClosure, 0, 1),
+					code.Make(code.OpReturnValue),
+				),
+			},
+		},
+		{
+			// the innermost function gets a from the free cells of the
+			// one in between
+			"fn(a) { fn() { fn() { a = 1 } } }",
+			[]object.Object{
+				integer(1),
+				function(0, 0, "",
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpDup),
+					code.Make(code.OpSetFree, 0),
+					code.Make(code.OpReturnValue),
+				),
+				function(0, 0, "",
+					code.Make(code.OpFreeCell, 0),
+					code.Make(code.OpClosure, 1, 1),
+					code.Make(code.OpReturnValue),
+				),
+				function(1, 1, "",
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpNewCell, 0),
+					code.Make(code.OpSetCell, 0),
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpClosure, 2, 1),
+					code.Make(code.OpReturnValue),
+				),
+			},
+		},
+		{
+			// the cell exists before the function that refers to it
+			"fn() { let f = fn() { f() }; f }",
+			[]object.Object{
+				function(0, 0, "f",
+					code.Make(code.OpGetFree, 0),
+					code.Make(code.OpCall, 0),
+					code.Make(code.OpReturnValue),
+				),
+				function(1, 0, "",
+					code.Make(code.OpNewCell, 0),
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpClosure, 0, 1),
+					code.Make(code.OpSetCell, 0),
+					code.Make(code.OpGetCell, 0),
+					code.Make(code.OpReturnValue),
+				),
+			},
+		},
+	}
+	for _, tt := range tests {
+		bytecode, errs := compile(t, tt.input)
+		if len(errs) != 0 {
+			t.Errorf("Compile(%q) errors: %q", tt.input, errs)
+			continue
+		}
+		if !reflect.DeepEqual(withoutLines(bytecode.Constants), tt.constants) {
+			t.Errorf("Compile(%q) constants wrong.\ngot= %v\nwant=%v", tt.input, bytecode.Constants, tt.constants)
+		}
+	}
+}
+
+func TestLines(t *testing.T) {
+	bytecode, _ := compile(t, "let f = fn(x) {\n  x + 1\n};\nf(2)")
+	fn := bytecode.Constants[1].(*object.CompiledFunction)
+	// OpGetLocal 0, OpConstant 0, OpAdd, OpReturnValue
+	tests := []struct {
+		lines    code.LineTable
+		offset   int
+		expected string
+	}{
+		{fn.Lines, 0, "2:3"},
+		{fn.Lines, 5, "2:3"},
+		{fn.Lines, 6, "1:9"},
+		{bytecode.Lines, 0, "1:9"},
+		{bytecode.Lines, 7, "1:1"},
+		{bytecode.Lines, 9, "4:1"},
+	}
+	for _, tt := range tests {
+		if got := tt.lines.Lookup(tt.offset).String(); got != tt.expected {
+			t.Errorf("Lookup(%d) = %s, want %s", tt.offset, got, tt.expected)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let s = \"ab\"; s[0] = \"c\"", []string{"1:15: cannot assign to (s[0])"}},
+		{"let s = \"ab\"; s[0]++", []string{"1:15: cannot assign to (s[0])"}},
+		{"puts = 1; len += 1; first++", []string{
+			"1:1: cannot assign to builtin puts",
+			"1:11: cannot assign to builtin len",
+			"1:21: cannot assign to builtin first",
+		}},
+	}
+	for _, tt := range tests {
+		_, errs := compile(t, tt.input)
+		if !reflect.DeepEqual(errs, tt.expected) {
+			t.Errorf("Compile(%q) errors = %q, want %q", tt.input, errs, tt.expected)
+		}
+	}
+}
+
+func TestBuiltins(t *testing.T) {
+	var names []string
+	for _, b := range object.Builtins {
+		names = append(names, b.Name)
+	}
+	if !reflect.DeepEqual(names, resolver.DefaultBuiltins) {
+		t.Errorf("object.Builtins = %v, resolver.DefaultBuiltins = %v", names, resolver.DefaultBuiltins)
+	}
+}
+
+func TestDisassemble(t *testing.T) {
+	bytecode, _ := compile(t, `let greet = fn(name) { "hi " + name }; puts(greet("you"))`)
+	var out bytes.Buffer
+	if err := Disassemble(&out, bytecode); err != nil {
+		t.Fatal(err)
+	}
+	expected := `main (1 globals):
+0000 OpClosure 1 0            ; fn greet
+0004 OpSetGlobal 0
+0007 OpNull
+0008 OpResult
+0009 OpGetBuiltin 4           ; puts
+0011 OpGetGlobal 0
+0014 OpConstant 2             ; "you"
+0017 OpCall 1
+0019 OpCall 1
+0021 OpResult
+
+fn greet (constant 1, 1 params, 1 locals):
+0000 OpConstant 0             ; "hi "
+0003 OpGetLocal 0
+0005 OpAdd
+0006 OpReturnValue
+`
+	if got := out.String(); got != expected {
+		t.Errorf("Disassemble wrong.\ngot=\n%s\nwant=\n%s", got, expected)
+	}
+}
@@ -0,0 +1,63 @@
+package compiler
+
+import (
+	"fmt"
+	"gointer/code"
+	"gointer/object"
+	"io"
+	"strconv"
+)
+
+// Disassemble writes the instructions of b to w, followed by those of
+// every function it defines. The constants and builtins instructions
+// refer to are printed next to them.
+func Disassemble(w io.Writer, b *Bytecode) error {
+	note := func(op code.Opcode, operands []int) string {
+		switch op {
+		case code.OpConstant, code.OpClosure:
+			if operands[0] < len(b.Constants) {
+				return describe(b.Constants[operands[0]])
+			}
+		case code.OpGetBuiltin:
+			if operands[0] < len(object.Builtins) {
+				return object.Builtins[operands[0]].Name
+			}
+		}
+		return ""
+	}
+	if _, err := fmt.Fprintf(w, "main (%d globals):\n", b.NumGlobals); err != nil {
+		return err
+	}
+	if err := code.Fprint(w, b.Instructions, note); err != nil {
+		return err
+	}
+	for i, c := range b.Constants {
+		fn, ok := c.(*object.CompiledFunction)
+		if !ok {
+			continue
+		}
+		_, err := fmt.Fprintf(w, "\n%s (constant %d, %d params, %d locals):\n",
+			describe(fn), i, fn.NumParameters, fn.NumLocals)
+		if err != nil {
+			return err
+		}
+		if err := code.Fprint(w, fn.Instructions, note); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// describe returns how a constant is shown in disassemblies.
+func describe(c object.Object) string {
+	switch c := c.(type) {
+	case *object.String:
+		return strconv.Quote(c.Value)
+	case *object.CompiledFunction:
+		if c.Name == "" {
+			return "fn"
+		}
+		return "fn " + c.Name
+	}
+	return c.Inspect()
+}
@@ -0,0 +1,224 @@
+// Package conformance holds the tests every engine must pass: the vm
+// and the tree-walking evaluator run the same programs to the same
+// results, errors and output.
+package conformance
+
+import (
+	"bytes"
+	"gointer/ast"
+	"gointer/compiler"
+	"gointer/evaluator"
+	"gointer/lexer"
+	"gointer/optimize"
+	"gointer/parser"
+	"gointer/resolver"
+	"gointer/vm"
+	"strings"
+	"testing"
+)
+
+// An engine runs a resolved program and returns its result, or the
+// error, along with what it printed.
+type engine struct {
+	name string
+	exec func(program *ast.Program, info *resolver.Info) (string, string)
+}
+
+var engines = []engine{
+	{"vm", func(program *ast.Program, info *resolver.Info) (string, string) {
+		// compile errors are reported like runtime errors, since the
+		// evaluator only finds them when it gets there
+		bytecode, errs := compiler.Compile(program, info)
+		if len(errs) != 0 {
+			return strings.Join(errs, "\n"), ""
+		}
+		var out bytes.Buffer
+		machine := vm.New(bytecode, vm.WithOutput(&out))
+		if err := machine.Run(); err != nil {
+			return err.Error(), out.String()
+		}
+		return machine.Result().Inspect(), out.String()
+	}},
+	{"eval", func(program *ast.Program, info *resolver.Info) (string, string) {
+		var out bytes.Buffer
+		ev := evaluator.New(program, info, evaluator.WithOutput(&out))
+		if err := ev.Run(); err != nil {
+			return err.Error(), out.String()
+		}
+		return ev.Result().Inspect(), out.String()
+	}},
+}
+
+// run parses and resolves input, optimized if optimized is set, and
+// runs it with e.
+func (e engine) run(t *testing.T, input string, optimized bool) (string, string) {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if errs := p.Errors(); len(errs) != 0 {
+		t.Fatalf("parse errors in %q: %v", input, errs)
+	}
+	if optimized {
+		program = optimize.Run(program)
+	}
+	info, errs := resolver.Resolve(program)
+	if len(errs) != 0 {
+		t.Fatalf("resolve errors in %q: %v", input, errs)
+	}
+	return e.exec(program, info)
+}
+
+// describe returns the name of e for errors.
+func (e engine) describe(optimized bool) string {
+	if optimized {
+		return e.name + " (optimized)"
+	}
+	return e.name
+}
+
+// TestConformance checks the results every engine must agree on.
+func TestConformance(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		// arithmetic
+		{"1 + 2 * 3", "7"},
+		{"-(5 + 5) * 2", "-20"},
+		{"7 / 2; 7 % -3", "1"},
+		{"2 ** 10; 2 ** 0", "1"},
+		{"1 << 3 | 1", "9"},
+		{"12 & 10 ^ 1", "9"},
+		{"-8 >> 1", "-4"},
+		{"~0", "-1"},
+		{"5 & 3 == 1; 6 & 1 == 0", "true"},
+		{"1 | 2 < 4", "true"},
+		{"9223372036854775807 + 1", "-9223372036854775808"},
+
+		// comparisons and logic
+		{"1 < 2", "true"},
+		{"2 <= 1", "false"},
+		{"3 >= 3", "true"},
+		{"3 <= 3; 3 < 3", "false"},
+		{`"b" <= "a"`, "false"},
+		{`"a" < "b"`, "true"},
+		{`"a" + "b"`, "ab"},
+		{`"a" == "a"`, "true"},
+		{"1 == true", "false"},
+		{`1 != "1"`, "true"},
+		{"true == true", "true"},
+		{"!5; !!0", "true"},
+		{"1 && false", "false"},
+		{"0 || false", "true"},
+		{"false && 1 / 0; true || 1 / 0", "true"},
+
+		// conditionals and blocks
+		{"if (1 < 2) { 10 } else { 20 }", "10"},
+		{"if (1 > 2) { 10 }", "null"},
+		{"if (false) { 10 } else { let x = 1; }", "null"},
+		{"let x = if (true) { 1; 2 }; x", "2"},
+
+		// variables and assignment
+		{"let x = 1; x = x + 1; x", "2"},
+		{"let x = 1; x += 2", "3"},
+		{"let x = 5; x++", "5"},
+		{"let x = 5; x--; x", "4"},
+		{"let x = 1; if (true) { let x = 2; }; x", "1"},
+		{"let x = 5; if (true) { let x = x + 1; x }", "6"},
+		{"let x = 5; let f = fn() { let x = x * 2; x }; f() + x", "15"},
+
+		// loops
+		{"let n = 0; while (n < 5) { n++; }; n", "5"},
+		{"let s = 0; for (let i = 0; i < 4; i++) { s += i; }; s", "6"},
+		{"let s = 0; for (let i = 0; i < 10; i++) { if (i == 3) { break; }; s += i; }; s", "3"},
+		{"let s = 0; for (let i = 0; i < 5; i++) { if (i % 2 == 0) { continue; }; s += i; }; s", "4"},
+		{`let s = ""; for (c in "abc") { s = c + s; }; s`, "cba"},
+		{`let n = 0; outer: for (a in "ab") { for (b in "xy") { if (b == "y") { continue outer; }; n++; } }; n`, "2"},
+		{"let n = 0; outer: while (true) { while (true) { break outer; } }; n", "0"},
+		{`let n = 0; for (a in "ab") { for (b in "xy") { n++; break; } }; n`, "2"},
+		{`for (c in "ab") { c }`, "null"},
+		{"let s = 0; for (let i = 0; i < 3; i++) { s += i; }", "null"},
+		{"for (let i = 0; i < 3; i += 1) { }", "null"},
+		{"5; let y = 2;", "null"},
+		{"let s = 0; while (s < 3) { s++; }; s", "3"},
+
+		// break and continue inside expressions
+		{`for (c in "ab") { let y = 1 + if (true) { continue; } else { 2 }; }`, "null"},
+		{"let n = 0; while (n < 5000) { n++; let y = 1 + if (true) { continue; } else { 2 }; }; n", "5000"},
+		{`let n = 0; outer: for (a in "ab") { for (b in "xy") { n += 1 + if (b == "y") { continue outer; } else { 0 }; } }; n`, "2"},
+		{"let n = 0; while (true) { let ok = true && if (n > 2) { break; } else { true }; n++; }; n", "3"},
+		{`let s = ""; for (c in "abc") { s = s + c + if (c == "b") { break; } else { "," }; }; s`, "a,"},
+		{"let f = fn() { 1 + if (true) { return 5; } else { 0 } }; f() + 1", "6"},
+		{`fn() { let s = ""; for (c in "abc") { s = s + if (c == "b") { continue; } else { c }; }; s }()`, "ac"},
+		{`let f = fn(s) { let n = 0; for (c in s) { n = n + len(if (c == "x") { break; } else { c }); }; n }; f("abxcd")`, "2"},
+
+		// functions and closures
+		{"let add = fn(a, b) { a + b }; add(1, 2)", "3"},
+		{"let f = fn() { return 1; 2 }; f()", "1"},
+		{"let f = fn() { }; f()", "null"},
+		{"let fact = fn(n) { if (n < 2) { 1 } else { n * fact(n - 1) } }; fact(10)", "3628800"},
+		{"let adder = fn(a) { fn(b) { a + b } }; adder(2)(3)", "5"},
+		{"let counter = fn() { let n = 0; fn() { n++; n } }; let c = counter(); c(); c(); c()", "3"},
+		{"let f = fn(a) { fn() { fn() { a = a * 2; a } } }; let g = f(2)(); g(); g()", "8"},
+		{"fn() { let fib = fn(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } }; fib(15) }()", "610"},
+		{"let x = 1; let f = fn() { x = 10; }; f(); x", "10"},
+		{"let fs = fn() { let a = 0; let inc = fn() { a++ }; let get = fn() { a }; inc(); inc(); get() }; fs()", "2"},
+		{"return 5; 6", "5"},
+		{"if (true) { return 1; }; 2", "1"},
+		{"let x = 1; if (true) { let f = fn() { x }; let x = 2; f() + x }", "3"},
+		// variables outside of functions are globals, there is one per
+		// declaration; in functions each iteration has its own
+		{`let g = 0; for (c in "ab") { if (g == 0) { g = fn() { c }; } }; g()`, "b"},
+		{`fn() { let g = 0; for (c in "ab") { if (g == 0) { g = fn() { c }; } }; g() }()`, "a"},
+
+		// builtins and strings
+		{`len("héllo")`, "5"},
+		{`first("abc") + last("abc") + rest("abc")`, "acbc"},
+		{`"abc"[1]`, "b"},
+		{`"abc"[3]`, "null"},
+
+		// runtime errors
+		{"1 + true", "1:1: type mismatch: INTEGER + BOOLEAN"},
+		{`"a" < 1`, "1:1: type mismatch: STRING < INTEGER"},
+		{"true <= false", "1:1: unknown operator: BOOLEAN <= BOOLEAN"},
+		{"true + false", "1:1: unknown operator: BOOLEAN + BOOLEAN"},
+		{`"a" - "b"`, "1:1: unknown operator: STRING - STRING"},
+		{"-true", "1:1: unknown operator: -BOOLEAN"},
+		{"let x = 0;\n1 / x", "2:1: integer divide by zero"},
+		{"2 ** -1", "1:1: negative right operand of **"},
+		{"let f = fn(a) { a };\nf(1, 2)", "2:1: wrong number of arguments to f: got=2, want=1"},
+		{"let x = 1; x()", "1:12: calling non-function INTEGER"},
+		{"let f = fn(x) {\n  x + 1\n};\nf(true)", "2:3: type mismatch: BOOLEAN + INTEGER"},
+		{"for (c in 5) { }", "1:1: cannot range over INTEGER"},
+		{"5[0]", "1:1: index operator not supported: INTEGER[INTEGER]"},
+		{"len(1)", "1:1: argument to len not supported, got INTEGER"},
+		{"let f = fn() { f() }; f()", "1:16: stack overflow"},
+		{"let x = 1; x += true", "1:12: type mismatch: INTEGER + BOOLEAN"},
+		{`let s = "a"; s++`, "1:14: type mismatch: STRING + INTEGER"},
+		{`let s = "abc"; s[0] = "x"`, "1:16: cannot assign to (s[0])"},
+		{`let s = "abc"; s[0] += "x"`, "1:16: cannot assign to (s[0])"},
+		{`let s = "abc"; s[0]++`, "1:16: cannot assign to (s[0])"},
+		{"len = 1", "1:1: cannot assign to builtin len"},
+	}
+	for _, tt := range tests {
+		for _, e := range engines {
+			for _, optimized := range []bool{false, true} {
+				if got, _ := e.run(t, tt.input, optimized); got != tt.expected {
+					t.Errorf("%s: run(%q) = %q, want %q", e.describe(optimized), tt.input, got, tt.expected)
+				}
+			}
+		}
+	}
+}
+
+func TestOutput(t *testing.T) {
+	input := `puts("a", 1); for (c in "xy") { puts(c) }; let f = fn() { puts("f") }; f()`
+	for _, e := range engines {
+		for _, optimized := range []bool{false, true} {
+			_, out := e.run(t, input, optimized)
+			if expected := "a\n1\nx\ny\nf\n"; out != expected {
+				t.Errorf("%s: output = %q, want %q", e.describe(optimized), out, expected)
+			}
+		}
+	}
+}
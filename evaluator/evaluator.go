@@ -0,0 +1,572 @@
+// Package evaluator runs programs by walking their syntax trees. It
+// gives the same results and errors as the compiler and vm, and serves
+// as the reference they are checked against.
+//
+// Variables are looked up by the objects the resolver bound them to,
+// so that they are scoped exactly as in compiled code. Each block, and
+// each call, gets an environment of its own, but as in the vm the
+// variables declared outside of functions are globals: there is one of
+// each, even when it is declared in a loop.
+package evaluator
+
+import (
+	"fmt"
+	"gointer/ast"
+	"gointer/i18n"
+	"gointer/object"
+	"gointer/resolver"
+	"gointer/token"
+	"io"
+	"os"
+)
+
+// MaxFrames limits how deep calls nest, counting the program itself as
+// the vm does.
+const MaxFrames = 1024
+
+// An Error is a runtime error, reported at the position of the node
+// that failed.
+type Error struct {
+	Pos token.Position
+	Msg string
+}
+
+func (e *Error) Error() string { return fmt.Sprintf("%s: %s", e.Pos, e.Msg) }
+
+// A Function is a function value: a function literal together with the
+// environment it was created in.
+type Function struct {
+	Literal *ast.FunctionLiteral
+	// Name is the variable the function was bound to by a let, if any.
+	Name string
+	env  *environment
+}
+
+// Functions are reported as closures, as in the vm.
+func (f *Function) Type() object.ObjectType { return object.CLOSURE_OBJ }
+func (f *Function) Inspect() string {
+	if f.Name != "" {
+		return fmt.Sprintf("Closure[%s]", f.Name)
+	}
+	return fmt.Sprintf("Closure[%p]", f)
+}
+
+// An environment holds the variables declared in one scope.
+type environment struct {
+	vars  map[*resolver.Object]object.Object
+	outer *environment
+}
+
+func newEnvironment(outer *environment) *environment {
+	return &environment{vars: map[*resolver.Object]object.Object{}, outer: outer}
+}
+
+// returned is passed up as an error by a return statement, to the call
+// or the program it ends.
+type returned struct {
+	value object.Object
+}
+
+func (*returned) Error() string { return "return outside of a function" }
+
+// jump is passed up as an error by break and continue, to the loop
+// they leave.
+type jump struct {
+	// next is set for continue.
+	next  bool
+	label string
+}
+
+func (*jump) Error() string { return "break or continue outside of a loop" }
+
+type Evaluator struct {
+	program *ast.Program
+	info    *resolver.Info
+	globals map[*resolver.Object]object.Object
+	// result is the value of the last statement run outside of
+	// functions, or the value the program returned.
+	result object.Object
+	// depth is the number of calls in progress.
+	depth int
+
+	out  io.Writer
+	lang i18n.Lang
+}
+
+type Option func(*Evaluator)
+
+// WithOutput sets where builtins such as puts write to, os.Stdout by
+// default.
+func WithOutput(w io.Writer) Option {
+	return func(ev *Evaluator) {
+		ev.out = w
+	}
+}
+
+// WithLang selects the language runtime errors are reported in.
+func WithLang(lang i18n.Lang) Option {
+	return func(ev *Evaluator) {
+		ev.lang = lang
+	}
+}
+
+// New returns an evaluator ready to run program, whose identifiers
+// info resolves.
+func New(program *ast.Program, info *resolver.Info, opts ...Option) *Evaluator {
+	ev := &Evaluator{
+		program: program,
+		info:    info,
+		globals: map[*resolver.Object]object.Object{},
+		result:  object.NULL,
+		out:     os.Stdout,
+	}
+	for _, opt := range opts {
+		opt(ev)
+	}
+	return ev
+}
+
+// Result returns the value of the program: the value it returned, or
+// else the value of its last statement, null unless that is an
+// expression.
+func (ev *Evaluator) Result() object.Object {
+	return ev.result
+}
+
+// Run runs the program to its end.
+func (ev *Evaluator) Run() error {
+	env := newEnvironment(nil)
+	for _, s := range ev.program.Statments {
+		v, err := ev.topLevel(s, env)
+		if r, ok := err.(*returned); ok {
+			ev.result = r.value
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		ev.result = v
+	}
+	return nil
+}
+
+// error returns err as an Error at n.
+func (ev *Evaluator) error(n ast.Node, err error) error {
+	msg := err.Error()
+	if e, ok := err.(*object.Error); ok {
+		msg = e.Localize(ev.lang)
+	}
+	return &Error{Pos: n.Pos(), Msg: msg}
+}
+
+func (ev *Evaluator) errorf(n ast.Node, msg i18n.MessageID, args ...any) error {
+	return ev.error(n, object.Errorf(msg, args...))
+}
+
+// declare sets the variable id declares in env to v.
+func (ev *Evaluator) declare(env *environment, id *ast.Identifier, v object.Object) {
+	vars := env.vars
+	if ev.depth == 0 {
+		vars = ev.globals
+	}
+	vars[ev.info.Defs[id]] = v
+}
+
+// vars returns the variables that hold obj as seen from env, nil if it
+// was not declared yet.
+func (ev *Evaluator) vars(env *environment, obj *resolver.Object) map[*resolver.Object]object.Object {
+	if _, ok := ev.globals[obj]; ok {
+		return ev.globals
+	}
+	for ; env != nil; env = env.outer {
+		if _, ok := env.vars[obj]; ok {
+			return env.vars
+		}
+	}
+	return nil
+}
+
+// topLevel runs s, a statement of the program, and returns its value.
+func (ev *Evaluator) topLevel(s ast.Statment, env *environment) (object.Object, error) {
+	if s, ok := s.(*ast.ExpressionStatment); ok {
+		return ev.expr(s.Expression, env)
+	}
+	return object.NULL, ev.stmt(s, env)
+}
+
+func (ev *Evaluator) stmts(list []ast.Statment, env *environment) error {
+	for _, s := range list {
+		if err := ev.stmt(s, env); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ev *Evaluator) stmt(s ast.Statment, env *environment) error {
+	switch s := s.(type) {
+	case *ast.LetStatment:
+		var v object.Object
+		if fn, ok := s.Value.(*ast.FunctionLiteral); ok {
+			v = &Function{Literal: fn, Name: s.Name.Value, env: env}
+		} else {
+			var err error
+			if v, err = ev.expr(s.Value, env); err != nil {
+				return err
+			}
+		}
+		ev.declare(env, s.Name, v)
+	case *ast.ReturnStatment:
+		v := object.Object(object.NULL)
+		if s.ReturnValue != nil {
+			var err error
+			if v, err = ev.expr(s.ReturnValue, env); err != nil {
+				return err
+			}
+		}
+		return &returned{value: v}
+	case *ast.ExpressionStatment:
+		_, err := ev.expr(s.Expression, env)
+		return err
+	case *ast.BlockStatment:
+		return ev.stmts(s.Statments, newEnvironment(env))
+	case *ast.WhileStatment:
+		for {
+			cond, err := ev.expr(s.Condition, env)
+			if err != nil {
+				return err
+			}
+			if !object.IsTruthy(cond) {
+				return nil
+			}
+			if more, err := ev.iteration(s.Label, s.Body, env); !more {
+				return err
+			}
+		}
+	case *ast.ForStatment:
+		env = newEnvironment(env)
+		if s.Init != nil {
+			if err := ev.stmt(s.Init, env); err != nil {
+				return err
+			}
+		}
+		for {
+			if s.Condition != nil {
+				cond, err := ev.expr(s.Condition, env)
+				if err != nil {
+					return err
+				}
+				if !object.IsTruthy(cond) {
+					return nil
+				}
+			}
+			if more, err := ev.iteration(s.Label, s.Body, env); !more {
+				return err
+			}
+			if s.Post != nil {
+				if _, err := ev.expr(s.Post, env); err != nil {
+					return err
+				}
+			}
+		}
+	case *ast.ForInStatment:
+		iterable, err := ev.expr(s.Iterable, env)
+		if err != nil {
+			return err
+		}
+		it, err := object.Iterate(iterable)
+		if err != nil {
+			return ev.error(s, err)
+		}
+		for {
+			v, ok := it.Next()
+			if !ok {
+				return nil
+			}
+			inner := newEnvironment(env)
+			ev.declare(inner, s.Variable, v)
+			if more, err := ev.iteration(s.Label, s.Body, inner); !more {
+				return err
+			}
+		}
+	case *ast.BreakStatment:
+		return &jump{label: labelName(s.Label)}
+	case *ast.ContinueStatment:
+		return &jump{next: true, label: labelName(s.Label)}
+	default:
+		panic(fmt.Sprintf("evaluator: unexpected node type %T", s))
+	}
+	return nil
+}
+
+// iteration runs body once for the loop labelled label, and reports
+// whether the loop goes on. A break or continue that leaves an outer
+// loop is returned as the error.
+func (ev *Evaluator) iteration(label *ast.Identifier, body *ast.BlockStatment, env *environment) (bool, error) {
+	err := ev.stmt(body, env)
+	if j, ok := err.(*jump); ok && (j.label == "" || j.label == labelName(label)) {
+		return j.next, nil
+	}
+	return err == nil, err
+}
+
+func labelName(label *ast.Identifier) string {
+	if label == nil {
+		return ""
+	}
+	return label.Value
+}
+
+// block returns the value of b: the value of its last statement if that
+// is an expression, null otherwise.
+func (ev *Evaluator) block(b *ast.BlockStatment, env *environment) (object.Object, error) {
+	if b == nil || len(b.Statments) == 0 {
+		return object.NULL, nil
+	}
+	env = newEnvironment(env)
+	if err := ev.stmts(b.Statments[:len(b.Statments)-1], env); err != nil {
+		return nil, err
+	}
+	last := b.Statments[len(b.Statments)-1]
+	if s, ok := last.(*ast.ExpressionStatment); ok && s.Expression != nil {
+		return ev.expr(s.Expression, env)
+	}
+	return object.NULL, ev.stmt(last, env)
+}
+
+func (ev *Evaluator) expr(e ast.Expression, env *environment) (object.Object, error) {
+	switch e := e.(type) {
+	case *ast.IntegerLiteral:
+		return &object.Integer{Value: e.Value}, nil
+	case *ast.StringLiteral:
+		return &object.String{Value: e.Value}, nil
+	case *ast.Boolean:
+		return object.NativeBool(e.Value), nil
+	case *ast.Identifier:
+		return ev.load(e, env)
+	case *ast.PrefixExpression:
+		right, err := ev.expr(e.Right, env)
+		if err != nil {
+			return nil, err
+		}
+		v, err := object.Unary(e.Operator, right)
+		if err != nil {
+			return nil, ev.error(e, err)
+		}
+		return v, nil
+	case *ast.InfixExpression:
+		return ev.infix(e, env)
+	case *ast.PostfixExpression:
+		op, ok := map[string]string{"++": "+", "--": "-"}[e.Operator]
+		if !ok {
+			return nil, ev.errorf(e, i18n.UnknownOperator, e.Operator)
+		}
+		// x++ is the value of x before the increment
+		return ev.update(e.Left, env, false, func(old object.Object) (object.Object, error) {
+			v, err := object.Binary(op, old, &object.Integer{Value: 1})
+			if err != nil {
+				return nil, ev.error(e, err)
+			}
+			return v, nil
+		})
+	case *ast.AssignExpression:
+		if e.Operator == "=" {
+			store, err := ev.assignee(e.Target, env)
+			if err != nil {
+				return nil, err
+			}
+			v, err := ev.expr(e.Value, env)
+			if err != nil {
+				return nil, err
+			}
+			store(v)
+			return v, nil
+		}
+		op := e.Operator[:len(e.Operator)-1]
+		return ev.update(e.Target, env, true, func(old object.Object) (object.Object, error) {
+			v, err := ev.expr(e.Value, env)
+			if err != nil {
+				return nil, err
+			}
+			if v, err = object.Binary(op, old, v); err != nil {
+				return nil, ev.error(e, err)
+			}
+			return v, nil
+		})
+	case *ast.IndexExpression:
+		left, err := ev.expr(e.Left, env)
+		if err != nil {
+			return nil, err
+		}
+		index, err := ev.expr(e.Index, env)
+		if err != nil {
+			return nil, err
+		}
+		v, err := object.Index(left, index)
+		if err != nil {
+			return nil, ev.error(e, err)
+		}
+		return v, nil
+	case *ast.CallExpression:
+		fn, err := ev.expr(e.Function, env)
+		if err != nil {
+			return nil, err
+		}
+		args := make([]object.Object, len(e.Arguments))
+		for i, a := range e.Arguments {
+			if args[i], err = ev.expr(a, env); err != nil {
+				return nil, err
+			}
+		}
+		return ev.call(e, fn, args)
+	case *ast.IfExpression:
+		cond, err := ev.expr(e.Condition, env)
+		if err != nil {
+			return nil, err
+		}
+		if object.IsTruthy(cond) {
+			return ev.block(e.Consequence, env)
+		}
+		if e.Alternative != nil {
+			return ev.block(e.Alternative, env)
+		}
+		return object.NULL, nil
+	case *ast.FunctionLiteral:
+		return &Function{Literal: e, env: env}, nil
+	default:
+		panic(fmt.Sprintf("evaluator: unexpected node type %T", e))
+	}
+}
+
+// load returns the value of the variable or builtin id refers to.
+// Variables that were not set yet are null.
+func (ev *Evaluator) load(id *ast.Identifier, env *environment) (object.Object, error) {
+	obj, ok := ev.info.Uses[id]
+	if !ok {
+		return nil, ev.errorf(id, i18n.UndefinedName, id.Value)
+	}
+	if obj.Kind == resolver.Builtin {
+		i, ok := object.LookupBuiltin(obj.Name)
+		if !ok {
+			return nil, ev.errorf(id, i18n.UndefinedName, id.Value)
+		}
+		return object.Builtins[i], nil
+	}
+	if vars := ev.vars(env, obj); vars != nil {
+		return vars[obj], nil
+	}
+	return object.NULL, nil
+}
+
+// assignee returns a function that stores to the variable target
+// refers to, or the error that it cannot be assigned to.
+func (ev *Evaluator) assignee(target ast.Expression, env *environment) (func(object.Object), error) {
+	id, ok := target.(*ast.Identifier)
+	if !ok {
+		// there is nothing to assign to an index of yet
+		return nil, ev.errorf(target, i18n.InvalidAssignTarget, target)
+	}
+	obj, ok := ev.info.Uses[id]
+	switch {
+	case !ok:
+		return nil, ev.errorf(id, i18n.UndefinedName, id.Value)
+	case obj.Kind == resolver.Builtin:
+		return nil, ev.errorf(id, i18n.AssignBuiltin, id.Value)
+	}
+	vars := ev.vars(env, obj)
+	if vars == nil {
+		vars = env.vars
+	}
+	return func(v object.Object) { vars[obj] = v }, nil
+}
+
+// update assigns to target the value compute returns from its old one.
+// The value of the assignment is the new value if result is set, the
+// old one otherwise.
+func (ev *Evaluator) update(target ast.Expression, env *environment, result bool,
+	compute func(object.Object) (object.Object, error)) (object.Object, error) {
+	store, err := ev.assignee(target, env)
+	if err != nil {
+		return nil, err
+	}
+	old, err := ev.load(target.(*ast.Identifier), env)
+	if err != nil {
+		return nil, err
+	}
+	v, err := compute(old)
+	if err != nil {
+		return nil, err
+	}
+	store(v)
+	if result {
+		return v, nil
+	}
+	return old, nil
+}
+
+func (ev *Evaluator) infix(e *ast.InfixExpression, env *environment) (object.Object, error) {
+	left, err := ev.expr(e.Left, env)
+	if err != nil {
+		return nil, err
+	}
+	switch e.Operator {
+	case "&&", "||":
+		// a && b is false as soon as an operand is falsy, a || b true
+		// as soon as one is truthy
+		short := e.Operator == "||"
+		if object.IsTruthy(left) == short {
+			return object.NativeBool(short), nil
+		}
+		right, err := ev.expr(e.Right, env)
+		if err != nil {
+			return nil, err
+		}
+		return object.NativeBool(object.IsTruthy(right)), nil
+	}
+	right, err := ev.expr(e.Right, env)
+	if err != nil {
+		return nil, err
+	}
+	v, err := object.Binary(e.Operator, left, right)
+	if err != nil {
+		return nil, ev.error(e, err)
+	}
+	return v, nil
+}
+
+// call calls fn with args for the call expression e.
+func (ev *Evaluator) call(e *ast.CallExpression, fn object.Object, args []object.Object) (object.Object, error) {
+	switch fn := fn.(type) {
+	case *Function:
+		params := fn.Literal.Parameters
+		if len(args) != len(params) {
+			name := fn.Name
+			if name == "" {
+				name = "function"
+			}
+			return nil, ev.errorf(e, i18n.WrongArgCount, name, len(args), len(params))
+		}
+		if ev.depth+1 >= MaxFrames {
+			return nil, ev.errorf(e, i18n.StackOverflow)
+		}
+		env := newEnvironment(fn.env)
+		for i, p := range params {
+			env.vars[ev.info.Defs[p]] = args[i]
+		}
+		ev.depth++
+		v, err := ev.block(fn.Literal.Body, env)
+		ev.depth--
+		if r, ok := err.(*returned); ok {
+			return r.value, nil
+		}
+		return v, err
+	case *object.Builtin:
+		v, err := fn.Fn(ev.out, args...)
+		if err != nil {
+			return nil, ev.error(e, err)
+		}
+		return v, nil
+	default:
+		return nil, ev.errorf(e, i18n.NotCallable, fn.Type())
+	}
+}
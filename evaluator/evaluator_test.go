@@ -0,0 +1,82 @@
+package evaluator
+
+import (
+	"gointer/i18n"
+	"gointer/lexer"
+	"gointer/parser"
+	"gointer/resolver"
+	"io"
+	"testing"
+)
+
+// run runs input and returns its result or runtime error. Unlike the
+// compiler, the evaluator runs programs with resolve errors, and only
+// reports them when it gets to them.
+func run(t *testing.T, input string, opts ...Option) string {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if errs := p.Errors(); len(errs) != 0 {
+		t.Fatalf("parse errors in %q: %v", input, errs)
+	}
+	info, _ := resolver.Resolve(program)
+	ev := New(program, info, append([]Option{WithOutput(io.Discard)}, opts...)...)
+	if err := ev.Run(); err != nil {
+		return err.Error()
+	}
+	return ev.Result().Inspect()
+}
+
+func TestUndefinedNames(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x = 1;\ny = 2", "2:1: undefined: y"},
+		{"let x = 1; x += y", "1:17: undefined: y"},
+		{"z++", "1:1: undefined: z"},
+		{"let x = 1; x + y", "1:16: undefined: y"},
+		{"if (false) { y = 1 }; 5", "5"},
+		{"false && y", "false"},
+	}
+	for _, tt := range tests {
+		if got := run(t, tt.input); got != tt.expected {
+			t.Errorf("run(%q) = %q, want %q", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestLocalizedErrors(t *testing.T) {
+	got := run(t, "1 / 0", WithLang(i18n.Chinese))
+	if expected := "1:1: 整数除以零"; got != expected {
+		t.Errorf("error = %q, want %q", got, expected)
+	}
+}
+
+func TestInspectFunction(t *testing.T) {
+	if got, expected := run(t, "let f = fn() { 1 }; f"), "Closure[f]"; got != expected {
+		t.Errorf("run = %q, want %q", got, expected)
+	}
+}
+
+func TestLoops(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let n = 0; while (n < 3) { n++; }; n", "3"},
+		{"let n = 0; for (let i = 0; i < 10; i++) { if (i == 4) { break; }; n += i; }; n", "6"},
+		{"let n = 0; for (let i = 0; ; i++) { if (i > 3) { break; }; if (i % 2 == 1) { continue; }; n += i; }; n", "2"},
+		{"let n = 0; outer: while (true) { while (true) { n++; break outer; } }; n", "1"},
+		{"let n = 0; outer: for (let i = 0; i < 3; i++) { for (let j = 0; j < 3; j++) { if (j == 1) { continue outer; }; n++; } }; n", "3"},
+		{`let s = ""; outer: for (a in "ab") { inner: for (b in "xyz") { if (b == "y") { continue inner; }; if (b == "z") { break outer; }; s += a + b; } }; s`, "ax"},
+		{"let n = 0; for (let i = 0; i < 3; i++) { for (let j = 0; j < 3; j++) { if (j == 1) { break; }; n++; } }; n", "3"},
+		{"let f = fn() { let i = 0; while (true) { i++; if (i == 5) { return i; } } }; f()", "5"},
+		{`let n = 0; for (c in "") { n++; }; n`, "0"},
+	}
+	for _, tt := range tests {
+		if got := run(t, tt.input); got != tt.expected {
+			t.Errorf("run(%q) = %q, want %q", tt.input, got, tt.expected)
+		}
+	}
+}
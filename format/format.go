@@ -0,0 +1,545 @@
+// Package format prints syntax trees as canonical source: one statement
+// per line, blocks indented with tabs, single spaces around infix
+// operators and only the parentheses the parser needs.
+package format
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"gointer/ast"
+	"gointer/lexer"
+	"gointer/parser"
+	"gointer/token"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+type Option func(*printer)
+
+// WithKeywords spells keywords from kw instead of the default English
+// ones, see token.Dialect.
+func WithKeywords(kw token.Keywords) Option {
+	return func(p *printer) {
+		p.keywords = kw
+	}
+}
+
+// Fprint writes the canonical source of node to w. The comments of a
+// Program are printed before the statement they precede, or at the end
+// of the line of the statement they follow.
+func Fprint(w io.Writer, node ast.Node, opts ...Option) error {
+	p := newPrinter(opts...)
+	switch n := node.(type) {
+	case *ast.Program:
+		p.comments = n.Comments
+		p.stmts(n.Statments, token.Position{Offset: math.MaxInt})
+	case ast.Statment:
+		p.stmt(n, nil)
+		p.buf.WriteByte('\n')
+	case ast.Expression:
+		p.expr(n)
+		p.buf.WriteByte('\n')
+	default:
+		p.print(node)
+	}
+	_, err := p.buf.WriteTo(w)
+	return err
+}
+
+// Source formats a program in the default dialect. It returns the
+// lexer and parser errors instead if src does not parse.
+func Source(src []byte) ([]byte, error) {
+	l := lexer.New(string(src), lexer.WithComments())
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if errs := p.Errors(); len(errs) != 0 {
+		return nil, errors.New(strings.Join(errs, "\n"))
+	}
+	buf := bytes.Buffer{}
+	if err := Fprint(&buf, program); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+type printer struct {
+	buf      bytes.Buffer
+	keywords token.Keywords
+	indent   int
+
+	// comments not printed yet, in source order
+	comments []*ast.Comment
+	// line is the source line of the last statement or comment
+	// printed, 0 at the start of a block where no blank line is kept
+	line int
+}
+
+func newPrinter(opts ...Option) *printer {
+	p := &printer{}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if p.keywords == nil {
+		p.keywords, _ = token.Dialect("en")
+	}
+	return p
+}
+
+// render returns the source of n on its own, without comments.
+func (p *printer) render(n ast.Node) string {
+	sub := &printer{keywords: p.keywords, indent: p.indent}
+	sub.print(n)
+	return sub.buf.String()
+}
+
+func (p *printer) keyword(t token.TokenType) string {
+	if s, ok := p.keywords.Spelling(t); ok {
+		return s
+	}
+	return string(t)
+}
+
+func (p *printer) writeIndent() {
+	for range p.indent {
+		p.buf.WriteByte('\t')
+	}
+}
+
+// newline keeps a single blank line before source line l if there was
+// one in the source.
+func (p *printer) newline(l int) {
+	if p.line > 0 && l > p.line+1 {
+		p.buf.WriteByte('\n')
+	}
+}
+
+// stmts prints list one statement per line, followed by the comments
+// that come before end.
+func (p *printer) stmts(list []ast.Statment, end token.Position) {
+	for i, s := range list {
+		var next ast.Statment
+		if i+1 < len(list) {
+			next = list[i+1]
+		}
+		p.leadingComments(s.Pos())
+		p.newline(s.Pos().Line)
+		p.writeIndent()
+		p.stmt(s, next)
+		p.trailingComments(s.End())
+		p.buf.WriteByte('\n')
+	}
+	p.leadingComments(end)
+}
+
+// leadingComments prints the comments before pos on lines of their own.
+func (p *printer) leadingComments(pos token.Position) {
+	for len(p.comments) > 0 && p.comments[0].Pos().Offset < pos.Offset {
+		c := p.comments[0]
+		p.comments = p.comments[1:]
+		p.newline(c.Pos().Line)
+		p.writeIndent()
+		p.buf.WriteString(c.Token.Literal)
+		p.buf.WriteByte('\n')
+		p.line = c.End().Line
+	}
+}
+
+// trailingComments prints the comments inside a statement that ends at
+// end, and those following it on the same line, after the statement.
+func (p *printer) trailingComments(end token.Position) {
+	if !end.IsValid() {
+		return
+	}
+	p.line = end.Line
+	first := true
+	for len(p.comments) > 0 {
+		c := p.comments[0]
+		if c.Pos().Offset >= end.Offset && c.Pos().Line != end.Line {
+			return
+		}
+		p.comments = p.comments[1:]
+		if first {
+			p.buf.WriteByte(' ')
+			first = false
+		} else {
+			p.buf.WriteByte('\n')
+			p.writeIndent()
+		}
+		p.buf.WriteString(c.Token.Literal)
+		p.line = max(p.line, c.End().Line)
+	}
+}
+
+func (p *printer) print(node ast.Node) {
+	switch n := node.(type) {
+	case ast.Statment:
+		p.stmt(n, nil)
+	case ast.Expression:
+		p.expr(n)
+	case *ast.Comment:
+		p.buf.WriteString(n.Token.Literal)
+	case *ast.Program:
+		p.stmts(n.Statments, token.Position{})
+	case ast.TypeExpr:
+		p.typ(n)
+	default:
+		panic(fmt.Sprintf("format: unexpected node type %T", n))
+	}
+}
+
+// stmt prints s without indentation or newline. next is the statement
+// that follows s in the same list, if any.
+func (p *printer) stmt(s ast.Statment, next ast.Statment) {
+	switch s := s.(type) {
+	case *ast.LetStatment:
+		p.simpleStmt(s)
+		p.buf.WriteByte(';')
+	case *ast.ReturnStatment:
+		p.buf.WriteString(p.keyword(token.RETURN))
+		if s.ReturnValue != nil {
+			p.buf.WriteByte(' ')
+			p.expr(s.ReturnValue)
+		}
+		p.buf.WriteByte(';')
+	case *ast.ExpressionStatment:
+		p.simpleStmt(s)
+		if _, ok := s.Expression.(*ast.IfExpression); !ok || p.continues(next) {
+			p.buf.WriteByte(';')
+		}
+	case *ast.BlockStatment:
+		p.block(s)
+	case *ast.WhileStatment:
+		p.label(s.Label)
+		p.buf.WriteString(p.keyword(token.WHILE))
+		p.buf.WriteString(" (")
+		p.expr(s.Condition)
+		p.buf.WriteString(") ")
+		p.block(s.Body)
+	case *ast.ForStatment:
+		p.label(s.Label)
+		p.buf.WriteString(p.keyword(token.FOR))
+		p.buf.WriteString(" (")
+		if s.Init != nil {
+			p.simpleStmt(s.Init)
+		}
+		p.buf.WriteByte(';')
+		if s.Condition != nil {
+			p.buf.WriteByte(' ')
+			p.expr(s.Condition)
+		}
+		p.buf.WriteByte(';')
+		if s.Post != nil {
+			p.buf.WriteByte(' ')
+			p.expr(s.Post)
+		}
+		p.buf.WriteString(") ")
+		p.block(s.Body)
+	case *ast.ForInStatment:
+		p.label(s.Label)
+		p.buf.WriteString(p.keyword(token.FOR))
+		p.buf.WriteString(" (")
+		p.expr(s.Variable)
+		p.buf.WriteString(" " + p.keyword(token.IN) + " ")
+		p.expr(s.Iterable)
+		p.buf.WriteString(") ")
+		p.block(s.Body)
+	case *ast.BreakStatment:
+		p.branch(token.BREAK, s.Label)
+	case *ast.ContinueStatment:
+		p.branch(token.CONTINUE, s.Label)
+	default:
+		panic(fmt.Sprintf("format: unexpected node type %T", s))
+	}
+}
+
+// simpleStmt prints a let or expression statement without the
+// semicolon, as in the init clause of a for loop.
+func (p *printer) simpleStmt(s ast.Statment) {
+	switch s := s.(type) {
+	case *ast.LetStatment:
+		p.buf.WriteString(p.keyword(token.LET) + " ")
+		p.expr(s.Name)
+		p.annotation(s.Type)
+		p.buf.WriteString(" = ")
+		p.expr(s.Value)
+	case *ast.ExpressionStatment:
+		p.expr(s.Expression)
+	default:
+		panic(fmt.Sprintf("format: unexpected init statement %T", s))
+	}
+}
+
+// continues reports whether next would be parsed as part of a
+// preceding if expression without a semicolon between them, as in
+// if (c) { f } (x).
+func (p *printer) continues(next ast.Statment) bool {
+	if next == nil {
+		return false
+	}
+	src := p.render(next)
+	return src != "" && strings.ContainsRune("([+-*/%<>=!&|^?~", rune(src[0]))
+}
+
+func (p *printer) label(l *ast.Identifier) {
+	if l != nil {
+		p.buf.WriteString(l.Value + ": ")
+	}
+}
+
+func (p *printer) branch(t token.TokenType, label *ast.Identifier) {
+	p.buf.WriteString(p.keyword(t))
+	if label != nil {
+		p.buf.WriteString(" " + label.Value)
+	}
+	p.buf.WriteByte(';')
+}
+
+func (p *printer) block(b *ast.BlockStatment) {
+	if b == nil {
+		p.buf.WriteString("{}")
+		return
+	}
+	if len(b.Statments) == 0 &&
+		(len(p.comments) == 0 || p.comments[0].Pos().Offset >= b.End().Offset) {
+		p.buf.WriteString("{}")
+		return
+	}
+	p.buf.WriteString("{\n")
+	p.indent++
+	p.line = 0
+	p.stmts(b.Statments, b.End())
+	p.indent--
+	p.writeIndent()
+	p.buf.WriteByte('}')
+}
+
+func (p *printer) expr(e ast.Expression) {
+	switch e := e.(type) {
+	case nil:
+	case *ast.Identifier:
+		p.buf.WriteString(e.Value)
+	case *ast.IntegerLiteral:
+		if e.Token.Literal != "" {
+			p.buf.WriteString(e.Token.Literal)
+		} else {
+			p.buf.WriteString(strconv.FormatInt(e.Value, 10))
+		}
+	case *ast.StringLiteral:
+		p.buf.WriteString(quote(e.Value))
+	case *ast.Boolean:
+		if e.Value {
+			p.buf.WriteString(p.keyword(token.TRUE))
+		} else {
+			p.buf.WriteString(p.keyword(token.FALSE))
+		}
+	case *ast.PrefixExpression:
+		p.buf.WriteString(e.Operator)
+		if prefixParens(e.Right) {
+			p.paren(e.Right)
+			return
+		}
+		// - -x, not --x
+		for _, sign := range []string{"-", "+"} {
+			if strings.HasSuffix(e.Operator, sign) && strings.HasPrefix(p.render(e.Right), sign) {
+				p.buf.WriteByte(' ')
+			}
+		}
+		p.expr(e.Right)
+	case *ast.InfixExpression:
+		prec := parser.Precedence(e.Token.Type)
+		p.left(e.Left, prec)
+		p.buf.WriteString(" " + e.Operator + " ")
+		p.right(e.Right, operandLevel(e))
+	case *ast.PostfixExpression:
+		p.left(e.Left, parser.POSTFIX)
+		p.buf.WriteString(e.Operator)
+	case *ast.AssignExpression:
+		p.expr(e.Target)
+		p.buf.WriteString(" " + e.Operator + " ")
+		p.right(e.Value, parser.ASSIGNMENT-1)
+	case *ast.IndexExpression:
+		p.left(e.Left, parser.INDEX)
+		p.buf.WriteByte('[')
+		p.expr(e.Index)
+		p.buf.WriteByte(']')
+	case *ast.CallExpression:
+		p.left(e.Function, parser.CALL)
+		p.buf.WriteByte('(')
+		for i, a := range e.Arguments {
+			if i > 0 {
+				p.buf.WriteString(", ")
+			}
+			p.expr(a)
+		}
+		p.buf.WriteByte(')')
+	case *ast.IfExpression:
+		p.buf.WriteString(p.keyword(token.IF) + " (")
+		p.expr(e.Condition)
+		p.buf.WriteString(") ")
+		p.block(e.Consequence)
+		if e.Alternative != nil {
+			p.buf.WriteString(" " + p.keyword(token.ELSE) + " ")
+			p.block(e.Alternative)
+		}
+	case *ast.FunctionLiteral:
+		p.buf.WriteString(p.keyword(token.FUNCTION) + "(")
+		for i, param := range e.Parameters {
+			if i > 0 {
+				p.buf.WriteString(", ")
+			}
+			p.expr(param)
+			p.annotation(e.ParamType(i))
+		}
+		p.buf.WriteString(") ")
+		if e.ReturnType != nil {
+			p.buf.WriteString("-> ")
+			p.typ(e.ReturnType)
+			p.buf.WriteByte(' ')
+		}
+		p.block(e.Body)
+	default:
+		panic(fmt.Sprintf("format: unexpected node type %T", e))
+	}
+}
+
+// annotation prints ": t" if t is not nil.
+func (p *printer) annotation(t ast.TypeExpr) {
+	if t != nil {
+		p.buf.WriteString(": ")
+		p.typ(t)
+	}
+}
+
+func (p *printer) typ(t ast.TypeExpr) {
+	switch t := t.(type) {
+	case *ast.NamedType:
+		p.buf.WriteString(t.Name)
+	case *ast.FunctionType:
+		p.buf.WriteString(p.keyword(token.FUNCTION) + "(")
+		for i, param := range t.Params {
+			if i > 0 {
+				p.buf.WriteString(", ")
+			}
+			p.typ(param)
+		}
+		p.buf.WriteByte(')')
+		if t.Result != nil {
+			p.buf.WriteString(" -> ")
+			p.typ(t.Result)
+		}
+	default:
+		panic(fmt.Sprintf("format: unexpected node type %T", t))
+	}
+}
+
+func (p *printer) paren(e ast.Expression) {
+	p.buf.WriteByte('(')
+	p.expr(e)
+	p.buf.WriteByte(')')
+}
+
+// left prints the left operand of an operator of precedence prec.
+func (p *printer) left(e ast.Expression, prec int) {
+	if rightOpen(e) < prec {
+		p.paren(e)
+		return
+	}
+	p.expr(e)
+}
+
+// right prints an operand that the parser reads at precedence level.
+func (p *printer) right(e ast.Expression, level int) {
+	if leftOpen(e) <= level {
+		p.paren(e)
+		return
+	}
+	p.expr(e)
+}
+
+// closed is the precedence of an expression that no operator can
+// split, because it starts or ends with a token of its own.
+const closed = math.MaxInt
+
+// operandLevel returns the precedence the parser reads the right
+// operand of e at, one less for right associative operators.
+func operandLevel(e *ast.InfixExpression) int {
+	prec := parser.Precedence(e.Token.Type)
+	if parser.Associativity(e.Token.Type) == parser.RightAssoc {
+		prec--
+	}
+	return prec
+}
+
+func prefixParens(e ast.Expression) bool {
+	return leftOpen(e) <= parser.PREFIX
+}
+
+// leftOpen returns the precedence of the operator that follows the
+// leftmost operand of e. Printed after an operand read at that level
+// or higher, the operator would not apply to the whole of e.
+func leftOpen(e ast.Expression) int {
+	switch e := e.(type) {
+	case *ast.InfixExpression:
+		return parser.Precedence(e.Token.Type)
+	case *ast.AssignExpression:
+		return parser.ASSIGNMENT
+	case *ast.PostfixExpression:
+		return parser.POSTFIX
+	case *ast.CallExpression:
+		return parser.CALL
+	case *ast.IndexExpression:
+		return parser.INDEX
+	default:
+		return closed
+	}
+}
+
+// rightOpen returns the precedence the rightmost operand of e was read
+// at. Printed before an operator of higher precedence, the operator
+// would take that operand instead of the whole of e.
+func rightOpen(e ast.Expression) int {
+	level, right := closed, ast.Expression(nil)
+	switch e := e.(type) {
+	case *ast.InfixExpression:
+		level, right = operandLevel(e), e.Right
+	case *ast.AssignExpression:
+		level, right = parser.ASSIGNMENT-1, e.Value
+	case *ast.PrefixExpression:
+		if prefixParens(e.Right) {
+			return parser.PREFIX
+		}
+		level, right = parser.PREFIX, e.Right
+	}
+	if right != nil && leftOpen(right) > level {
+		level = min(level, rightOpen(right))
+	}
+	return level
+}
+
+// quote returns s as a string literal using the escapes the lexer
+// understands.
+func quote(s string) string {
+	b := strings.Builder{}
+	b.WriteByte('"')
+	for _, ch := range s {
+		switch ch {
+		case '\n':
+			b.WriteString(`\n`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		default:
+			b.WriteRune(ch)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,165 @@
+package format
+
+import (
+	"bytes"
+	"gointer/ast"
+	"gointer/lexer"
+	"gointer/parser"
+	"gointer/token"
+	"testing"
+)
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x   =  (1+2)*3", "let x = (1 + 2) * 3;\n"},
+		{"return a", "return a;\n"},
+		{`"a\tb\"c\\"`, `"a\tb\"c\\";` + "\n"},
+		{"x+=y[1]<<2|~z", "x += y[1] << 2 | ~z;\n"},
+		{"a - (b - c); (a - b) - c", "a - (b - c);\na - b - c;\n"},
+		{"2 ** (3 ** 4); (2 ** 3) ** 4", "2 ** 3 ** 4;\n(2 ** 3) ** 4;\n"},
+		{"(-2) ** 2; -(2 ** 2)", "(-2) ** 2;\n-2 ** 2;\n"},
+		{"-(-x); -(x--); !(!x)", "- -x;\n-x--;\n!!x;\n"},
+		{"-(a * b); !(a == b); a * (b + c) * d", "-(a * b);\n!(a == b);\na * (b + c) * d;\n"},
+		{"(a + b)(c); (f(x))[0]; (a[0])(1)", "(a + b)(c);\nf(x)[0];\na[0](1);\n"},
+		{"x = (y = 3); (x) += 1", "x = y = 3;\nx += 1;\n"},
+		{"(x)++; (-x)!", "x++;\n(-x)!;\n"},
+		{"(a && b) || c; a && (b || c)", "a && b || c;\na && (b || c);\n"},
+		{"f((x = 1), 2)", "f(x = 1, 2);\n"},
+		{"if(a){b}else{c}", "if (a) {\n\tb;\n} else {\n\tc;\n}\n"},
+		{"if (a) { b };\n-c", "if (a) {\n\tb;\n};\n-c;\n"},
+		{"if (a) {}; x", "if (a) {}\nx;\n"},
+		{"let z = if (x) { 1 } else { 2 } + 1", "let z = if (x) {\n\t1;\n} else {\n\t2;\n} + 1;\n"},
+		{"outer:while(x){if(y){break outer}\ncontinue}", "outer: while (x) {\n\tif (y) {\n\t\tbreak outer;\n\t}\n\tcontinue;\n}\n"},
+		{"for(let i=0;i<10;i++){f(i)}", "for (let i = 0; i < 10; i++) {\n\tf(i);\n}\n"},
+		{"for (;;) {}\nfor(;x;){}", "for (;;) {}\nfor (; x;) {}\n"},
+		{"for(v in vs){print(v)}", "for (v in vs) {\n\tprint(v);\n}\n"},
+		{"let add=fn(a,b){return a+b}; fn(){}()", "let add = fn(a, b) {\n\treturn a + b;\n};\nfn() {}();\n"},
+		{"let x:int=5; let f=fn(a:int,b)->fn(int)->bool{g}", "let x: int = 5;\nlet f = fn(a: int, b) -> fn(int) -> bool {\n\tg;\n};\n"},
+		{"a\n\n\n\nb\nc", "a;\n\nb;\nc;\n"},
+		{"while (x) {\n\n  a\n\n}", "while (x) {\n\ta;\n}\n"},
+	}
+
+	for _, tt := range tests {
+		got, err := Source([]byte(tt.input))
+		if err != nil {
+			t.Errorf("Source(%q) failed: %v", tt.input, err)
+			continue
+		}
+		if string(got) != tt.expected {
+			t.Errorf("Source(%q) wrong.\ngot=%q\nwant=%q", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestSourceComments(t *testing.T) {
+	input := `// header
+
+let x = 1; // one
+// before y
+
+
+let y = f(a, // arg
+  b)
+while (x) {
+	// first
+	x -= 1 // step
+
+	// last
+}
+// end
+`
+	expected := `// header
+
+let x = 1; // one
+// before y
+
+let y = f(a, b); // arg
+while (x) {
+	// first
+	x -= 1; // step
+
+	// last
+}
+// end
+`
+	got, err := Source([]byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != expected {
+		t.Errorf("wrong output.\ngot=%s\nwant=%s", got, expected)
+	}
+}
+
+// TestSourcePreservesTree checks that formatting does not change the
+// parsed program and that formatted source formats to itself.
+func TestSourcePreservesTree(t *testing.T) {
+	inputs := []string{
+		"let y = -(-x) + - -x; x+=y[1]<<2|~z",
+		"2 ** 3 ** 4; (2 ** 3) ** 4; (-2) ** 2; -2 ** 2; (a + b)(c); (f(x))[0]",
+		"a - (b - c); (a - b) - c; x = y = 3; -(a * b); !(a == b); a * (b + c) * d",
+		"a + b * c + d / e - f; 3 + 4 * 5 == 3 * 1 + 4 * 5; a | b ^ c & d; a << b + c",
+		"x++ + -y; a!; a? ; -a!; (a + b)!; - -a",
+		"if (a) { b } else { c }\n(d)",
+		"let z = if (x) { 1 } else { 2 } + 1; if (x) { 1 }[0]",
+		"let f = fn(x) { fn(y) { x + y } }; f(1)(2); -fn() { 1 }()",
+		"outer: for (x in xs) { for (let i = 0; i < x; i += 1) { if (i) { continue outer } } }",
+	}
+	for _, input := range inputs {
+		want := parse(t, input).String()
+		formatted, err := Source([]byte(input))
+		if err != nil {
+			t.Errorf("Source(%q) failed: %v", input, err)
+			continue
+		}
+		if got := parse(t, string(formatted)).String(); got != want {
+			t.Errorf("Source(%q) changed the program.\nformatted=%s\ngot=%s\nwant=%s", input, formatted, got, want)
+		}
+		again, err := Source(formatted)
+		if err != nil {
+			t.Errorf("Source(%q) failed: %v", formatted, err)
+			continue
+		}
+		if !bytes.Equal(again, formatted) {
+			t.Errorf("formatting is not idempotent.\nfirst=%s\nsecond=%s", formatted, again)
+		}
+	}
+}
+
+func TestFprintDialect(t *testing.T) {
+	zh, _ := token.Dialect("zh")
+	input := "令 x = 真; 当 (x) { 如果 (x) { 跳出 } 否则 { 继续 } }"
+	p := parser.New(lexer.New(input, lexer.WithKeywords(zh)))
+	program := p.ParseProgram()
+	if errs := p.Errors(); len(errs) != 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+	expected := "令 x = 真;\n当 (x) {\n\t如果 (x) {\n\t\t跳出;\n\t} 否则 {\n\t\t继续;\n\t}\n}\n"
+	buf := bytes.Buffer{}
+	if err := Fprint(&buf, program, WithKeywords(zh)); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != expected {
+		t.Errorf("wrong output.\ngot=%s\nwant=%s", buf.String(), expected)
+	}
+}
+
+func TestSourceErrors(t *testing.T) {
+	_, err := Source([]byte("let = 5"))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func parse(t *testing.T, input string) *ast.Program {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if errs := p.Errors(); len(errs) != 0 {
+		t.Fatalf("parse errors in %q: %v", input, errs)
+	}
+	return program
+}
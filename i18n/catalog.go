@@ -0,0 +1,118 @@
+package i18n
+
+var catalogs = map[Lang]map[MessageID]string{
+	English: {
+		UnexpectedChar:     "unexpected character %q",
+		UnterminatedString: "unterminated string literal",
+		InvalidEscape:      "invalid escape sequence \"\\%c\" in string literal",
+		InvalidNumber:      "invalid numeric literal %q",
+
+		ExpectedNextToken:   "expected next token to be %s, got %s instead",
+		NoPrefixParseFn:     "no prefix parse function for %s found",
+		InvalidInteger:      "could not parse %q as integer",
+		InvalidAssignTarget: "cannot assign to %s",
+		NotInLoop:           "%s is not in a loop",
+		UndefinedLabel:      "undefined label %s",
+		LabelWithoutLoop:    "label %s must be followed by a loop",
+		ExpectedType:        "expected type, got %s",
+
+		UndefinedName: "undefined: %s",
+		Redeclared:    "%s redeclared in this block",
+
+		VetUnused:        "%s declared and not used",
+		VetShadow:        "declaration of %s shadows declaration at %s",
+		VetShadowBuiltin: "declaration of %s shadows builtin",
+		VetUnreachable:   "unreachable code",
+		VetSelfCompare:   "comparison of %s with itself",
+		VetConstCond:     "condition is always %s",
+		VetDivZero:       "division by zero",
+		VetMissingElse:   "if without else, the function returns no value when the condition is false",
+
+		TypeMismatch:      "invalid operation: %s (mismatched types %s and %s)",
+		TypeInvalidOp:     "invalid operation: operator %s not defined on %s (type %s)",
+		TypeCannotDeclare: "cannot use %s (type %s) as %s value in variable declaration",
+		TypeCannotAssign:  "cannot use %s (type %s) as %s value in assignment",
+		TypeCannotReturn:  "cannot use %s (type %s) as %s value in return",
+		TypeCannotPass:    "cannot use %s (type %s) as %s value in argument to %s",
+		TypeNotFunc:       "invalid operation: cannot call non-function %s (type %s)",
+		TypeArgCount:      "wrong number of arguments in call to %s: have %d, want %d",
+		TypeUnknown:       "undefined type %s",
+		TypeBranches:      "if branches have mismatched types %s and %s",
+		TypeRecursive:     "recursive type in %s",
+
+		TooManyLocals:    "function has more than %d local variables",
+		TooManyConstants: "program has more than %d constants",
+		UnknownOperator:  "unknown operator %s",
+		AssignBuiltin:    "cannot assign to builtin %s",
+
+		WrongArgCount:     "wrong number of arguments to %s: got=%d, want=%d",
+		BuiltinArgType:    "argument to %s not supported, got %s",
+		CannotRange:       "cannot range over %s",
+		OperandMismatch:   "type mismatch: %s %s %s",
+		UnknownPrefixOp:   "unknown operator: %s%s",
+		UnknownInfixOp:    "unknown operator: %s %s %s",
+		DivisionByZero:    "integer divide by zero",
+		NegativeOperand:   "negative right operand of %s",
+		NotCallable:       "calling non-function %s",
+		IndexNotSupported: "index operator not supported: %s[%s]",
+		StackOverflow:     "stack overflow",
+		BadOperand:        "internal error: %s operand of %s",
+	},
+	Chinese: {
+		UnexpectedChar:     "意外的字符 %q",
+		UnterminatedString: "字符串字面量没有结束",
+		InvalidEscape:      "字符串字面量中的转义序列 \"\\%c\" 无效",
+		InvalidNumber:      "无效的数字字面量 %q",
+
+		ExpectedNextToken:   "下一个词法单元应为 %s，实际为 %s",
+		NoPrefixParseFn:     "没有找到 %s 的前缀解析函数",
+		InvalidInteger:      "无法将 %q 解析为整数",
+		InvalidAssignTarget: "不能给 %s 赋值",
+		NotInLoop:           "%s 不在循环中",
+		UndefinedLabel:      "未定义的标签 %s",
+		LabelWithoutLoop:    "标签 %s 后面必须是循环",
+		ExpectedType:        "应为类型，实际为 %s",
+
+		UndefinedName: "未定义: %s",
+		Redeclared:    "%s 在此块中重复声明",
+
+		VetUnused:        "%s 已声明但未使用",
+		VetShadow:        "%s 的声明遮蔽了 %s 处的声明",
+		VetShadowBuiltin: "%s 的声明遮蔽了内置函数",
+		VetUnreachable:   "不可达的代码",
+		VetSelfCompare:   "%s 与自身比较",
+		VetConstCond:     "条件始终为 %s",
+		VetDivZero:       "除以零",
+		VetMissingElse:   "if 缺少 else，条件为假时函数没有返回值",
+
+		TypeMismatch:      "无效运算: %s (类型 %s 和 %s 不匹配)",
+		TypeInvalidOp:     "无效运算: 运算符 %s 不能用于 %s (类型 %s)",
+		TypeCannotDeclare: "不能在变量声明中将 %s (类型 %s) 用作 %s 类型的值",
+		TypeCannotAssign:  "不能在赋值中将 %s (类型 %s) 用作 %s 类型的值",
+		TypeCannotReturn:  "不能在 return 中将 %s (类型 %s) 用作 %s 类型的值",
+		TypeCannotPass:    "不能将 %s (类型 %s) 作为 %s 类型的参数传给 %s",
+		TypeNotFunc:       "无效运算: 不能调用非函数 %s (类型 %s)",
+		TypeArgCount:      "调用 %s 的参数个数错误: 实际 %d 个，应为 %d 个",
+		TypeUnknown:       "未定义的类型 %s",
+		TypeBranches:      "if 分支的类型 %s 和 %s 不匹配",
+		TypeRecursive:     "%s 的类型是递归的",
+
+		TooManyLocals:    "函数的局部变量超过 %d 个",
+		TooManyConstants: "程序的常量超过 %d 个",
+		UnknownOperator:  "未知的运算符 %s",
+		AssignBuiltin:    "不能给内置函数 %s 赋值",
+
+		WrongArgCount:     "%s 的参数个数错误: 实际=%d, 应为=%d",
+		BuiltinArgType:    "%s 不支持该参数类型, 实际为 %s",
+		CannotRange:       "不能遍历 %s",
+		OperandMismatch:   "类型不匹配: %s %s %s",
+		UnknownPrefixOp:   "未知的运算符: %s%s",
+		UnknownInfixOp:    "未知的运算符: %s %s %s",
+		DivisionByZero:    "整数除以零",
+		NegativeOperand:   "%s 的右操作数为负数",
+		NotCallable:       "调用非函数 %s",
+		IndexNotSupported: "不支持的索引运算: %s[%s]",
+		StackOverflow:     "栈溢出",
+		BadOperand:        "内部错误: %s 操作数出现在 %s",
+	},
+}
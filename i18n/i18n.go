@@ -0,0 +1,128 @@
+// Package i18n holds the diagnostic message catalogs. Every error the
+// lexer, parser and later passes report is identified by a MessageID
+// and rendered in the language the caller asked for.
+package i18n
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+type Lang string
+
+const (
+	English Lang = "en"
+	Chinese Lang = "zh"
+)
+
+// Langs lists the languages that have a catalog.
+var Langs = []Lang{English, Chinese}
+
+type MessageID int
+
+const (
+	// lexer
+	UnexpectedChar MessageID = iota
+	UnterminatedString
+	InvalidEscape
+	InvalidNumber
+
+	// parser
+	ExpectedNextToken
+	NoPrefixParseFn
+	InvalidInteger
+	InvalidAssignTarget
+	NotInLoop
+	UndefinedLabel
+	LabelWithoutLoop
+	ExpectedType
+
+	// resolver
+	UndefinedName
+	Redeclared
+
+	// vet
+	VetUnused
+	VetShadow
+	VetShadowBuiltin
+	VetUnreachable
+	VetSelfCompare
+	VetConstCond
+	VetDivZero
+	VetMissingElse
+
+	// types
+	TypeMismatch
+	TypeInvalidOp
+	TypeCannotDeclare
+	TypeCannotAssign
+	TypeCannotReturn
+	TypeCannotPass
+	TypeNotFunc
+	TypeArgCount
+	TypeUnknown
+	TypeBranches
+	TypeRecursive
+
+	// compiler
+	TooManyLocals
+	TooManyConstants
+	UnknownOperator
+	AssignBuiltin
+
+	// runtime
+	WrongArgCount
+	BuiltinArgType
+	CannotRange
+	OperandMismatch
+	UnknownPrefixOp
+	UnknownInfixOp
+	DivisionByZero
+	NegativeOperand
+	NotCallable
+	IndexNotSupported
+	StackOverflow
+	BadOperand
+)
+
+// Sprintf formats the message id in lang. Languages without a catalog,
+// and messages missing from one, fall back to English.
+func Sprintf(lang Lang, id MessageID, args ...any) string {
+	format, ok := catalogs[lang][id]
+	if !ok {
+		format = catalogs[English][id]
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// ParseLang maps a language name or a locale such as "zh_CN.UTF-8"
+// to a supported Lang.
+func ParseLang(s string) (Lang, bool) {
+	s = strings.ToLower(s)
+	if i := strings.IndexAny(s, "_-.@"); i >= 0 {
+		s = s[:i]
+	}
+	for _, lang := range Langs {
+		if s == string(lang) {
+			return lang, true
+		}
+	}
+	return "", false
+}
+
+// FromEnv picks the language from LC_ALL, LC_MESSAGES or LANG, in the
+// order the C library consults them, defaulting to English.
+func FromEnv() Lang {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		v := os.Getenv(name)
+		if v == "" {
+			continue
+		}
+		if lang, ok := ParseLang(v); ok {
+			return lang
+		}
+		return English
+	}
+	return English
+}
@@ -0,0 +1,67 @@
+package i18n
+
+import (
+	"regexp"
+	"testing"
+)
+
+var verbRe = regexp.MustCompile(`%[a-z]`)
+
+// TestCatalogsComplete makes sure every message exists in every
+// catalog and takes the same arguments as the English original.
+func TestCatalogsComplete(t *testing.T) {
+	for id, en := range catalogs[English] {
+		for _, lang := range Langs {
+			msg, ok := catalogs[lang][id]
+			if !ok {
+				t.Errorf("catalog %s is missing message %d (%q)", lang, id, en)
+				continue
+			}
+			if got, want := verbRe.FindAllString(msg, -1), verbRe.FindAllString(en, -1); len(got) != len(want) {
+				t.Errorf("catalog %s message %d verbs %q, english has %q", lang, id, got, want)
+			}
+		}
+	}
+	for _, lang := range Langs {
+		if len(catalogs[lang]) != len(catalogs[English]) {
+			t.Errorf("catalog %s has %d messages, english has %d",
+				lang, len(catalogs[lang]), len(catalogs[English]))
+		}
+	}
+}
+
+func TestParseLang(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Lang
+		ok       bool
+	}{
+		{"en", English, true},
+		{"en_US.UTF-8", English, true},
+		{"zh_CN.UTF-8", Chinese, true},
+		{"zh-Hans", Chinese, true},
+		{"ZH", Chinese, true},
+		{"C", "", false},
+		{"fr_FR", "", false},
+	}
+	for _, tt := range tests {
+		lang, ok := ParseLang(tt.input)
+		if lang != tt.expected || ok != tt.ok {
+			t.Errorf("ParseLang(%q) expected=(%q, %t) got=(%q, %t)",
+				tt.input, tt.expected, tt.ok, lang, ok)
+		}
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv("LC_ALL", "")
+	t.Setenv("LC_MESSAGES", "")
+	t.Setenv("LANG", "zh_CN.UTF-8")
+	if lang := FromEnv(); lang != Chinese {
+		t.Errorf("LANG=zh_CN.UTF-8 expected=%q got=%q", Chinese, lang)
+	}
+	t.Setenv("LC_ALL", "C")
+	if lang := FromEnv(); lang != English {
+		t.Errorf("LC_ALL=C expected=%q got=%q", English, lang)
+	}
+}
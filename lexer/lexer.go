@@ -1,33 +1,160 @@
 package lexer
 
-import "gointer/token"
+import (
+	"bufio"
+	"fmt"
+	"gointer/i18n"
+	"gointer/token"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// bufferSize bounds how much of the input is held in memory at once,
+// so scripts of any length can be lexed straight from a pipe.
+const bufferSize = 4096
 
 type Lexer struct {
-	input        string
+	r            *bufio.Reader
 	position     int
 	readPosition int
-	ch           byte
+	ch           rune
+	line         int
+	column       int
+	eof          bool
+	err          error
+
+	errors []string
+
+	keywords  token.Keywords
+	operators []operator
+	lang      i18n.Lang
+	comments  bool
+
+	buf []byte
+}
+
+type Option func(*Lexer)
+
+// WithKeywords makes the lexer recognize the keywords in kw instead of
+// the default English ones, see token.Dialect.
+func WithKeywords(kw token.Keywords) Option {
+	return func(l *Lexer) {
+		l.keywords = kw
+	}
+}
+
+type operator struct {
+	spelling string
+	typ      token.TokenType
 }
 
-func New(input string) *Lexer {
-	l := &Lexer{input: input}
+// WithOperator makes the lexer emit a token of type typ for spelling,
+// so hosts can add operators such as |> or <=> next to the parser's
+// WithInfix. Custom spellings take priority over the built-in ones and
+// the longest matching spelling wins.
+func WithOperator(spelling string, typ token.TokenType) Option {
+	return func(l *Lexer) {
+		op := operator{spelling: spelling, typ: typ}
+		i := 0
+		for i < len(l.operators) && len(l.operators[i].spelling) >= len(spelling) {
+			i++
+		}
+		l.operators = slices.Insert(l.operators, i, op)
+	}
+}
+
+// WithLang selects the language error messages are reported in.
+func WithLang(lang i18n.Lang) Option {
+	return func(l *Lexer) {
+		l.lang = lang
+	}
+}
+
+// WithComments makes the lexer emit COMMENT tokens for // comments
+// instead of skipping them, for tools that reproduce the source.
+func WithComments() Option {
+	return func(l *Lexer) {
+		l.comments = true
+	}
+}
+
+func New(input string, opts ...Option) *Lexer {
+	return NewReader(strings.NewReader(input), opts...)
+}
+
+// NewReader returns a Lexer that reads its input incrementally from r.
+func NewReader(r io.Reader, opts ...Option) *Lexer {
+	l := &Lexer{r: bufio.NewReaderSize(r, bufferSize), line: 1}
+	for _, opt := range opts {
+		opt(l)
+	}
 	l.readChar()
 	return l
 }
 
+// Err returns the first non-EOF error encountered while reading input.
+// The lexer reports EOF once a read fails.
+func (l *Lexer) Err() error {
+	return l.err
+}
+
+// Errors returns the problems found in the input so far, each
+// prefixed with the line:column it was found at.
+func (l *Lexer) Errors() []string {
+	return l.errors
+}
+
+func (l *Lexer) error(pos token.Position, id i18n.MessageID, args ...any) {
+	l.errors = append(l.errors, fmt.Sprintf("%s: %s", pos, i18n.Sprintf(l.lang, id, args...)))
+}
+
+func (l *Lexer) pos() token.Position {
+	return token.Position{Offset: l.position, Line: l.line, Column: l.column}
+}
+
 func (l *Lexer) readChar() {
-	if l.readPosition >= len(l.input) {
-		l.ch = 0
-	} else {
-		l.ch = l.input[l.readPosition]
+	if l.eof {
+		return
+	}
+	if l.ch == '\n' {
+		l.line++
+		l.column = 0
 	}
 	l.position = l.readPosition
-	l.readPosition += 1
+	l.column++
+	ch, size, err := l.r.ReadRune()
+	if err != nil {
+		if err != io.EOF && l.err == nil {
+			l.err = err
+		}
+		l.ch = 0
+		l.eof = true
+		return
+	}
+	l.ch = ch
+	l.readPosition += size
 }
 
 func (l *Lexer) NextToken() token.Token {
 	var tok token.Token
 	l.skipWhitespace()
+	pos := l.pos()
+
+	if tok, ok := l.readOperator(); ok {
+		tok.Pos, tok.End = pos, l.pos()
+		return tok
+	}
+	if l.isComment() {
+		tok = token.Token{Type: token.COMMENT, Literal: l.readWhile(func(ch rune) bool {
+			return ch != '\n' && ch != 0
+		})}
+		tok.Pos, tok.End = pos, l.pos()
+		return tok
+	}
 
 	switch l.ch {
 	case '=':
@@ -43,6 +170,8 @@ func (l *Lexer) NextToken() token.Token {
 		}
 	case ';':
 		tok = newToken(token.SEMICOLON, l.ch)
+	case ':':
+		tok = newToken(token.COLON, l.ch)
 	case '(':
 		tok = newToken(token.LPAREN, l.ch)
 	case ')':
@@ -50,17 +179,120 @@ func (l *Lexer) NextToken() token.Token {
 	case ',':
 		tok = newToken(token.COMMA, l.ch)
 	case '+':
-		tok = newToken(token.PLUS, l.ch)
+		switch l.peekChar() {
+		case '+':
+			l.readChar()
+			tok = token.Token{
+				Type:    token.INCR,
+				Literal: "++",
+			}
+		case '=':
+			l.readChar()
+			tok = token.Token{
+				Type:    token.PLUS_ASSIGN,
+				Literal: "+=",
+			}
+		default:
+			tok = newToken(token.PLUS, l.ch)
+		}
 	case '{':
 		tok = newToken(token.LBRACE, l.ch)
 	case '}':
 		tok = newToken(token.RBRACE, l.ch)
+	case '[':
+		tok = newToken(token.LBRACKET, l.ch)
+	case ']':
+		tok = newToken(token.RBRACKET, l.ch)
 	case '-':
-		tok = newToken(token.MINUS, l.ch)
+		switch l.peekChar() {
+		case '-':
+			l.readChar()
+			tok = token.Token{
+				Type:    token.DECR,
+				Literal: "--",
+			}
+		case '>':
+			l.readChar()
+			tok = token.Token{
+				Type:    token.ARROW,
+				Literal: "->",
+			}
+		case '=':
+			l.readChar()
+			tok = token.Token{
+				Type:    token.MINUS_ASSIGN,
+				Literal: "-=",
+			}
+		default:
+			tok = newToken(token.MINUS, l.ch)
+		}
+	case '?':
+		tok = newToken(token.QUESTION, l.ch)
 	case '*':
-		tok = newToken(token.ASTERISK, l.ch)
+		switch l.peekChar() {
+		case '*':
+			l.readChar()
+			tok = token.Token{
+				Type:    token.POWER,
+				Literal: "**",
+			}
+		case '=':
+			l.readChar()
+			tok = token.Token{
+				Type:    token.ASTERISK_ASSIGN,
+				Literal: "*=",
+			}
+		default:
+			tok = newToken(token.ASTERISK, l.ch)
+		}
 	case '/':
-		tok = newToken(token.SLASH, l.ch)
+		switch l.peekChar() {
+		case '=':
+			l.readChar()
+			tok = token.Token{
+				Type:    token.SLASH_ASSIGN,
+				Literal: "/=",
+			}
+		default:
+			tok = newToken(token.SLASH, l.ch)
+		}
+	case '%':
+		switch l.peekChar() {
+		case '=':
+			l.readChar()
+			tok = token.Token{
+				Type:    token.PERCENT_ASSIGN,
+				Literal: "%=",
+			}
+		default:
+			tok = newToken(token.PERCENT, l.ch)
+		}
+	case '&':
+		switch l.peekChar() {
+		case '&':
+			l.readChar()
+			tok = token.Token{
+				Type:    token.AND,
+				Literal: "&&",
+			}
+		default:
+			tok = newToken(token.BIT_AND, l.ch)
+		}
+	case '|':
+		switch l.peekChar() {
+		case '|':
+			l.readChar()
+			tok = token.Token{
+				Type:    token.OR,
+				Literal: "||",
+			}
+		default:
+			tok = newToken(token.BIT_OR, l.ch)
+		}
+	case '^':
+		tok = newToken(token.BIT_XOR, l.ch)
+	case '~':
+		tok = newToken(token.BIT_NOT, l.ch)
 	case '!':
 		switch l.peekChar() {
 		case '=':
@@ -73,29 +305,69 @@ func (l *Lexer) NextToken() token.Token {
 			tok = newToken(token.BANG, l.ch)
 		}
 	case '>':
-		tok = newToken(token.GT, l.ch)
+		switch l.peekChar() {
+		case '=':
+			l.readChar()
+			tok = token.Token{
+				Type:    token.GT_EQ,
+				Literal: ">=",
+			}
+		case '>':
+			l.readChar()
+			tok = token.Token{
+				Type:    token.SHR,
+				Literal: ">>",
+			}
+		default:
+			tok = newToken(token.GT, l.ch)
+		}
 	case '<':
-		tok = newToken(token.LT, l.ch)
+		switch l.peekChar() {
+		case '=':
+			l.readChar()
+			tok = token.Token{
+				Type:    token.LT_EQ,
+				Literal: "<=",
+			}
+		case '<':
+			l.readChar()
+			tok = token.Token{
+				Type:    token.SHL,
+				Literal: "<<",
+			}
+		default:
+			tok = newToken(token.LT, l.ch)
+		}
+	case '"':
+		tok = l.readString(pos)
 	case 0:
 		tok.Type = token.EOF
 	default:
 		if isLetter(l.ch) {
 			tok.Literal = l.readIdentifier()
-			tok.Type = token.LookupIdent(tok.Literal)
+			tok.Type = l.lookupIdent(tok.Literal)
+			tok.Pos, tok.End = pos, l.pos()
 			return tok
 		} else if isDigit(l.ch) {
 			tok.Literal = l.readNumber()
 			tok.Type = token.INT
+			if _, err := strconv.ParseInt(tok.Literal, 0, 64); err != nil {
+				l.error(pos, i18n.InvalidNumber, tok.Literal)
+				tok.Type = token.ILLEGAL
+			}
+			tok.Pos, tok.End = pos, l.pos()
 			return tok
 		} else {
+			l.error(pos, i18n.UnexpectedChar, l.ch)
 			tok = newToken(token.ILLEGAL, l.ch)
 		}
 	}
 	l.readChar()
+	tok.Pos, tok.End = pos, l.pos()
 	return tok
 }
 
-func newToken(typ token.TokenType, ch byte) token.Token {
+func newToken(typ token.TokenType, ch rune) token.Token {
 	return token.Token{
 		Type:    typ,
 		Literal: string(ch),
@@ -103,44 +375,138 @@ func newToken(typ token.TokenType, ch byte) token.Token {
 
 }
 
-func isLetter(ch byte) bool {
+func (l *Lexer) lookupIdent(ident string) token.TokenType {
+	if l.keywords != nil {
+		return l.keywords.Lookup(ident)
+	}
+	return token.LookupIdent(ident)
+}
+
+func isLetter(ch rune) bool {
 	return 'a' <= ch && ch <= 'z' ||
 		'A' <= ch && ch <= 'Z' ||
-		ch == '_'
+		ch == '_' ||
+		ch >= utf8.RuneSelf && unicode.IsLetter(ch)
 }
 
-func isDigit(ch byte) bool {
+func isDigit(ch rune) bool {
 	return ch >= '0' && ch <= '9'
 }
 
 func (l *Lexer) readIdentifier() string {
-	pos := l.position
-	for isLetter(l.ch) {
+	return l.readWhile(isLetter)
+}
+
+// readNumber reads a digit followed by any letters and digits, so that
+// 0x1f, 1_000 and malformed literals such as 12ab come out as a single
+// token that can be validated as a whole.
+func (l *Lexer) readNumber() string {
+	return l.readWhile(func(ch rune) bool {
+		return isDigit(ch) || isLetter(ch)
+	})
+}
+
+// readString reads a double quoted string starting at the current
+// opening quote and leaves l.ch on the closing quote. The token
+// literal holds the unescaped contents.
+func (l *Lexer) readString(start token.Position) token.Token {
+	l.buf = l.buf[:0]
+	for {
 		l.readChar()
+		switch l.ch {
+		case '"':
+			return token.Token{Type: token.STRING, Literal: string(l.buf)}
+		case 0:
+			l.error(start, i18n.UnterminatedString)
+			return token.Token{Type: token.ILLEGAL, Literal: `"` + string(l.buf)}
+		case '\\':
+			pos := l.pos()
+			l.readChar()
+			switch l.ch {
+			case 'n':
+				l.buf = append(l.buf, '\n')
+			case 't':
+				l.buf = append(l.buf, '\t')
+			case 'r':
+				l.buf = append(l.buf, '\r')
+			case '"', '\\':
+				l.buf = utf8.AppendRune(l.buf, l.ch)
+			case 0:
+				l.error(start, i18n.UnterminatedString)
+				return token.Token{Type: token.ILLEGAL, Literal: `"` + string(l.buf)}
+			default:
+				l.error(pos, i18n.InvalidEscape, l.ch)
+				l.buf = append(l.buf, '\\')
+				l.buf = utf8.AppendRune(l.buf, l.ch)
+			}
+		default:
+			l.buf = utf8.AppendRune(l.buf, l.ch)
+		}
 	}
-	return l.input[pos:l.position]
 }
 
-func (l *Lexer) readNumber() string {
-	pos := l.position
-	for isDigit(l.ch) {
+// readWhile consumes characters as long as pred holds. The scratch
+// buffer is reused between tokens, only the returned string is copied.
+func (l *Lexer) readWhile(pred func(rune) bool) string {
+	l.buf = l.buf[:0]
+	for pred(l.ch) {
+		l.buf = utf8.AppendRune(l.buf, l.ch)
 		l.readChar()
 	}
-	return l.input[pos:l.position]
+	return string(l.buf)
+}
+
+// readOperator consumes the longest custom operator starting at l.ch.
+func (l *Lexer) readOperator() (token.Token, bool) {
+	if len(l.operators) == 0 || l.ch == 0 {
+		return token.Token{}, false
+	}
+	for _, op := range l.operators {
+		first, size := utf8.DecodeRuneInString(op.spelling)
+		if first != l.ch {
+			continue
+		}
+		rest, _ := l.r.Peek(len(op.spelling) - size)
+		if string(rest) != op.spelling[size:] {
+			continue
+		}
+		for range utf8.RuneCountInString(op.spelling) {
+			l.readChar()
+		}
+		return token.Token{Type: op.typ, Literal: op.spelling}, true
+	}
+	return token.Token{}, false
 }
 
-func (l *Lexer) peekChar() byte {
-	if l.readPosition >= len(l.input) {
+func (l *Lexer) peekChar() rune {
+	b, _ := l.r.Peek(utf8.UTFMax)
+	if len(b) == 0 {
 		return 0
 	}
-	return l.input[l.readPosition]
+	ch, _ := utf8.DecodeRune(b)
+	return ch
 }
 
+func (l *Lexer) isComment() bool {
+	return l.ch == '/' && l.peekChar() == '/'
+}
+
+// skipWhitespace skips blanks, and // line comments unless they are
+// emitted as tokens.
 func (l *Lexer) skipWhitespace() {
-	for l.ch == ' ' ||
-		l.ch == '\r' ||
-		l.ch == '\n' ||
-		l.ch == '\t' {
-		l.readChar()
+	for {
+		switch {
+		case l.ch == ' ' ||
+			l.ch == '\r' ||
+			l.ch == '\n' ||
+			l.ch == '\t':
+			l.readChar()
+		case !l.comments && l.isComment():
+			for l.ch != '\n' && l.ch != 0 {
+				l.readChar()
+			}
+		default:
+			return
+		}
 	}
 }
@@ -1,8 +1,12 @@
 package lexer
 
 import (
+	"errors"
 	"gointer/token"
+	"io"
+	"strings"
 	"testing"
+	"testing/iotest"
 )
 
 func TestNextToken(t *testing.T) {
@@ -118,3 +122,419 @@ func TestNextToken(t *testing.T) {
 		}
 	}
 }
+
+func TestNextTokenCompoundOperators(t *testing.T) {
+	input := `a <= b >= c;
+	a % b ** c;
+	a && b || c;
+	a & b | c ^ ~d;
+	a << 2 >> 1;
+	i++ + j-- - k?;
+	x += 1 -= 2 *= 3 /= 4 %= a[0];
+	outer: for (x in xs) { while (y) { break outer; continue; } }
+	fn(a: int) -> int
+	`
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.IDENT, "a"},
+		{token.LT_EQ, "<="},
+		{token.IDENT, "b"},
+		{token.GT_EQ, ">="},
+		{token.IDENT, "c"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "a"},
+		{token.PERCENT, "%"},
+		{token.IDENT, "b"},
+		{token.POWER, "**"},
+		{token.IDENT, "c"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "a"},
+		{token.AND, "&&"},
+		{token.IDENT, "b"},
+		{token.OR, "||"},
+		{token.IDENT, "c"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "a"},
+		{token.BIT_AND, "&"},
+		{token.IDENT, "b"},
+		{token.BIT_OR, "|"},
+		{token.IDENT, "c"},
+		{token.BIT_XOR, "^"},
+		{token.BIT_NOT, "~"},
+		{token.IDENT, "d"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "a"},
+		{token.SHL, "<<"},
+		{token.INT, "2"},
+		{token.SHR, ">>"},
+		{token.INT, "1"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "i"},
+		{token.INCR, "++"},
+		{token.PLUS, "+"},
+		{token.IDENT, "j"},
+		{token.DECR, "--"},
+		{token.MINUS, "-"},
+		{token.IDENT, "k"},
+		{token.QUESTION, "?"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.PLUS_ASSIGN, "+="},
+		{token.INT, "1"},
+		{token.MINUS_ASSIGN, "-="},
+		{token.INT, "2"},
+		{token.ASTERISK_ASSIGN, "*="},
+		{token.INT, "3"},
+		{token.SLASH_ASSIGN, "/="},
+		{token.INT, "4"},
+		{token.PERCENT_ASSIGN, "%="},
+		{token.IDENT, "a"},
+		{token.LBRACKET, "["},
+		{token.INT, "0"},
+		{token.RBRACKET, "]"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "outer"},
+		{token.COLON, ":"},
+		{token.FOR, "for"},
+		{token.LPAREN, "("},
+		{token.IDENT, "x"},
+		{token.IN, "in"},
+		{token.IDENT, "xs"},
+		{token.RPAREN, ")"},
+		{token.LBRACE, "{"},
+		{token.WHILE, "while"},
+		{token.LPAREN, "("},
+		{token.IDENT, "y"},
+		{token.RPAREN, ")"},
+		{token.LBRACE, "{"},
+		{token.BREAK, "break"},
+		{token.IDENT, "outer"},
+		{token.SEMICOLON, ";"},
+		{token.CONTINUE, "continue"},
+		{token.SEMICOLON, ";"},
+		{token.RBRACE, "}"},
+		{token.RBRACE, "}"},
+		{token.FUNCTION, "fn"},
+		{token.LPAREN, "("},
+		{token.IDENT, "a"},
+		{token.COLON, ":"},
+		{token.IDENT, "int"},
+		{token.RPAREN, ")"},
+		{token.ARROW, "->"},
+		{token.IDENT, "int"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - token type wrong expected:%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - token literal wrong expected:%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestNewReader(t *testing.T) {
+	long := strings.Repeat("x", 3*bufferSize)
+	input := "let " + long + " = 10 >= 5;\n" +
+		strings.Repeat("a + b;\n", bufferSize)
+
+	want := New(input)
+	got := NewReader(iotest.OneByteReader(strings.NewReader(input)))
+	for i := 0; ; i++ {
+		exp, act := want.NextToken(), got.NextToken()
+		if exp != act {
+			t.Fatalf("tokens[%d] - expected=%+v, got=%+v", i, exp, act)
+		}
+		if exp.Type == token.EOF {
+			break
+		}
+	}
+	if got.Err() != nil {
+		t.Fatalf("unexpected read error: %v", got.Err())
+	}
+}
+
+func TestNewReaderError(t *testing.T) {
+	errBroken := errors.New("broken pipe")
+	r := io.MultiReader(strings.NewReader("let x"), iotest.ErrReader(errBroken))
+	l := NewReader(r)
+
+	tests := []token.TokenType{token.LET, token.IDENT, token.EOF, token.EOF}
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt {
+			t.Fatalf("tests[%d] - token type wrong expected:%q, got=%q", i, tt, tok.Type)
+		}
+	}
+	if !errors.Is(l.Err(), errBroken) {
+		t.Fatalf("l.Err() wrong expected:%v, got=%v", errBroken, l.Err())
+	}
+}
+
+func TestNextTokenStringsAndComments(t *testing.T) {
+	input := `// leading comment
+	let s = "hello world"; // trailing comment
+	"a\tb\n\"c\"\\";
+	"";
+	`
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.LET, "let"},
+		{token.IDENT, "s"},
+		{token.ASSIGN, "="},
+		{token.STRING, "hello world"},
+		{token.SEMICOLON, ";"},
+		{token.STRING, "a\tb\n\"c\"\\"},
+		{token.SEMICOLON, ";"},
+		{token.STRING, ""},
+		{token.SEMICOLON, ";"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - token type wrong expected:%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - token literal wrong expected:%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+	if len(l.Errors()) != 0 {
+		t.Fatalf("unexpected lexer errors: %q", l.Errors())
+	}
+}
+
+func TestNextTokenPosition(t *testing.T) {
+	input := "let x = 10;\n  \"中文\" + y\n"
+	tests := []struct {
+		expectedType token.TokenType
+		expectedPos  token.Position
+	}{
+		{token.LET, token.Position{Offset: 0, Line: 1, Column: 1}},
+		{token.IDENT, token.Position{Offset: 4, Line: 1, Column: 5}},
+		{token.ASSIGN, token.Position{Offset: 6, Line: 1, Column: 7}},
+		{token.INT, token.Position{Offset: 8, Line: 1, Column: 9}},
+		{token.SEMICOLON, token.Position{Offset: 10, Line: 1, Column: 11}},
+		{token.STRING, token.Position{Offset: 14, Line: 2, Column: 3}},
+		{token.PLUS, token.Position{Offset: 23, Line: 2, Column: 8}},
+		{token.IDENT, token.Position{Offset: 25, Line: 2, Column: 10}},
+		{token.EOF, token.Position{Offset: 27, Line: 3, Column: 1}},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - token type wrong expected:%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+		if tok.Pos != tt.expectedPos {
+			t.Fatalf("tests[%d] - token position wrong expected:%+v, got=%+v",
+				i, tt.expectedPos, tok.Pos)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedTypes  []token.TokenType
+		expectedErrors []string
+	}{
+		{
+			"1 @ 2",
+			[]token.TokenType{token.INT, token.ILLEGAL, token.INT},
+			[]string{`1:3: unexpected character '@'`},
+		},
+		{
+			"let s = \"abc",
+			[]token.TokenType{token.LET, token.IDENT, token.ASSIGN, token.ILLEGAL},
+			[]string{`1:9: unterminated string literal`},
+		},
+		{
+			`"a\qb"`,
+			[]token.TokenType{token.STRING},
+			[]string{`1:3: invalid escape sequence "\q" in string literal`},
+		},
+		{
+			"x + 12ab;\n0x;",
+			[]token.TokenType{token.IDENT, token.PLUS, token.ILLEGAL, token.SEMICOLON, token.ILLEGAL, token.SEMICOLON},
+			[]string{`1:5: invalid numeric literal "12ab"`, `2:1: invalid numeric literal "0x"`},
+		},
+		{
+			"99999999999999999999",
+			[]token.TokenType{token.ILLEGAL},
+			[]string{`1:1: invalid numeric literal "99999999999999999999"`},
+		},
+		{
+			"0x1f + 1_000",
+			[]token.TokenType{token.INT, token.PLUS, token.INT},
+			nil,
+		},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		for i, typ := range tt.expectedTypes {
+			tok := l.NextToken()
+			if tok.Type != typ {
+				t.Fatalf("%q tokens[%d] - token type wrong expected:%q, got=%q",
+					tt.input, i, typ, tok.Type)
+			}
+		}
+		if tok := l.NextToken(); tok.Type != token.EOF {
+			t.Fatalf("%q - expected EOF, got=%q", tt.input, tok.Type)
+		}
+		errs := l.Errors()
+		if len(errs) != len(tt.expectedErrors) {
+			t.Fatalf("%q - expected %d errors, got=%q", tt.input, len(tt.expectedErrors), errs)
+		}
+		for i, msg := range tt.expectedErrors {
+			if errs[i] != msg {
+				t.Errorf("%q errors[%d] - expected=%q, got=%q", tt.input, i, msg, errs[i])
+			}
+		}
+	}
+}
+
+func TestNextTokenDialect(t *testing.T) {
+	kw, ok := token.Dialect("zh")
+	if !ok {
+		t.Fatalf("dialect zh not registered")
+	}
+	input := `令 最大 = 函数(甲, 乙) {
+		如果 (甲 > 乙) { 返回 真; } 否则 { 返回 假; }
+	};
+	let`
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.LET, "令"},
+		{token.IDENT, "最大"},
+		{token.ASSIGN, "="},
+		{token.FUNCTION, "函数"},
+		{token.LPAREN, "("},
+		{token.IDENT, "甲"},
+		{token.COMMA, ","},
+		{token.IDENT, "乙"},
+		{token.RPAREN, ")"},
+		{token.LBRACE, "{"},
+		{token.IF, "如果"},
+		{token.LPAREN, "("},
+		{token.IDENT, "甲"},
+		{token.GT, ">"},
+		{token.IDENT, "乙"},
+		{token.RPAREN, ")"},
+		{token.LBRACE, "{"},
+		{token.RETURN, "返回"},
+		{token.TRUE, "真"},
+		{token.SEMICOLON, ";"},
+		{token.RBRACE, "}"},
+		{token.ELSE, "否则"},
+		{token.LBRACE, "{"},
+		{token.RETURN, "返回"},
+		{token.FALSE, "假"},
+		{token.SEMICOLON, ";"},
+		{token.RBRACE, "}"},
+		{token.RBRACE, "}"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "let"},
+		{token.EOF, ""},
+	}
+
+	l := New(input, WithKeywords(kw))
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - token type wrong expected:%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - token literal wrong expected:%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestNextTokenEnd(t *testing.T) {
+	input := "let x == \"中\\n文\";"
+	tests := []struct {
+		expectedLiteral string
+		expectedEnd     token.Position
+	}{
+		{"let", token.Position{Offset: 3, Line: 1, Column: 4}},
+		{"x", token.Position{Offset: 5, Line: 1, Column: 6}},
+		{"==", token.Position{Offset: 8, Line: 1, Column: 9}},
+		{"中\n文", token.Position{Offset: 19, Line: 1, Column: 16}},
+		{";", token.Position{Offset: 20, Line: 1, Column: 17}},
+		{"", token.Position{Offset: 20, Line: 1, Column: 17}},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - token literal wrong expected:%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+		if tok.End != tt.expectedEnd {
+			t.Fatalf("tests[%d] - token end wrong expected:%+v, got=%+v",
+				i, tt.expectedEnd, tok.End)
+		}
+	}
+}
+
+func TestNextTokenComments(t *testing.T) {
+	input := "// top\nx / y // tail\n//"
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+		expectedPos     token.Position
+	}{
+		{token.COMMENT, "// top", token.Position{Offset: 0, Line: 1, Column: 1}},
+		{token.IDENT, "x", token.Position{Offset: 7, Line: 2, Column: 1}},
+		{token.SLASH, "/", token.Position{Offset: 9, Line: 2, Column: 3}},
+		{token.IDENT, "y", token.Position{Offset: 11, Line: 2, Column: 5}},
+		{token.COMMENT, "// tail", token.Position{Offset: 13, Line: 2, Column: 7}},
+		{token.COMMENT, "//", token.Position{Offset: 21, Line: 3, Column: 1}},
+		{token.EOF, "", token.Position{Offset: 23, Line: 3, Column: 3}},
+	}
+
+	l := New(input, WithComments())
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+		if tok.Pos != tt.expectedPos {
+			t.Fatalf("tests[%d] - position wrong. expected=%+v, got=%+v",
+				i, tt.expectedPos, tok.Pos)
+		}
+	}
+}
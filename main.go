@@ -1,10 +1,132 @@
 package main
 
 import (
+	"flag"
+	"fmt"
+	"gointer/i18n"
+	"gointer/lexer"
+	"gointer/optimize"
+	"gointer/parser"
 	"gointer/repl"
+	"gointer/token"
+	"io"
 	"os"
+	"strings"
 )
 
+// commands are the subcommands of gointer, "gointer <name> [flags]".
+// Without one gointer starts the REPL.
+var commands = map[string]func(args []string) int{
+	"ast":    runAST,
+	"check":  runCheck,
+	"disasm": runDisasm,
+	"fmt":    runFmt,
+	"run":    runRun,
+	"vet":    runVet,
+}
+
 func main() {
-	repl.Start(os.Stdin, os.Stdout)
+	if len(os.Args) > 1 {
+		if cmd, ok := commands[os.Args[1]]; ok {
+			os.Exit(cmd(os.Args[2:]))
+		}
+	}
+
+	fs := flag.NewFlagSet("gointer", flag.ExitOnError)
+	common := addCommonFlags(fs)
+	_ = fs.Parse(os.Args[1:])
+
+	lexOpts, _, err := common.options()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gointer: %v\n", err)
+		os.Exit(2)
+	}
+	repl.Start(os.Stdin, os.Stdout, lexOpts...)
+}
+
+// commonFlags are the flags that every command accepts.
+type commonFlags struct {
+	dialect *string
+	lang    *string
+}
+
+func addCommonFlags(fs *flag.FlagSet) *commonFlags {
+	return &commonFlags{
+		dialect: fs.String("dialect", "en",
+			"keyword dialect, one of: "+strings.Join(token.DialectNames(), ", ")),
+		lang: fs.String("lang", string(i18n.FromEnv()),
+			"language of error messages, one of: en, zh (default from LANG)"),
+	}
+}
+
+// keywords returns the keyword table of the selected dialect.
+func (c *commonFlags) keywords() (token.Keywords, bool) {
+	return token.Dialect(*c.dialect)
+}
+
+// language returns the selected language of error messages.
+func (c *commonFlags) language() (i18n.Lang, bool) {
+	return i18n.ParseLang(*c.lang)
+}
+
+func (c *commonFlags) options() ([]lexer.Option, []parser.Option, error) {
+	keywords, ok := c.keywords()
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown dialect %q", *c.dialect)
+	}
+	lang, ok := c.language()
+	if !ok {
+		return nil, nil, fmt.Errorf("unsupported language %q", *c.lang)
+	}
+	lexOpts := []lexer.Option{lexer.WithKeywords(keywords), lexer.WithLang(lang)}
+	parseOpts := []parser.Option{parser.WithLang(lang)}
+	return lexOpts, parseOpts, nil
+}
+
+// optimizeFlags are the flags of the commands that can optimize the
+// program first.
+type optimizeFlags struct {
+	enabled *bool
+	disable *string
+}
+
+// addOptimizeFlags adds -optimize to fs, on by default if on is set,
+// with the given usage, and -disable.
+func addOptimizeFlags(fs *flag.FlagSet, on bool, usage string) *optimizeFlags {
+	return &optimizeFlags{
+		enabled: fs.Bool("optimize", on, usage),
+		disable: fs.String("disable", "", "comma separated `names` of optimization passes to turn off"),
+	}
+}
+
+// options returns the options of optimize.Run the flags select.
+func (o *optimizeFlags) options() ([]optimize.Option, error) {
+	if *o.disable == "" {
+		return nil, nil
+	}
+	names := strings.Split(*o.disable, ",")
+	for _, name := range names {
+		if _, ok := optimize.Lookup(name); !ok {
+			return nil, fmt.Errorf("unknown optimization pass %q", name)
+		}
+	}
+	return []optimize.Option{optimize.Disable(names...)}, nil
+}
+
+// openInput opens the named file, or stdin for "" and "-".
+func openInput(name string) (io.ReadCloser, error) {
+	if name == "" || name == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	return os.Open(name)
+}
+
+// printErrors prints errors to stderr prefixed with the input name.
+func printErrors(name string, errs []string) {
+	if name == "" {
+		name = "-"
+	}
+	for _, msg := range errs {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", name, msg)
+	}
 }
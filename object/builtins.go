@@ -0,0 +1,94 @@
+package object
+
+import (
+	"fmt"
+	"gointer/i18n"
+	"io"
+	"unicode/utf8"
+)
+
+type BuiltinFunction func(out io.Writer, args ...Object) (Object, error)
+
+// A Builtin is a function implemented in Go. It writes its output, if
+// any, to out.
+type Builtin struct {
+	Name string
+	Fn   BuiltinFunction
+}
+
+func (b *Builtin) Type() ObjectType { return BUILTIN_OBJ }
+func (b *Builtin) Inspect() string  { return "builtin " + b.Name }
+
+// Builtins are the functions every program can call, in the order of
+// resolver.DefaultBuiltins. The compiler refers to them by index.
+var Builtins = []*Builtin{
+	{"len", builtinLen},
+	{"first", builtinFirst},
+	{"last", builtinLast},
+	{"rest", builtinRest},
+	{"puts", builtinPuts},
+}
+
+// LookupBuiltin returns the index of the builtin called name.
+func LookupBuiltin(name string) (int, bool) {
+	for i, b := range Builtins {
+		if b.Name == name {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// stringArg returns the single string argument of the builtin name.
+func stringArg(name string, args []Object) (string, error) {
+	if len(args) != 1 {
+		return "", Errorf(i18n.WrongArgCount, name, len(args), 1)
+	}
+	s, ok := args[0].(*String)
+	if !ok {
+		return "", Errorf(i18n.BuiltinArgType, name, args[0].Type())
+	}
+	return s.Value, nil
+}
+
+func builtinLen(out io.Writer, args ...Object) (Object, error) {
+	s, err := stringArg("len", args)
+	if err != nil {
+		return nil, err
+	}
+	return &Integer{Value: int64(utf8.RuneCountInString(s))}, nil
+}
+
+func builtinFirst(out io.Writer, args ...Object) (Object, error) {
+	s, err := stringArg("first", args)
+	if err != nil || s == "" {
+		return NULL, err
+	}
+	_, size := utf8.DecodeRuneInString(s)
+	return &String{Value: s[:size]}, nil
+}
+
+func builtinLast(out io.Writer, args ...Object) (Object, error) {
+	s, err := stringArg("last", args)
+	if err != nil || s == "" {
+		return NULL, err
+	}
+	_, size := utf8.DecodeLastRuneInString(s)
+	return &String{Value: s[len(s)-size:]}, nil
+}
+
+func builtinRest(out io.Writer, args ...Object) (Object, error) {
+	s, err := stringArg("rest", args)
+	if err != nil || s == "" {
+		return NULL, err
+	}
+	_, size := utf8.DecodeRuneInString(s)
+	return &String{Value: s[size:]}, nil
+}
+
+func builtinPuts(out io.Writer, args ...Object) (Object, error) {
+	for _, arg := range args {
+		fmt.Fprintln(out, arg.Inspect())
+	}
+	return NULL, nil
+}
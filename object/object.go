@@ -0,0 +1,165 @@
+// Package object defines the values programs compute with.
+package object
+
+import (
+	"fmt"
+	"gointer/code"
+	"gointer/i18n"
+	"strconv"
+	"unicode/utf8"
+)
+
+type ObjectType string
+
+const (
+	INTEGER_OBJ           = "INTEGER"
+	BOOLEAN_OBJ           = "BOOLEAN"
+	STRING_OBJ            = "STRING"
+	NULL_OBJ              = "NULL"
+	COMPILED_FUNCTION_OBJ = "COMPILED_FUNCTION"
+	CLOSURE_OBJ           = "CLOSURE"
+	BUILTIN_OBJ           = "BUILTIN"
+	CELL_OBJ              = "CELL"
+	ITERATOR_OBJ          = "ITERATOR"
+)
+
+type Object interface {
+	Type() ObjectType
+	Inspect() string
+}
+
+type Integer struct {
+	Value int64
+}
+
+func (i *Integer) Type() ObjectType { return INTEGER_OBJ }
+func (i *Integer) Inspect() string  { return strconv.FormatInt(i.Value, 10) }
+
+type Boolean struct {
+	Value bool
+}
+
+func (b *Boolean) Type() ObjectType { return BOOLEAN_OBJ }
+func (b *Boolean) Inspect() string  { return strconv.FormatBool(b.Value) }
+
+type String struct {
+	Value string
+}
+
+func (s *String) Type() ObjectType { return STRING_OBJ }
+func (s *String) Inspect() string  { return s.Value }
+
+type Null struct{}
+
+func (n *Null) Type() ObjectType { return NULL_OBJ }
+func (n *Null) Inspect() string  { return "null" }
+
+// The values of true, false and null are shared.
+var (
+	TRUE  = &Boolean{Value: true}
+	FALSE = &Boolean{Value: false}
+	NULL  = &Null{}
+)
+
+// NativeBool returns the shared Boolean for b.
+func NativeBool(b bool) *Boolean {
+	if b {
+		return TRUE
+	}
+	return FALSE
+}
+
+// IsTruthy reports whether o counts as true in a condition. Everything
+// but false and null does.
+func IsTruthy(o Object) bool {
+	return o != FALSE && o != NULL
+}
+
+// CompiledFunction is a function literal compiled to bytecode.
+type CompiledFunction struct {
+	Instructions  code.Instructions
+	NumLocals     int
+	NumParameters int
+	// Name is the variable the function was bound to by a let, if any.
+	Name string
+	// Lines gives the source positions of Instructions.
+	Lines code.LineTable
+}
+
+func (cf *CompiledFunction) Type() ObjectType { return COMPILED_FUNCTION_OBJ }
+func (cf *CompiledFunction) Inspect() string {
+	if cf.Name != "" {
+		return fmt.Sprintf("CompiledFunction[%s]", cf.Name)
+	}
+	return fmt.Sprintf("CompiledFunction[%p]", cf)
+}
+
+// A Cell holds a variable that closures capture, so that they all see
+// the assignments to it.
+type Cell struct {
+	Value Object
+}
+
+func (c *Cell) Type() ObjectType { return CELL_OBJ }
+func (c *Cell) Inspect() string  { return fmt.Sprintf("Cell[%s]", c.Value.Inspect()) }
+
+// A Closure is a function value: a compiled function together with the
+// cells of the variables it captures.
+type Closure struct {
+	Fn   *CompiledFunction
+	Free []*Cell
+}
+
+func (c *Closure) Type() ObjectType { return CLOSURE_OBJ }
+func (c *Closure) Inspect() string {
+	if c.Fn.Name != "" {
+		return fmt.Sprintf("Closure[%s]", c.Fn.Name)
+	}
+	return fmt.Sprintf("Closure[%p]", c)
+}
+
+// An Iterator yields the values a for-in loop ranges over.
+type Iterator struct {
+	Next func() (Object, bool)
+}
+
+func (it *Iterator) Type() ObjectType { return ITERATOR_OBJ }
+func (it *Iterator) Inspect() string  { return "iterator" }
+
+// Iterate returns an iterator over o, which must be a string, whose
+// characters it yields.
+func Iterate(o Object) (*Iterator, error) {
+	s, ok := o.(*String)
+	if !ok {
+		return nil, Errorf(i18n.CannotRange, o.Type())
+	}
+	rest := s.Value
+	return &Iterator{Next: func() (Object, bool) {
+		if rest == "" {
+			return nil, false
+		}
+		_, size := utf8.DecodeRuneInString(rest)
+		c := rest[:size]
+		rest = rest[size:]
+		return &String{Value: c}, true
+	}}, nil
+}
+
+// An Error is a runtime error. It is reported in English unless
+// localized.
+type Error struct {
+	Msg  i18n.MessageID
+	Args []any
+}
+
+// Errorf returns the error msg formatted with args.
+func Errorf(msg i18n.MessageID, args ...any) *Error {
+	return &Error{Msg: msg, Args: args}
+}
+
+func (e *Error) Error() string { return e.Localize(i18n.English) }
+
+// Localize returns the message of e in lang.
+func (e *Error) Localize(lang i18n.Lang) string {
+	return i18n.Sprintf(lang, e.Msg, e.Args...)
+}
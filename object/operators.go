@@ -0,0 +1,146 @@
+package object
+
+import "gointer/i18n"
+
+// Binary applies the binary operator op, as written in the source, to
+// left and right. Integers wrap around on overflow, and values of
+// different types are never equal. Every engine uses it, so that they
+// agree on what operators do.
+func Binary(op string, left, right Object) (Object, error) {
+	switch l := left.(type) {
+	case *Integer:
+		if r, ok := right.(*Integer); ok {
+			return integerOp(op, l.Value, r.Value)
+		}
+	case *String:
+		if r, ok := right.(*String); ok {
+			return stringOp(op, l.Value, r.Value)
+		}
+	}
+	// booleans and null are shared, so the other values are only equal
+	// to themselves
+	switch {
+	case op == "==":
+		return NativeBool(left == right), nil
+	case op == "!=":
+		return NativeBool(left != right), nil
+	case left.Type() != right.Type():
+		return nil, Errorf(i18n.OperandMismatch, left.Type(), op, right.Type())
+	}
+	return nil, Errorf(i18n.UnknownInfixOp, left.Type(), op, right.Type())
+}
+
+func integerOp(op string, l, r int64) (Object, error) {
+	var v int64
+	switch op {
+	case "+":
+		v = l + r
+	case "-":
+		v = l - r
+	case "*":
+		v = l * r
+	case "/", "%":
+		if r == 0 {
+			return nil, Errorf(i18n.DivisionByZero)
+		}
+		if op == "/" {
+			v = l / r
+		} else {
+			v = l % r
+		}
+	case "**":
+		if r < 0 {
+			return nil, Errorf(i18n.NegativeOperand, op)
+		}
+		v = 1
+		for x, n := l, r; n > 0; n >>= 1 {
+			if n&1 == 1 {
+				v *= x
+			}
+			x *= x
+		}
+	case "&":
+		v = l & r
+	case "|":
+		v = l | r
+	case "^":
+		v = l ^ r
+	case "<<", ">>":
+		if r < 0 {
+			return nil, Errorf(i18n.NegativeOperand, op)
+		}
+		if op == "<<" {
+			v = l << r
+		} else {
+			v = l >> r
+		}
+	case "==":
+		return NativeBool(l == r), nil
+	case "!=":
+		return NativeBool(l != r), nil
+	case ">":
+		return NativeBool(l > r), nil
+	case ">=":
+		return NativeBool(l >= r), nil
+	case "<":
+		return NativeBool(l < r), nil
+	case "<=":
+		return NativeBool(l <= r), nil
+	default:
+		return nil, Errorf(i18n.UnknownInfixOp, INTEGER_OBJ, op, INTEGER_OBJ)
+	}
+	return &Integer{Value: v}, nil
+}
+
+func stringOp(op string, l, r string) (Object, error) {
+	switch op {
+	case "+":
+		return &String{Value: l + r}, nil
+	case "==":
+		return NativeBool(l == r), nil
+	case "!=":
+		return NativeBool(l != r), nil
+	case ">":
+		return NativeBool(l > r), nil
+	case ">=":
+		return NativeBool(l >= r), nil
+	case "<":
+		return NativeBool(l < r), nil
+	case "<=":
+		return NativeBool(l <= r), nil
+	}
+	return nil, Errorf(i18n.UnknownInfixOp, STRING_OBJ, op, STRING_OBJ)
+}
+
+// Unary applies the prefix operator op to right.
+func Unary(op string, right Object) (Object, error) {
+	if op == "!" {
+		return NativeBool(!IsTruthy(right)), nil
+	}
+	i, ok := right.(*Integer)
+	if !ok {
+		return nil, Errorf(i18n.UnknownPrefixOp, op, right.Type())
+	}
+	switch op {
+	case "-":
+		return &Integer{Value: -i.Value}, nil
+	case "~":
+		return &Integer{Value: ^i.Value}, nil
+	}
+	return nil, Errorf(i18n.UnknownPrefixOp, op, right.Type())
+}
+
+// Index returns left[index]. Strings are indexed by character, and an
+// index out of range gives null.
+func Index(left, index Object) (Object, error) {
+	s, ok := left.(*String)
+	i, isInt := index.(*Integer)
+	if !ok || !isInt {
+		return nil, Errorf(i18n.IndexNotSupported, left.Type(), index.Type())
+	}
+	runes := []rune(s.Value)
+	if i.Value < 0 || i.Value >= int64(len(runes)) {
+		return NULL, nil
+	}
+	return &String{Value: string(runes[i.Value])}, nil
+}
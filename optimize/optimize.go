@@ -0,0 +1,105 @@
+// Package optimize rewrites a program into a simpler one that computes
+// the same values. The rewrites are done by passes, which are
+// registered by name and run in registration order until none of them
+// changes the program any more:
+//
+//	fold        -(5 + 5) * 2 == !true  =>  false
+//	simplify    !(a == b)              =>  a != b
+//	deadbranch  if (true) { a } else { b }  =>  a
+//
+// Folding follows the runtime: integers wrap around on overflow, and
+// operations that fail at runtime, such as a division by zero, are left
+// alone so that they still do.
+package optimize
+
+import (
+	"gointer/ast"
+	"slices"
+)
+
+// A Pass is one kind of rewrite.
+type Pass struct {
+	// Name identifies the pass in flags.
+	Name string
+	// Doc is a one line description of what the pass rewrites.
+	Doc string
+	// Rewrite returns the node that replaces n, or n itself to leave it
+	// alone. Nodes are rewritten bottom-up, so the children of n have
+	// been rewritten already. Rewrite must not modify n in place, and
+	// may return nil only for a statement, to remove it.
+	Rewrite func(n ast.Node) ast.Node
+}
+
+var passes []*Pass
+
+// Register adds p to the passes run by Run, after the ones registered
+// before it. It panics if a pass with the same name is registered.
+func Register(p *Pass) {
+	if _, ok := Lookup(p.Name); ok {
+		panic("optimize: pass " + p.Name + " registered twice")
+	}
+	passes = append(passes, p)
+}
+
+// Passes returns the registered passes in the order they run.
+func Passes() []*Pass {
+	return slices.Clone(passes)
+}
+
+// Lookup returns the registered pass with the given name.
+func Lookup(name string) (*Pass, bool) {
+	for _, p := range passes {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return nil, false
+}
+
+type config struct {
+	disabled map[string]bool
+}
+
+type Option func(*config)
+
+// Disable turns off the passes with the given names.
+func Disable(names ...string) Option {
+	return func(c *config) {
+		for _, name := range names {
+			c.disabled[name] = true
+		}
+	}
+}
+
+// Run runs the enabled passes over program until it no longer changes
+// and returns the result. program itself is rewritten, use ast.Clone
+// first to keep it.
+func Run(program *ast.Program, opts ...Option) *ast.Program {
+	cfg := config{disabled: map[string]bool{}}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	var enabled []*Pass
+	for _, p := range passes {
+		if !cfg.disabled[p.Name] {
+			enabled = append(enabled, p)
+		}
+	}
+
+	for changed := true; changed; {
+		changed = false
+		program = ast.Modify(program, func(n ast.Node) ast.Node {
+			for _, p := range enabled {
+				if n == nil {
+					break
+				}
+				if r := p.Rewrite(n); r != n {
+					changed = true
+					n = r
+				}
+			}
+			return n
+		}).(*ast.Program)
+	}
+	return program
+}
@@ -0,0 +1,140 @@
+package optimize
+
+import (
+	"bytes"
+	"gointer/ast"
+	"gointer/format"
+	"gointer/lexer"
+	"gointer/parser"
+	"testing"
+)
+
+func optimize(t *testing.T, input string, opts ...Option) string {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if errs := p.Errors(); len(errs) != 0 {
+		t.Fatalf("parse errors in %q: %v", input, errs)
+	}
+	var buf bytes.Buffer
+	format.Fprint(&buf, Run(program, opts...))
+	return buf.String()
+}
+
+func TestFold(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"-(5 + 5) * 2 == !true", "false;\n"},
+		{"1 + 2 * 3; 7 / 2; 7 % -3; 2 ** 10", "7;\n3;\n1;\n1024;\n"},
+		{"3 - 5; - -5; -5; ~0", "-2;\n5;\n-5;\n-1;\n"},
+		{"(3 - 5) ** 2", "4;\n"},
+		{"1 << 3 | 1; 12 & 10 ^ 1; -8 >> 1", "9;\n9;\n-4;\n"},
+		{"9223372036854775807 + 1; 9223372036854775807 + 2", "9223372036854775807 + 1;\n-9223372036854775807;\n"},
+		{`"a" + "b"; "a" < "b"; "a" == "a"`, "\"ab\";\ntrue;\ntrue;\n"},
+		{"1 < 2; 2 <= 1; true != false; !5; !!0", "true;\nfalse;\ntrue;\nfalse;\ntrue;\n"},
+		{"1 && false; 0 || false", "false;\ntrue;\n"},
+		{"x + 1 * 2", "x + 2;\n"},
+		{"1 / 0; 1 % 0; 2 ** -1; 1 << -1", "1 / 0;\n1 % 0;\n2 ** -1;\n1 << -1;\n"},
+		{`1 + "a"; 1 == true; 1 != "1"; -"a"; ~true`, "1 + \"a\";\nfalse;\ntrue;\n-\"a\";\n~true;\n"},
+		{"let f = fn(x) { return x * (60 * 60); }", "let f = fn(x) {\n\treturn x * 3600;\n};\n"},
+	}
+	for _, tt := range tests {
+		if got := optimize(t, tt.input, Disable("simplify", "deadbranch")); got != tt.expected {
+			t.Errorf("fold(%q) wrong.\ngot=%q\nwant=%q", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestSimplify(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"!!(a < b); !!a", "a < b;\n!!a;\n"},
+		{"!(a == b); !(a != b)", "a != b;\na == b;\n"},
+		{"a == true; true == a; (a < b) == true; (a < b) != true; (a < b) == false", "a == true;\ntrue == a;\na < b;\n!(a < b);\n!(a < b);\n"},
+		{"false && f(); true || f(); true && a < b; false || a < b", "false;\ntrue;\na < b;\na < b;\n"},
+		{"true && a; a < b && true; a < b || false; a && false; f() && false", "true && a;\na < b;\na < b;\nfalse;\nf() && false;\n"},
+		{`(1 / 0 == 1) && false; ("a" < 1) || true; (a < b) && false`, "1 / 0 == 1 && false;\n\"a\" < 1 || true;\na < b && false;\n"},
+		{`(a == "x") && false; (!a || -1 != ~0) || true; x[0] && false`, "false;\ntrue;\nx[0] && false;\n"},
+	}
+	for _, tt := range tests {
+		if got := optimize(t, tt.input, Disable("fold", "deadbranch")); got != tt.expected {
+			t.Errorf("simplify(%q) wrong.\ngot=%q\nwant=%q", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestDeadBranch(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x = if (true) { 1 } else { 2 }", "let x = 1;\n"},
+		{"let x = if (false) { 1 } else { 2 }", "let x = 2;\n"},
+		{"if (true) { a; b } else { c }; d", "a;\nb;\nd;\n"},
+		{"if (false) { a; b } else { c; d }; e", "c;\nd;\ne;\n"},
+		{"if (false) { a; b }; c", "c;\n"},
+		{"if (false) { a; b }", "if (false) {}\n"},
+		{"if (1) { let a = 1; a } else { b }; c", "if (1) {\n\tlet a = 1;\n\ta;\n}\nc;\n"},
+		{"while (false) { a }; b; while (false) { c }", "b;\nwhile (false) {\n\tc;\n}\n"},
+		{"if (x) { a } else { b }", "if (x) {\n\ta;\n} else {\n\tb;\n}\n"},
+		{"let f = fn() { if (true) { return 1; }; 2 }", "let f = fn() {\n\treturn 1;\n\t2;\n};\n"},
+	}
+	for _, tt := range tests {
+		if got := optimize(t, tt.input, Disable("fold", "simplify")); got != tt.expected {
+			t.Errorf("deadbranch(%q) wrong.\ngot=%q\nwant=%q", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"if (1 < 2) { a } else { b }", "a;\n"},
+		{"let debug = false; if (debug && 1 > 0) { puts(x) }; run()", "let debug = false;\nif (debug && true) {\n\tputs(x);\n}\nrun();\n"},
+		{"if (!(2 * 3 == 6)) { puts(1) } else { x = 1 + 1 }", "x = 2;\n"},
+		{"while (1 > 2 || false) { a }; b", "b;\n"},
+	}
+	for _, tt := range tests {
+		if got := optimize(t, tt.input); got != tt.expected {
+			t.Errorf("Run(%q) wrong.\ngot=%q\nwant=%q", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestRunSpans(t *testing.T) {
+	p := parser.New(lexer.New("let x = (1 + 2) * 3;"))
+	program := Run(p.ParseProgram())
+	let := program.Statments[0].(*ast.LetStatment)
+	lit, ok := let.Value.(*ast.IntegerLiteral)
+	if !ok || lit.Value != 9 {
+		t.Fatalf("value is not 9 got=%s", let.Value)
+	}
+	if lit.Pos().Column != 9 || lit.End().Column != 20 {
+		t.Errorf("literal covers %s-%s, want 1:9-1:20", lit.Pos(), lit.End())
+	}
+}
+
+func TestPasses(t *testing.T) {
+	var names []string
+	for _, p := range Passes() {
+		names = append(names, p.Name)
+	}
+	if got, want := len(names), 3; got != want || names[0] != "fold" {
+		t.Errorf("Passes() = %v", names)
+	}
+	if _, ok := Lookup("deadbranch"); !ok {
+		t.Errorf("Lookup(deadbranch) failed")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Errorf("registering fold twice did not panic")
+		}
+	}()
+	Register(&Pass{Name: "fold"})
+}
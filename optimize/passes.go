@@ -0,0 +1,482 @@
+package optimize
+
+import (
+	"gointer/ast"
+	"gointer/token"
+	"math"
+	"reflect"
+	"strconv"
+)
+
+func init() {
+	Register(&Pass{
+		Name:    "fold",
+		Doc:     "evaluate operators whose operands are literals",
+		Rewrite: fold,
+	})
+	Register(&Pass{
+		Name:    "simplify",
+		Doc:     "simplify boolean expressions such as !!(a < b) and a == true",
+		Rewrite: simplify,
+	})
+	Register(&Pass{
+		Name:    "deadbranch",
+		Doc:     "remove the branches of ifs and the loops whose condition is constant",
+		Rewrite: deadBranch,
+	})
+}
+
+// constant returns the value of e if it is a literal: an int64, a bool
+// or a string. A negative number, which is written as -5, counts.
+func constant(e ast.Expression) (any, bool) {
+	switch e := e.(type) {
+	case *ast.IntegerLiteral:
+		return e.Value, true
+	case *ast.Boolean:
+		return e.Value, true
+	case *ast.StringLiteral:
+		return e.Value, true
+	case *ast.PrefixExpression:
+		if lit, ok := e.Right.(*ast.IntegerLiteral); ok && e.Operator == "-" && lit.Value >= 0 {
+			return -lit.Value, true
+		}
+	}
+	return nil, false
+}
+
+// truthy reports whether v counts as true in a condition. Everything
+// but false does.
+func truthy(v any) bool {
+	return v != false
+}
+
+// literal returns the literal for v covering the source of e, or nil if
+// v cannot be written as one.
+func literal(v any, e ast.Expression) ast.Expression {
+	tok := token.Token{Pos: e.Pos(), End: e.End()}
+	var lit ast.Expression
+	switch v := v.(type) {
+	case int64:
+		if v == math.MinInt64 {
+			return nil
+		}
+		if v < 0 {
+			tok.Type, tok.Literal = token.MINUS, "-"
+			right := literal(-v, e)
+			lit = &ast.PrefixExpression{Token: tok, Operator: "-", Right: right}
+			break
+		}
+		tok.Type, tok.Literal = token.INT, strconv.FormatInt(v, 10)
+		lit = &ast.IntegerLiteral{Token: tok, Value: v}
+	case bool:
+		tok.Type, tok.Literal = token.FALSE, "false"
+		if v {
+			tok.Type, tok.Literal = token.TRUE, "true"
+		}
+		lit = &ast.Boolean{Token: tok, Value: v}
+	case string:
+		tok.Type, tok.Literal = token.STRING, v
+		lit = &ast.StringLiteral{Token: tok, Value: v}
+	default:
+		return nil
+	}
+	lit.(spanner).SetSpan(e.Pos(), e.End())
+	return lit
+}
+
+type spanner interface {
+	SetSpan(from, to token.Position)
+}
+
+func fold(n ast.Node) ast.Node {
+	switch e := n.(type) {
+	case *ast.PrefixExpression:
+		v, ok := constant(e.Right)
+		if !ok {
+			return n
+		}
+		var result any
+		switch x, isInt := v.(int64); {
+		case e.Operator == "!":
+			result = !truthy(v)
+		case e.Operator == "-" && isInt:
+			if _, ok := e.Right.(*ast.IntegerLiteral); ok {
+				// already as simple as it gets
+				return n
+			}
+			result = -x
+		case e.Operator == "~" && isInt:
+			result = ^x
+		default:
+			return n
+		}
+		if lit := literal(result, e); lit != nil {
+			return lit
+		}
+	case *ast.InfixExpression:
+		l, ok := constant(e.Left)
+		if !ok {
+			return n
+		}
+		r, ok := constant(e.Right)
+		if !ok {
+			return n
+		}
+		if result, ok := evalInfix(e.Operator, l, r); ok {
+			if lit := literal(result, e); lit != nil {
+				return lit
+			}
+		}
+	}
+	return n
+}
+
+// evalInfix applies op to the constants l and r as the runtime would.
+// It fails for operands op is not defined on and for operations that
+// fail at runtime.
+func evalInfix(op string, l, r any) (any, bool) {
+	switch op {
+	case "&&":
+		return truthy(l) && truthy(r), true
+	case "||":
+		return truthy(l) || truthy(r), true
+	case "==", "!=":
+		// values of different types are never equal
+		if reflect.TypeOf(l) != reflect.TypeOf(r) {
+			return op == "!=", true
+		}
+	}
+	switch l := l.(type) {
+	case int64:
+		r, ok := r.(int64)
+		if !ok {
+			return nil, false
+		}
+		switch op {
+		case "+":
+			return l + r, true
+		case "-":
+			return l - r, true
+		case "*":
+			return l * r, true
+		case "/":
+			if r == 0 {
+				return nil, false
+			}
+			return l / r, true
+		case "%":
+			if r == 0 {
+				return nil, false
+			}
+			return l % r, true
+		case "**":
+			if r < 0 {
+				return nil, false
+			}
+			return power(l, r), true
+		case "&":
+			return l & r, true
+		case "|":
+			return l | r, true
+		case "^":
+			return l ^ r, true
+		case "<<":
+			if r < 0 {
+				return nil, false
+			}
+			return l << r, true
+		case ">>":
+			if r < 0 {
+				return nil, false
+			}
+			return l >> r, true
+		case "==":
+			return l == r, true
+		case "!=":
+			return l != r, true
+		case "<":
+			return l < r, true
+		case ">":
+			return l > r, true
+		case "<=":
+			return l <= r, true
+		case ">=":
+			return l >= r, true
+		}
+	case string:
+		r, ok := r.(string)
+		if !ok {
+			return nil, false
+		}
+		switch op {
+		case "+":
+			return l + r, true
+		case "==":
+			return l == r, true
+		case "!=":
+			return l != r, true
+		case "<":
+			return l < r, true
+		case ">":
+			return l > r, true
+		case "<=":
+			return l <= r, true
+		case ">=":
+			return l >= r, true
+		}
+	case bool:
+		r, ok := r.(bool)
+		if !ok {
+			return nil, false
+		}
+		switch op {
+		case "==":
+			return l == r, true
+		case "!=":
+			return l != r, true
+		}
+	}
+	return nil, false
+}
+
+// power returns x ** n for n >= 0, wrapping around on overflow.
+func power(x, n int64) int64 {
+	result := int64(1)
+	for ; n > 0; n >>= 1 {
+		if n&1 == 1 {
+			result *= x
+		}
+		x *= x
+	}
+	return result
+}
+
+// boolean reports whether e always evaluates to true or false.
+func boolean(e ast.Expression) bool {
+	switch e := e.(type) {
+	case *ast.Boolean:
+		return true
+	case *ast.PrefixExpression:
+		return e.Operator == "!"
+	case *ast.InfixExpression:
+		switch e.Operator {
+		case "==", "!=", "<", ">", "<=", ">=", "&&", "||":
+			return true
+		}
+	}
+	return false
+}
+
+// safe reports whether e can be left out: evaluating it has no effect
+// besides its value and cannot fail at runtime. Most operators fail on
+// operands of the wrong type, or on some values, so they are only safe
+// on constants they can be folded on.
+func safe(e ast.Expression) bool {
+	switch e := e.(type) {
+	case *ast.IntegerLiteral, *ast.Boolean, *ast.StringLiteral, *ast.Identifier, *ast.FunctionLiteral:
+		return true
+	case *ast.PrefixExpression:
+		if e.Operator == "!" {
+			return safe(e.Right)
+		}
+		v, ok := constant(e.Right)
+		_, isInt := v.(int64)
+		return ok && isInt && (e.Operator == "-" || e.Operator == "~")
+	case *ast.InfixExpression:
+		switch e.Operator {
+		case "&&", "||", "==", "!=":
+			return safe(e.Left) && safe(e.Right)
+		}
+		l, lok := constant(e.Left)
+		r, rok := constant(e.Right)
+		if lok && rok {
+			_, ok := evalInfix(e.Operator, l, r)
+			return ok
+		}
+	}
+	return false
+}
+
+func simplify(n ast.Node) ast.Node {
+	switch e := n.(type) {
+	case *ast.PrefixExpression:
+		if e.Operator != "!" {
+			return n
+		}
+		switch right := e.Right.(type) {
+		case *ast.PrefixExpression:
+			if right.Operator == "!" && boolean(right.Right) {
+				return right.Right
+			}
+		case *ast.InfixExpression:
+			negated := map[string]token.TokenType{"==": token.NOT_EQ, "!=": token.EQ}
+			if t, ok := negated[right.Operator]; ok {
+				ne := *right
+				ne.Token.Type, ne.Token.Literal, ne.Operator = t, string(t), string(t)
+				ne.SetSpan(e.Pos(), e.End())
+				return &ne
+			}
+		}
+	case *ast.InfixExpression:
+		l, lok := constant(e.Left)
+		r, rok := constant(e.Right)
+		switch e.Operator {
+		case "&&", "||":
+			// short is the value of a && b, or a || b, when a alone
+			// decides it and b is not evaluated.
+			short := e.Operator == "||"
+			switch {
+			case lok && truthy(l) == short:
+				return literal(short, e)
+			case lok && boolean(e.Right):
+				return e.Right
+			case rok && truthy(r) != short && boolean(e.Left):
+				return e.Left
+			case rok && truthy(r) == short && safe(e.Left):
+				return literal(short, e)
+			}
+		case "==", "!=":
+			x, b := e.Left, r
+			if lok {
+				x, b = e.Right, l
+			}
+			want, ok := b.(bool)
+			if !ok || lok == rok || !boolean(x) {
+				return n
+			}
+			if want == (e.Operator == "==") {
+				return x
+			}
+			not := &ast.PrefixExpression{
+				Token:    token.Token{Type: token.BANG, Literal: "!", Pos: e.Token.Pos, End: e.Token.End},
+				Operator: "!",
+				Right:    x,
+			}
+			not.SetSpan(e.Pos(), e.End())
+			return not
+		}
+	}
+	return n
+}
+
+func deadBranch(n ast.Node) ast.Node {
+	switch n := n.(type) {
+	case *ast.IfExpression:
+		return deadIf(n)
+	case *ast.BlockStatment:
+		if list, ok := deadStatments(n.Statments); ok {
+			b := *n
+			b.Statments = list
+			return &b
+		}
+	case *ast.Program:
+		if list, ok := deadStatments(n.Statments); ok {
+			p := *n
+			p.Statments = list
+			return &p
+		}
+	}
+	return n
+}
+
+// deadIf removes the branch of e that is never taken. What is left is
+// either the expression of a single expression statement, or an if
+// with the condition true and no else, or with the condition false and
+// an empty block.
+func deadIf(e *ast.IfExpression) ast.Node {
+	v, ok := constant(e.Condition)
+	if !ok {
+		return e
+	}
+	taken := e.Consequence
+	if !truthy(v) {
+		taken = e.Alternative
+	}
+	if taken != nil && len(taken.Statments) == 1 {
+		if s, ok := taken.Statments[0].(*ast.ExpressionStatment); ok && s.Expression != nil {
+			return s.Expression
+		}
+	}
+	switch {
+	case taken == nil:
+		if len(e.Consequence.Statments) == 0 {
+			return e
+		}
+		empty := *e.Consequence
+		empty.Statments = []ast.Statment{}
+		cp := *e
+		cp.Consequence = &empty
+		return &cp
+	case e.Alternative == nil:
+		return e
+	case truthy(v):
+		cp := *e
+		cp.Alternative = nil
+		return &cp
+	default:
+		cp := *e
+		cp.Condition = literal(true, e.Condition)
+		cp.Consequence = e.Alternative
+		cp.Alternative = nil
+		return &cp
+	}
+}
+
+// deadStatments replaces the ifs left by deadIf in list by the
+// statements of the branch taken and removes loops that never run. The
+// last statement is kept if it is no longer needed, as it may be the
+// value of the list.
+func deadStatments(list []ast.Statment) ([]ast.Statment, bool) {
+	var out []ast.Statment
+	changed := false
+	for i, s := range list {
+		last := i == len(list)-1
+		switch s := s.(type) {
+		case *ast.ExpressionStatment:
+			e, ok := s.Expression.(*ast.IfExpression)
+			if !ok || e.Alternative != nil {
+				break
+			}
+			v, ok := constant(e.Condition)
+			if !ok {
+				break
+			}
+			switch {
+			case !truthy(v) || len(e.Consequence.Statments) == 0:
+				if last {
+					break
+				}
+				changed = true
+				continue
+			case !declares(e.Consequence):
+				changed = true
+				out = append(out, e.Consequence.Statments...)
+				continue
+			}
+		case *ast.WhileStatment:
+			if v, ok := constant(s.Condition); ok && !truthy(v) && !last {
+				changed = true
+				continue
+			}
+		}
+		out = append(out, s)
+	}
+	if !changed {
+		return nil, false
+	}
+	if out == nil {
+		out = []ast.Statment{}
+	}
+	return out, true
+}
+
+// declares reports whether b declares a variable of its own, which
+// would leak out if its statements were moved to the enclosing block.
+func declares(b *ast.BlockStatment) bool {
+	for _, s := range b.Statments {
+		if _, ok := s.(*ast.LetStatment); ok {
+			return true
+		}
+	}
+	return false
+}
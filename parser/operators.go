@@ -0,0 +1,90 @@
+package parser
+
+import (
+	"gointer/ast"
+	"gointer/token"
+)
+
+// Assoc is the associativity of an infix operator.
+type Assoc int
+
+const (
+	LeftAssoc Assoc = iota
+	RightAssoc
+)
+
+// PrefixParseFunc parses an expression that starts with p.CurToken().
+// It must leave the last token of the expression as the current token.
+type PrefixParseFunc func(p *Parser) ast.Expression
+
+// InfixParseFunc parses the rest of a binary expression when
+// p.CurToken() is the operator that follows left.
+type InfixParseFunc func(p *Parser, left ast.Expression) ast.Expression
+
+// PostfixParseFunc builds the expression for left followed by the
+// postfix operator p.CurToken().
+type PostfixParseFunc func(p *Parser, left ast.Expression) ast.Expression
+
+// WithPrefix registers a prefix operator on this parser only. A nil fn
+// parses t as an ast.PrefixExpression whose operand binds at PREFIX.
+func WithPrefix(t token.TokenType, fn PrefixParseFunc) Option {
+	return func(p *Parser) {
+		if fn == nil {
+			p.registerPrefix(t, p.parsePrefixExpression)
+			return
+		}
+		p.registerPrefix(t, func() ast.Expression { return fn(p) })
+	}
+}
+
+// WithInfix registers an infix operator on this parser only, with the
+// given precedence and associativity. A nil fn parses t as an
+// ast.InfixExpression. Registering an existing token overrides it.
+func WithInfix(t token.TokenType, precedence int, assoc Assoc, fn InfixParseFunc) Option {
+	return func(p *Parser) {
+		p.precedences[t] = precedence
+		p.rightAssoc[t] = assoc == RightAssoc
+		if fn == nil {
+			p.registerInfix(t, p.parseInfixExpression)
+			return
+		}
+		p.registerInfix(t, func(left ast.Expression) ast.Expression { return fn(p, left) })
+	}
+}
+
+// WithPostfix registers a postfix operator on this parser only. A nil
+// fn parses t as an ast.PostfixExpression.
+func WithPostfix(t token.TokenType, precedence int, fn PostfixParseFunc) Option {
+	return func(p *Parser) {
+		p.postfixPrecedences[t] = precedence
+		if fn == nil {
+			p.registerPostfix(t, p.parsePostfixExpression)
+			return
+		}
+		p.registerPostfix(t, func(left ast.Expression) ast.Expression { return fn(p, left) })
+	}
+}
+
+// CurToken returns the token being parsed.
+func (p *Parser) CurToken() token.Token { return p.curToken }
+
+// PeekToken returns the token after CurToken.
+func (p *Parser) PeekToken() token.Token { return p.peekToken }
+
+// NextToken advances to the next token.
+func (p *Parser) NextToken() { p.nextToken() }
+
+// ExpectPeek advances if the peek token has type t, and records an
+// error otherwise.
+func (p *Parser) ExpectPeek(t token.TokenType) bool { return p.expectPeek(t) }
+
+// ParseExpression parses an expression starting at CurToken, stopping
+// at the first operator that does not bind tighter than precedence.
+func (p *Parser) ParseExpression(precedence int) ast.Expression {
+	return p.parseExpression(precedence)
+}
+
+// AddError records a parse error from a custom parse function.
+func (p *Parser) AddError(msg string) {
+	p.errors = append(p.errors, msg)
+}
@@ -0,0 +1,77 @@
+package parser
+
+import (
+	"gointer/ast"
+	"gointer/lexer"
+	"gointer/token"
+	"testing"
+)
+
+const (
+	PIPE      token.TokenType = "|>"
+	SPACESHIP token.TokenType = "<=>"
+	LENGTH    token.TokenType = "#"
+	DEGREES   token.TokenType = "°"
+	COALESCE  token.TokenType = "??"
+)
+
+func TestCustomOperators(t *testing.T) {
+	lexOpts := []lexer.Option{
+		lexer.WithOperator("|>", PIPE),
+		lexer.WithOperator("<=>", SPACESHIP),
+		lexer.WithOperator("#", LENGTH),
+		lexer.WithOperator("°", DEGREES),
+		lexer.WithOperator("??", COALESCE),
+	}
+	coalesce := func(p *Parser, left ast.Expression) ast.Expression {
+		exp := &ast.InfixExpression{Token: p.CurToken(), Operator: "??", Left: left}
+		p.NextToken()
+		exp.Right = p.ParseExpression(LOGICALOR - 1)
+		return exp
+	}
+	parseOpts := []Option{
+		WithInfix(PIPE, LOWEST+5, LeftAssoc, nil),
+		WithInfix(SPACESHIP, LESSGRETATER, LeftAssoc, nil),
+		WithInfix(COALESCE, LOGICALOR, RightAssoc, coalesce),
+		WithPrefix(LENGTH, nil),
+		WithPostfix(DEGREES, POSTFIX, nil),
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"a |> f |> g", "((a|>f)|>g)"},
+		{"a + 1 |> f", "((a+1)|>f)"},
+		{"a || b |> f", "((a||b)|>f)"},
+		{"a <=> b + c", "(a<=>(b+c))"},
+		{"a <= b", "(a<=b)"},
+		{"a ?? b ?? c", "(a??(b??c))"},
+		{"#a + b", "((#a)+b)"},
+		{"90° + 1", "((90°)+1)"},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input, lexOpts...), parseOpts...)
+		prog := p.ParseProgram()
+		checkParseError(t, p)
+		if act := prog.String(); act != tt.expected {
+			t.Fatalf("%q expected=%q, got=%q", tt.input, tt.expected, act)
+		}
+	}
+}
+
+func TestCustomOperatorsScopedToParser(t *testing.T) {
+	p := New(lexer.New("a ** b ** c"), WithInfix(token.POWER, POWER, LeftAssoc, nil))
+	prog := p.ParseProgram()
+	checkParseError(t, p)
+	if act := prog.String(); act != "((a**b)**c)" {
+		t.Fatalf("expected=%q, got=%q", "((a**b)**c)", act)
+	}
+
+	p = New(lexer.New("a ** b ** c"))
+	prog = p.ParseProgram()
+	checkParseError(t, p)
+	if act := prog.String(); act != "(a**(b**c))" {
+		t.Fatalf("expected=%q, got=%q", "(a**(b**c))", act)
+	}
+}
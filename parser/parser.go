@@ -1,10 +1,13 @@
 package parser
 
 import (
-	"fmt"
 	"gointer/ast"
+	"gointer/i18n"
 	"gointer/lexer"
 	"gointer/token"
+	"io"
+	"maps"
+	"slices"
 	"strconv"
 )
 
@@ -15,27 +18,59 @@ type Parser struct {
 	peekToken token.Token
 
 	errors []string
+	lang   i18n.Lang
 
-	prefixParseFns map[token.TokenType]prefixParseFn
-	infixParseFns  map[token.TokenType]infixParseFn
+	tracer     io.Writer
+	traceLevel int
+
+	// labels of the loops enclosing the current token, innermost
+	// last, "" for an unlabeled loop
+	loops []string
+
+	comments []*ast.Comment
+
+	prefixParseFns  map[token.TokenType]prefixParseFn
+	infixParseFns   map[token.TokenType]infixParseFn
+	postfixParseFns map[token.TokenType]postfixParseFn
+
+	precedences        map[token.TokenType]int
+	postfixPrecedences map[token.TokenType]int
+	rightAssoc         map[token.TokenType]bool
+}
+
+type Option func(*Parser)
+
+// WithLang selects the language parse errors are reported in. Lexer
+// errors use the language the lexer was created with.
+func WithLang(lang i18n.Lang) Option {
+	return func(p *Parser) {
+		p.lang = lang
+	}
 }
 
-func New(l *lexer.Lexer) *Parser {
+func New(l *lexer.Lexer, opts ...Option) *Parser {
 	p := &Parser{
 		l: l, errors: make([]string, 0),
-		prefixParseFns: make(map[token.TokenType]prefixParseFn),
-		infixParseFns:  make(map[token.TokenType]infixParseFn),
+		prefixParseFns:  make(map[token.TokenType]prefixParseFn),
+		infixParseFns:   make(map[token.TokenType]infixParseFn),
+		postfixParseFns: make(map[token.TokenType]postfixParseFn),
+
+		precedences:        maps.Clone(precedences),
+		postfixPrecedences: maps.Clone(postfixPrecedences),
+		rightAssoc:         maps.Clone(rightAssoc),
 	}
-	p.nextToken()
-	p.nextToken()
 	{
 		p.registerPrefix(token.IDENT, p.parseIdentifier)
 		p.registerPrefix(token.INT, p.parseIntegerLiteral)
+		p.registerPrefix(token.STRING, p.parseStringLiteral)
 		p.registerPrefix(token.BANG, p.parsePrefixExpression)
 		p.registerPrefix(token.MINUS, p.parsePrefixExpression)
+		p.registerPrefix(token.BIT_NOT, p.parsePrefixExpression)
 		p.registerPrefix(token.TRUE, p.parseBoolean)
 		p.registerPrefix(token.FALSE, p.parseBoolean)
 		p.registerPrefix(token.LPAREN, p.parseGroupedExpression)
+		p.registerPrefix(token.IF, p.parseIfExpression)
+		p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
 	}
 	{
 		p.registerInfix(token.PLUS, p.parseInfixExpression)
@@ -46,7 +81,37 @@ func New(l *lexer.Lexer) *Parser {
 		p.registerInfix(token.NOT_EQ, p.parseInfixExpression)
 		p.registerInfix(token.LT, p.parseInfixExpression)
 		p.registerInfix(token.GT, p.parseInfixExpression)
+		p.registerInfix(token.LT_EQ, p.parseInfixExpression)
+		p.registerInfix(token.GT_EQ, p.parseInfixExpression)
+		p.registerInfix(token.PERCENT, p.parseInfixExpression)
+		p.registerInfix(token.POWER, p.parseInfixExpression)
+		p.registerInfix(token.AND, p.parseInfixExpression)
+		p.registerInfix(token.OR, p.parseInfixExpression)
+		p.registerInfix(token.BIT_AND, p.parseInfixExpression)
+		p.registerInfix(token.BIT_OR, p.parseInfixExpression)
+		p.registerInfix(token.BIT_XOR, p.parseInfixExpression)
+		p.registerInfix(token.SHL, p.parseInfixExpression)
+		p.registerInfix(token.SHR, p.parseInfixExpression)
+		p.registerInfix(token.LPAREN, p.parseCallExpression)
+		p.registerInfix(token.LBRACKET, p.parseIndexExpression)
+		p.registerInfix(token.ASSIGN, p.parseAssignExpression)
+		p.registerInfix(token.PLUS_ASSIGN, p.parseAssignExpression)
+		p.registerInfix(token.MINUS_ASSIGN, p.parseAssignExpression)
+		p.registerInfix(token.ASTERISK_ASSIGN, p.parseAssignExpression)
+		p.registerInfix(token.SLASH_ASSIGN, p.parseAssignExpression)
+		p.registerInfix(token.PERCENT_ASSIGN, p.parseAssignExpression)
 	}
+	{
+		p.registerPostfix(token.INCR, p.parsePostfixExpression)
+		p.registerPostfix(token.DECR, p.parsePostfixExpression)
+		p.registerPostfix(token.BANG, p.parsePostfixExpression)
+		p.registerPostfix(token.QUESTION, p.parsePostfixExpression)
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	p.nextToken()
+	p.nextToken()
 	return p
 }
 
@@ -56,14 +121,30 @@ func (p *Parser) registerPrefix(tokenType token.TokenType, fn prefixParseFn) {
 func (p *Parser) registerInfix(tokenType token.TokenType, fn infixParseFn) {
 	p.infixParseFns[tokenType] = fn
 }
+func (p *Parser) registerPostfix(tokenType token.TokenType, fn postfixParseFn) {
+	p.postfixParseFns[tokenType] = fn
+}
 
+// Errors returns the lexer's errors followed by the parse errors, so
+// that the root cause of a bad token is reported first.
 func (p *Parser) Errors() []string {
-	return p.errors
+	lexErrs := p.l.Errors()
+	errs := make([]string, 0, len(lexErrs)+len(p.errors))
+	errs = append(errs, lexErrs...)
+	return append(errs, p.errors...)
 }
 
+// nextToken advances by one token. Comments, which the lexer only
+// emits with lexer.WithComments, are set aside for the program.
 func (p *Parser) nextToken() {
 	p.curToken = p.peekToken
 	p.peekToken = p.l.NextToken()
+	for p.peekToken.Type == token.COMMENT {
+		c := &ast.Comment{Token: p.peekToken}
+		c.SetSpan(p.peekToken.Pos, p.peekToken.End)
+		p.comments = append(p.comments, c)
+		p.peekToken = p.l.NextToken()
+	}
 }
 
 func (p *Parser) ParseProgram() *ast.Program {
@@ -77,26 +158,55 @@ func (p *Parser) ParseProgram() *ast.Program {
 		}
 		p.nextToken()
 	}
+	program.Comments = p.comments
 	return program
 }
 
 func (p *Parser) parseStatment() ast.Statment {
-	var result ast.Statment
-	switch p.curToken.Type {
-	case token.LET:
-		result = p.parseLetStatment()
-	case token.RETURN:
-		result = p.parseReturnStatment()
+	defer p.untrace(p.trace("parseStatment"))
+	start := p.curToken.Pos
+	var stmt ast.Statment
+	switch {
+	case p.curTokenIs(token.LET):
+		if let := p.parseLetStatment(); let != nil {
+			stmt = let
+		}
+	case p.curTokenIs(token.RETURN):
+		stmt = p.parseReturnStatment()
+	case p.curTokenIs(token.WHILE) || p.curTokenIs(token.FOR):
+		stmt = p.parseLoopStatment(nil)
+	case p.curTokenIs(token.BREAK) || p.curTokenIs(token.CONTINUE):
+		stmt = p.parseBranchStatment()
+	case p.curTokenIs(token.IDENT) && p.peekTokenIs(token.COLON):
+		stmt = p.parseLabeledStatment()
 	default:
-		result = p.parseExpressionStatment()
+		stmt = p.parseExpressionStatment()
 	}
-	if result != nil {
-		return result
+	p.finishSpan(stmt, start)
+	return stmt
+}
+
+type spanner interface {
+	SetSpan(from, to token.Position)
+}
+
+// finishSpan records that n runs from start to the end of the current
+// token, the last one it was parsed from. n may be nil.
+func (p *Parser) finishSpan(n ast.Node, start token.Position) {
+	if s, ok := n.(spanner); ok {
+		s.SetSpan(start, p.curToken.End)
 	}
-	return nil
+}
+
+// newIdentifier returns an identifier for the current token.
+func (p *Parser) newIdentifier() *ast.Identifier {
+	ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	ident.SetSpan(p.curToken.Pos, p.curToken.End)
+	return ident
 }
 
 func (p *Parser) parseGroupedExpression() ast.Expression {
+	defer p.untrace(p.trace("parseGroupedExpression"))
 	p.nextToken()
 	exp := p.parseExpression(LOWEST)
 	if !p.expectPeek(token.RPAREN) {
@@ -106,6 +216,7 @@ func (p *Parser) parseGroupedExpression() ast.Expression {
 }
 
 func (p *Parser) parseExpressionStatment() *ast.ExpressionStatment {
+	defer p.untrace(p.trace("parseExpressionStatment"))
 	stmt := &ast.ExpressionStatment{Token: p.curToken}
 	stmt.Expression = p.parseExpression(LOWEST)
 	if p.peekTokenIs(token.SEMICOLON) {
@@ -115,24 +226,163 @@ func (p *Parser) parseExpressionStatment() *ast.ExpressionStatment {
 }
 
 func (p *Parser) parseExpression(precedence int) ast.Expression {
+	defer p.untrace(p.tracePrecedence("parseExpression", precedence))
 	prefix := p.prefixParseFns[p.curToken.Type]
+	if p.curTokenIs(token.ILLEGAL) {
+		// the lexer reported the token already: parse the operators after
+		// it as if it were an operand, so that they are not reported
+		// too, and drop the whole expression
+		bad := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		p.parseOperators(bad, p.curToken.Pos, precedence)
+		return nil
+	}
 	if prefix == nil {
 		p.noPrefixParseFnError(p.curToken.Type)
 		return nil
 	}
+	// every expression parsed here starts at start, including the
+	// operand of a grouped expression, whose span grows to cover the
+	// parentheses
+	start := p.curToken.Pos
 	leftExp := prefix()
-	for !p.peekTokenIs(token.SEMICOLON) && precedence < p.peekPrecedence() {
+	p.finishSpan(leftExp, start)
+	return p.parseOperators(leftExp, start, precedence)
+}
+
+// parseOperators parses the postfix and infix operators that follow
+// leftExp, which starts at start, as long as they bind tighter than
+// precedence.
+func (p *Parser) parseOperators(leftExp ast.Expression, start token.Position, precedence int) ast.Expression {
+	for !p.peekTokenIs(token.SEMICOLON) {
+		if postfix := p.peekPostfix(); postfix != nil {
+			if precedence >= p.peekPostfixPrecedence() {
+				return leftExp
+			}
+			p.nextToken()
+			leftExp = postfix(leftExp)
+			p.finishSpan(leftExp, start)
+			continue
+		}
+		if precedence >= p.peekPrecedence() {
+			return leftExp
+		}
 		infix := p.infixParseFns[p.peekToken.Type]
 		if infix == nil {
 			return leftExp
 		}
 		p.nextToken()
 		leftExp = infix(leftExp)
+		p.finishSpan(leftExp, start)
 	}
 	return leftExp
 }
 
+// peekPostfix returns the postfix parse function for the peek token.
+// A token that can also start an expression, like ! in n! and !x, is
+// only taken as a postfix operator when it is written directly after
+// its operand, so "a !b" stays two expressions.
+func (p *Parser) peekPostfix() postfixParseFn {
+	postfix := p.postfixParseFns[p.peekToken.Type]
+	if postfix == nil {
+		return nil
+	}
+	if _, ok := p.prefixParseFns[p.peekToken.Type]; ok && !p.peekAdjacent() {
+		return nil
+	}
+	return postfix
+}
+
+// peekAdjacent reports whether no whitespace separates the current
+// and the peek token.
+func (p *Parser) peekAdjacent() bool {
+	if p.curTokenIs(token.STRING) {
+		// the literal is unquoted, see lexer.readString
+		return false
+	}
+	return p.curToken.Pos.Offset+len(p.curToken.Literal) == p.peekToken.Pos.Offset
+}
+
+func (p *Parser) parsePostfixExpression(left ast.Expression) ast.Expression {
+	defer p.untrace(p.trace("parsePostfixExpression"))
+	if p.curTokenIs(token.INCR) || p.curTokenIs(token.DECR) {
+		p.checkAssignTarget(left)
+	}
+	return &ast.PostfixExpression{
+		Token:    p.curToken,
+		Left:     left,
+		Operator: p.curToken.Literal,
+	}
+}
+
+// parseAssignExpression parses x = v and the compound forms x += v.
+// Assignment is right-associative, a = b = c assigns c to both.
+func (p *Parser) parseAssignExpression(target ast.Expression) ast.Expression {
+	defer p.untrace(p.trace("parseAssignExpression"))
+	p.checkAssignTarget(target)
+	exp := &ast.AssignExpression{
+		Token:    p.curToken,
+		Target:   target,
+		Operator: p.curToken.Literal,
+	}
+	precedence := p.curPrecedence()
+	p.nextToken()
+	exp.Value = p.parseExpression(precedence - 1)
+	return exp
+}
+
+// checkAssignTarget reports targets other than a name or an index
+// expression, such as 1 = x or f() += 1. Index targets such as
+// a[i] = v only parse: strings cannot be changed and there are no
+// arrays or hashes yet, so the compiler and the evaluator reject them.
+func (p *Parser) checkAssignTarget(target ast.Expression) {
+	switch target.(type) {
+	case *ast.Identifier, *ast.IndexExpression, nil:
+	default:
+		p.error(i18n.InvalidAssignTarget, target)
+	}
+}
+
+func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
+	defer p.untrace(p.trace("parseCallExpression"))
+	exp := &ast.CallExpression{Token: p.curToken, Function: function}
+	exp.Arguments = p.parseExpressionList(token.RPAREN)
+	return exp
+}
+
+func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
+	defer p.untrace(p.trace("parseIndexExpression"))
+	exp := &ast.IndexExpression{Token: p.curToken, Left: left}
+	p.nextToken()
+	exp.Index = p.parseExpression(LOWEST)
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+	return exp
+}
+
+// parseExpressionList parses comma separated expressions up to the
+// end token, the current token being the opening delimiter.
+func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
+	list := make([]ast.Expression, 0)
+	if p.peekTokenIs(end) {
+		p.nextToken()
+		return list
+	}
+	p.nextToken()
+	list = append(list, p.parseExpression(LOWEST))
+	for p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		p.nextToken()
+		list = append(list, p.parseExpression(LOWEST))
+	}
+	if !p.expectPeek(end) {
+		return nil
+	}
+	return list
+}
+
 func (p *Parser) parsePrefixExpression() ast.Expression {
+	defer p.untrace(p.trace("parsePrefixExpression"))
 	expr := &ast.PrefixExpression{
 		Token:    p.curToken,
 		Operator: p.curToken.Literal,
@@ -143,55 +393,387 @@ func (p *Parser) parsePrefixExpression() ast.Expression {
 }
 
 func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
+	defer p.untrace(p.trace("parseInfixExpression"))
 	exp := &ast.InfixExpression{
 		Token:    p.curToken,
 		Operator: p.curToken.Literal,
 		Left:     left,
 	}
 	precedence := p.curPrecedence()
+	if p.rightAssoc[p.curToken.Type] {
+		// a ** b ** c == a ** (b ** c)
+		precedence--
+	}
 	p.nextToken()
 	exp.Right = p.parseExpression(precedence)
 	return exp
 }
 
+func (p *Parser) parseIfExpression() ast.Expression {
+	defer p.untrace(p.trace("parseIfExpression"))
+	exp := &ast.IfExpression{Token: p.curToken}
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	p.nextToken()
+	exp.Condition = p.parseExpression(LOWEST)
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	exp.Consequence = p.parseBlockStatment()
+
+	if p.peekTokenIs(token.ELSE) {
+		p.nextToken()
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+		exp.Alternative = p.parseBlockStatment()
+	}
+	return exp
+}
+
+func (p *Parser) parseFunctionLiteral() ast.Expression {
+	defer p.untrace(p.trace("parseFunctionLiteral"))
+	lit := &ast.FunctionLiteral{Token: p.curToken}
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	lit.Parameters, lit.ParamTypes = p.parseFunctionParameters()
+	if lit.Parameters == nil {
+		return nil
+	}
+	if p.peekTokenIs(token.ARROW) {
+		p.nextToken()
+		if lit.ReturnType = p.parseAnnotation(); lit.ReturnType == nil {
+			return nil
+		}
+	}
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	// break and continue cannot leave the function body
+	loops := p.loops
+	p.loops = nil
+	lit.Body = p.parseBlockStatment()
+	p.loops = loops
+	return lit
+}
+
+// parseFunctionParameters parses the parameters up to the closing ),
+// the current token being the opening (. The types are nil if no
+// parameter is annotated. It returns nil on an error.
+func (p *Parser) parseFunctionParameters() ([]*ast.Identifier, []ast.TypeExpr) {
+	defer p.untrace(p.trace("parseFunctionParameters"))
+	params := make([]*ast.Identifier, 0)
+	if p.peekTokenIs(token.RPAREN) {
+		p.nextToken()
+		return params, nil
+	}
+	var types []ast.TypeExpr
+	annotated := false
+	for {
+		if !p.expectPeek(token.IDENT) {
+			return nil, nil
+		}
+		params = append(params, p.newIdentifier())
+		var typ ast.TypeExpr
+		if p.peekTokenIs(token.COLON) {
+			p.nextToken()
+			if typ = p.parseAnnotation(); typ == nil {
+				return nil, nil
+			}
+			annotated = true
+		}
+		types = append(types, typ)
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+	if !p.expectPeek(token.RPAREN) {
+		return nil, nil
+	}
+	if !annotated {
+		types = nil
+	}
+	return params, types
+}
+
+// parseAnnotation parses the type after the current : or ->.
+func (p *Parser) parseAnnotation() ast.TypeExpr {
+	p.nextToken()
+	return p.parseType()
+}
+
+// parseType parses the type starting at the current token, leaving its
+// last token as the current one. It returns nil on an error.
+func (p *Parser) parseType() ast.TypeExpr {
+	defer p.untrace(p.trace("parseType"))
+	start := p.curToken.Pos
+	var typ ast.TypeExpr
+	switch p.curToken.Type {
+	case token.IDENT:
+		typ = &ast.NamedType{Token: p.curToken, Name: p.curToken.Literal}
+	case token.FUNCTION:
+		ft := &ast.FunctionType{Token: p.curToken, Params: make([]ast.TypeExpr, 0)}
+		if !p.expectPeek(token.LPAREN) {
+			return nil
+		}
+		if p.peekTokenIs(token.RPAREN) {
+			p.nextToken()
+		} else {
+			for {
+				p.nextToken()
+				param := p.parseType()
+				if param == nil {
+					return nil
+				}
+				ft.Params = append(ft.Params, param)
+				if !p.peekTokenIs(token.COMMA) {
+					break
+				}
+				p.nextToken()
+			}
+			if !p.expectPeek(token.RPAREN) {
+				return nil
+			}
+		}
+		if p.peekTokenIs(token.ARROW) {
+			p.nextToken()
+			if ft.Result = p.parseAnnotation(); ft.Result == nil {
+				return nil
+			}
+		}
+		typ = ft
+	case token.ILLEGAL:
+		// already reported by the lexer
+		return nil
+	default:
+		p.error(i18n.ExpectedType, p.curToken.Type)
+		return nil
+	}
+	p.finishSpan(typ, start)
+	return typ
+}
+
+// parseBlockStatment parses statements from the current { up to the
+// matching }, which is left as the current token.
+func (p *Parser) parseBlockStatment() *ast.BlockStatment {
+	defer p.untrace(p.trace("parseBlockStatment"))
+	block := &ast.BlockStatment{Token: p.curToken}
+	block.Statments = make([]ast.Statment, 0)
+	defer p.finishSpan(block, block.Token.Pos)
+	p.nextToken()
+
+	for !p.curTokenIs(token.RBRACE) {
+		if p.curTokenIs(token.EOF) {
+			p.error(i18n.ExpectedNextToken, token.RBRACE, token.EOF)
+			return block
+		}
+		if stmt := p.parseStatment(); stmt != nil {
+			block.Statments = append(block.Statments, stmt)
+		}
+		p.nextToken()
+	}
+	return block
+}
+
+// parseLabeledStatment parses "label: loop".
+func (p *Parser) parseLabeledStatment() ast.Statment {
+	defer p.untrace(p.trace("parseLabeledStatment"))
+	label := p.newIdentifier()
+	p.nextToken()
+	if !p.peekTokenIs(token.WHILE) && !p.peekTokenIs(token.FOR) {
+		p.error(i18n.LabelWithoutLoop, label)
+		return nil
+	}
+	p.nextToken()
+	return p.parseLoopStatment(label)
+}
+
+func (p *Parser) parseLoopStatment(label *ast.Identifier) ast.Statment {
+	name := ""
+	if label != nil {
+		name = label.Value
+	}
+	p.loops = append(p.loops, name)
+	defer func() { p.loops = p.loops[:len(p.loops)-1] }()
+
+	var stmt ast.Statment
+	if p.curTokenIs(token.WHILE) {
+		stmt = p.parseWhileStatment(label)
+	} else {
+		stmt = p.parseForStatment(label)
+	}
+	if stmt != nil && p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return stmt
+}
+
+func (p *Parser) parseWhileStatment(label *ast.Identifier) ast.Statment {
+	defer p.untrace(p.trace("parseWhileStatment"))
+	stmt := &ast.WhileStatment{Token: p.curToken, Label: label}
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	p.nextToken()
+	stmt.Condition = p.parseExpression(LOWEST)
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	stmt.Body = p.parseBlockStatment()
+	return stmt
+}
+
+// parseForStatment parses both for (x in xs) { } and the C-style
+// for (init; cond; post) { } loop.
+func (p *Parser) parseForStatment(label *ast.Identifier) ast.Statment {
+	defer p.untrace(p.trace("parseForStatment"))
+	tok := p.curToken
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	p.nextToken()
+
+	if p.curTokenIs(token.IDENT) && p.peekTokenIs(token.IN) {
+		stmt := &ast.ForInStatment{Token: tok, Label: label}
+		stmt.Variable = p.newIdentifier()
+		p.nextToken()
+		p.nextToken()
+		stmt.Iterable = p.parseExpression(LOWEST)
+		if !p.expectPeek(token.RPAREN) {
+			return nil
+		}
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+		stmt.Body = p.parseBlockStatment()
+		return stmt
+	}
+
+	stmt := &ast.ForStatment{Token: tok, Label: label}
+	if !p.curTokenIs(token.SEMICOLON) {
+		start := p.curToken.Pos
+		if p.curTokenIs(token.LET) {
+			if init := p.parseLetStatment(); init != nil {
+				stmt.Init = init
+			}
+		} else {
+			stmt.Init = p.parseExpressionStatment()
+		}
+		p.finishSpan(stmt.Init, start)
+		// both consume the ; that ends the init clause
+		if !p.curTokenIs(token.SEMICOLON) {
+			p.error(i18n.ExpectedNextToken, token.SEMICOLON, p.peekToken.Type)
+			return nil
+		}
+	}
+	if !p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+		stmt.Condition = p.parseExpression(LOWEST)
+	}
+	if !p.expectPeek(token.SEMICOLON) {
+		return nil
+	}
+	if !p.peekTokenIs(token.RPAREN) {
+		p.nextToken()
+		stmt.Post = p.parseExpression(LOWEST)
+	}
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	stmt.Body = p.parseBlockStatment()
+	return stmt
+}
+
+// parseBranchStatment parses break and continue, with an optional
+// label naming an enclosing loop.
+func (p *Parser) parseBranchStatment() ast.Statment {
+	defer p.untrace(p.trace("parseBranchStatment"))
+	tok := p.curToken
+	var label *ast.Identifier
+	// the label has to be on the same line, break\nf() is not a label
+	if p.peekTokenIs(token.IDENT) && p.peekToken.Pos.Line == tok.Pos.Line {
+		p.nextToken()
+		label = p.newIdentifier()
+	}
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	switch {
+	case len(p.loops) == 0:
+		p.error(i18n.NotInLoop, tok.Literal)
+	case label != nil && !slices.Contains(p.loops, label.Value):
+		p.error(i18n.UndefinedLabel, label.Value)
+	}
+
+	if tok.Type == token.BREAK {
+		return &ast.BreakStatment{Token: tok, Label: label}
+	}
+	return &ast.ContinueStatment{Token: tok, Label: label}
+}
+
 func (p *Parser) parseIdentifier() ast.Expression {
-	return &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	defer p.untrace(p.trace("parseIdentifier"))
+	return p.newIdentifier()
 }
 
 func (p *Parser) parseLetStatment() *ast.LetStatment {
+	defer p.untrace(p.trace("parseLetStatment"))
 	stmt := &ast.LetStatment{Token: p.curToken}
 	if !p.expectPeek(token.IDENT) {
 		return nil
 	}
-	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	stmt.Name = p.newIdentifier()
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		if stmt.Type = p.parseAnnotation(); stmt.Type == nil {
+			return nil
+		}
+	}
 
 	if !p.expectPeek(token.ASSIGN) {
 		return nil
 	}
+	p.nextToken()
+	stmt.Value = p.parseExpression(LOWEST)
 
-	/*
-		todo: parse expression
-	*/
-
-	for !p.curTokenIs(token.SEMICOLON) {
+	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
 	}
 	return stmt
 }
 
 func (p *Parser) parseIntegerLiteral() ast.Expression {
+	defer p.untrace(p.trace("parseIntegerLiteral"))
 	lit := &ast.IntegerLiteral{Token: p.curToken}
 	val, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
 	if err != nil {
-		p.errors = append(p.errors, fmt.Sprintf("could not parse %q as integer",
-			p.curToken.Literal))
+		p.error(i18n.InvalidInteger, p.curToken.Literal)
 		return nil
 	}
 	lit.Value = val
 	return lit
 }
 
+func (p *Parser) parseStringLiteral() ast.Expression {
+	defer p.untrace(p.trace("parseStringLiteral"))
+	return &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+}
+
 func (p *Parser) parseBoolean() ast.Expression {
+	defer p.untrace(p.trace("parseBoolean"))
 	return &ast.Boolean{
 		Token: p.curToken,
 		Value: p.curTokenIs(token.TRUE),
@@ -216,67 +798,147 @@ func (p *Parser) curTokenIs(typ token.TokenType) bool {
 }
 
 func (p *Parser) peekError(t token.TokenType) {
-	msg := fmt.Sprintf("expected next token to be %s, got %s instead", t, p.peekToken.Type)
-	p.errors = append(p.errors, msg)
+	if p.peekTokenIs(token.ILLEGAL) {
+		// already reported by the lexer
+		return
+	}
+	p.error(i18n.ExpectedNextToken, t, p.peekToken.Type)
 }
 
 func (p *Parser) parseReturnStatment() ast.Statment {
+	defer p.untrace(p.trace("parseReturnStatment"))
 	rs := &ast.ReturnStatment{Token: p.curToken}
 
 	p.nextToken()
+	rs.ReturnValue = p.parseExpression(LOWEST)
 
-	/*
-		todo: parse expression
-	*/
-	for !p.curTokenIs(token.SEMICOLON) {
+	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
 	}
-
 	return rs
 }
 
 func (p *Parser) noPrefixParseFnError(t token.TokenType) {
-	p.errors = append(p.errors,
-		fmt.Sprintf("no prefix parse function for %s found", t))
+	if t == token.ILLEGAL {
+		// already reported by the lexer
+		return
+	}
+	p.error(i18n.NoPrefixParseFn, t)
+}
+
+func (p *Parser) error(id i18n.MessageID, args ...any) {
+	p.errors = append(p.errors, i18n.Sprintf(p.lang, id, args...))
 }
 
 type (
-	prefixParseFn func() ast.Expression
-	infixParseFn  func(ast.Expression) ast.Expression
-	// sufixParseFn func(ast.Expression) ast.Exporession
+	prefixParseFn  func() ast.Expression
+	infixParseFn   func(ast.Expression) ast.Expression
+	postfixParseFn func(ast.Expression) ast.Expression
 )
 
+// Precedence levels, lowest first. They are spaced apart so that
+// operators registered with WithInfix or WithPostfix can be slotted in
+// between two levels, e.g. SUM+5.
+//
+// Unlike in C, the bitwise operators bind tighter than the comparisons,
+// as in Go: integers are not booleans, so x & 1 == 0 is only useful as
+// (x & 1) == 0.
 const (
-	_ int = iota
+	_ int = iota * 10
 	LOWEST
+	ASSIGNMENT   // = +=
+	LOGICALOR    // ||
+	LOGICALAND   // &&
 	EQUALS       // ==
 	LESSGRETATER // > <
+	BITOR        // |
+	BITXOR       // ^
+	BITAND       // &
+	SHIFT        // << >>
 	SUM          // +
 	PRODUCT      // *
-	PREFIX       // -X !X
+	PREFIX       // -X !X ~X
+	POWER        // **
+	POSTFIX      // X++ X! X?
 	CALL         // Fn(x)
+	INDEX        // a[i]
 )
 
 var precedences = map[token.TokenType]int{
-	token.EQ:       EQUALS,
-	token.NOT_EQ:   EQUALS,
-	token.LT:       LESSGRETATER,
-	token.GT:       LESSGRETATER,
-	token.PLUS:     SUM,
-	token.MINUS:    SUM,
-	token.SLASH:    PRODUCT,
-	token.ASTERISK: PRODUCT,
+	token.ASSIGN:          ASSIGNMENT,
+	token.PLUS_ASSIGN:     ASSIGNMENT,
+	token.MINUS_ASSIGN:    ASSIGNMENT,
+	token.ASTERISK_ASSIGN: ASSIGNMENT,
+	token.SLASH_ASSIGN:    ASSIGNMENT,
+	token.PERCENT_ASSIGN:  ASSIGNMENT,
+	token.OR:              LOGICALOR,
+	token.AND:             LOGICALAND,
+	token.BIT_OR:          BITOR,
+	token.BIT_XOR:         BITXOR,
+	token.BIT_AND:         BITAND,
+	token.EQ:              EQUALS,
+	token.NOT_EQ:          EQUALS,
+	token.LT:              LESSGRETATER,
+	token.GT:              LESSGRETATER,
+	token.LT_EQ:           LESSGRETATER,
+	token.GT_EQ:           LESSGRETATER,
+	token.SHL:             SHIFT,
+	token.SHR:             SHIFT,
+	token.PLUS:            SUM,
+	token.MINUS:           SUM,
+	token.SLASH:           PRODUCT,
+	token.ASTERISK:        PRODUCT,
+	token.PERCENT:         PRODUCT,
+	token.POWER:           POWER,
+	token.LPAREN:          CALL,
+	token.LBRACKET:        INDEX,
+}
+
+var rightAssoc = map[token.TokenType]bool{
+	token.POWER: true,
+}
+
+var postfixPrecedences = map[token.TokenType]int{
+	token.INCR:     POSTFIX,
+	token.DECR:     POSTFIX,
+	token.BANG:     POSTFIX,
+	token.QUESTION: POSTFIX,
+}
+
+// Associativity returns the default associativity of the infix
+// operator t.
+func Associativity(t token.TokenType) Assoc {
+	if rightAssoc[t] {
+		return RightAssoc
+	}
+	return LeftAssoc
+}
+
+// Precedence returns the default infix precedence of t, or LOWEST if t
+// is not an infix operator.
+func Precedence(t token.TokenType) int {
+	if p, ok := precedences[t]; ok {
+		return p
+	}
+	return LOWEST
+}
+
+func (p *Parser) peekPostfixPrecedence() int {
+	if p, ok := p.postfixPrecedences[p.peekToken.Type]; ok {
+		return p
+	}
+	return LOWEST
 }
 
 func (p *Parser) peekPrecedence() int {
-	if p, ok := precedences[p.peekToken.Type]; ok {
+	if p, ok := p.precedences[p.peekToken.Type]; ok {
 		return p
 	}
 	return LOWEST
 }
 
 func (p *Parser) curPrecedence() int {
-	if p, ok := precedences[p.curToken.Type]; ok {
+	if p, ok := p.precedences[p.curToken.Type]; ok {
 		return p
 	}
 	return LOWEST
@@ -3,8 +3,11 @@ package parser
 import (
 	"fmt"
 	"gointer/ast"
+	"gointer/i18n"
 	"gointer/lexer"
+	"gointer/token"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -151,6 +154,7 @@ func TestParseingPrefixExpression(t *testing.T) {
 	}{
 		{"!5", "!", 5},
 		{"-15", "-", 15},
+		{"~15", "~", 15},
 	}
 
 	for _, tt := range prefixTests {
@@ -223,6 +227,17 @@ func TestParsingInfixExpression(t *testing.T) {
 		{"5 < 5", 5, "<", 5},
 		{"5 == 5", 5, "==", 5},
 		{"5 != 5", 5, "!=", 5},
+		{"5 <= 5", 5, "<=", 5},
+		{"5 >= 5", 5, ">=", 5},
+		{"5 % 5", 5, "%", 5},
+		{"5 ** 5", 5, "**", 5},
+		{"5 && 5", 5, "&&", 5},
+		{"5 || 5", 5, "||", 5},
+		{"5 & 5", 5, "&", 5},
+		{"5 | 5", 5, "|", 5},
+		{"5 ^ 5", 5, "^", 5},
+		{"5 << 5", 5, "<<", 5},
+		{"5 >> 5", 5, ">>", 5},
 	}
 	for _, tt := range infixTests {
 		p := New(lexer.New(tt.input))
@@ -256,6 +271,47 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 		{"a * b / c", "((a*b)/c)"},
 		{"a + b / c", "(a+(b/c))"},
 		{"a + b * c + d / e - f", "(((a+(b*c))+(d/e))-f)"},
+		{"a + b % c", "(a+(b%c))"},
+		{"a ** b ** c", "(a**(b**c))"},
+		{"a * b ** c", "(a*(b**c))"},
+		{"-a ** b", "(-(a**b))"},
+		{"a ** -b", "(a**(-b))"},
+		{"a <= b == c >= d", "((a<=b)==(c>=d))"},
+		{"a || b && c", "(a||(b&&c))"},
+		{"a && b || c && d", "((a&&b)||(c&&d))"},
+		{"a == b && c != d", "((a==b)&&(c!=d))"},
+		{"a | b ^ c & d", "(a|(b^(c&d)))"},
+		{"a & b == c", "((a&b)==c)"},
+		{"5 & 3 == 1", "((5&3)==1)"},
+		{"a | b < c ^ d", "((a|b)<(c^d))"},
+		{"a == b | c", "(a==(b|c))"},
+		{"a << b + c", "(a<<(b+c))"},
+		{"a < b << c", "(a<(b<<c))"},
+		{"~a & b", "((~a)&b)"},
+		{"a || b | c", "(a||(b|c))"},
+		{"-x++", "(-(x++))"},
+		{"x-- * 2", "((x--)*2)"},
+		{"a + b!", "(a+(b!))"},
+		{"n! == 6", "((n!)==6)"},
+		{"!n!", "(!(n!))"},
+		{"2 ** 3!", "(2**(3!))"},
+		{"(a + b)!", "((a+b)!)"},
+		{"a? + b?", "((a?)+(b?))"},
+		{"a !b", "a(!b)"},
+		{"a\n!b", "a(!b)"},
+		{"a! !b", "(a!)(!b)"},
+		{"a + add(b * c) + d", "((a+add((b*c)))+d)"},
+		{"add(a, b, 1, 2 * 3, 4 + 5, add(6, 7 * 8))", "add(a, b, 1, (2*3), (4+5), add(6, (7*8)))"},
+		{"a * b[1] * c", "((a*(b[1]))*c)"},
+		{"add(a * b[2], b[1], 2 * c[1])", "add((a*(b[2])), (b[1]), (2*(c[1])))"},
+		{"x = 5", "(x=5)"},
+		{"x = y = 5", "(x=(y=5))"},
+		{"x += 1 + 2", "(x+=(1+2))"},
+		{"x = a || b", "(x=(a||b))"},
+		{"a[i] = v", "((a[i])=v)"},
+		{`h["k"] %= 2 ** 3`, "((h[k])%=(2**3))"},
+		{"x *= y /= z -= 1", "(x*=(y/=(z-=1)))"},
+		{"a[i]++", "((a[i])++)"},
 	}
 	for _, tt := range tets {
 		p := New(lexer.New(tt.input))
@@ -269,3 +325,536 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 }
 
 // TODO: add 2.8 helper func
+
+func TestStringLiteralExpression(t *testing.T) {
+	p := New(lexer.New(`"hello world";`))
+	prog := p.ParseProgram()
+	checkParseError(t, p)
+	AssertStmentCount(t, prog, 1)
+	stmt := AssertStmentType[*ast.ExpressionStatment](t, prog, 0)
+	str := AssertExprType[*ast.StringLiteral](t, stmt.Expression)
+	if str.Value != "hello world" {
+		t.Fatalf("str.Value is not %q got=%q", "hello world", str.Value)
+	}
+}
+
+func TestLexerErrorsReportedFirst(t *testing.T) {
+	p := New(lexer.New("let x 5; 1 + @;"))
+	p.ParseProgram()
+
+	expected := []string{
+		"1:14: unexpected character '@'",
+		"expected next token to be =, got INT instead",
+	}
+	errs := p.Errors()
+	if len(errs) != len(expected) {
+		t.Fatalf("expected %d errors got=%q", len(expected), errs)
+	}
+	for i, msg := range expected {
+		if errs[i] != msg {
+			t.Errorf("errors[%d] expected=%q got=%q", i, msg, errs[i])
+		}
+	}
+}
+
+func TestDialect(t *testing.T) {
+	kw, _ := token.Dialect("zh")
+	p := New(lexer.New("令 甲 = 5; 返回 甲; -甲 * 真;", lexer.WithKeywords(kw)))
+	prog := p.ParseProgram()
+	checkParseError(t, p)
+	AssertStmentCount(t, prog, 3)
+	let := AssertStmentType[*ast.LetStatment](t, prog, 0)
+	if let.Name.Value != "甲" {
+		t.Fatalf("let.Name.Value is not %q got=%q", "甲", let.Name.Value)
+	}
+	AssertStmentType[*ast.ReturnStatment](t, prog, 1)
+	stmt := AssertStmentType[*ast.ExpressionStatment](t, prog, 2)
+	if stmt.String() != "((-甲)*真)" {
+		t.Fatalf("expected=%q, got=%q", "((-甲)*真)", stmt.String())
+	}
+}
+
+func TestLocalizedErrors(t *testing.T) {
+	l := lexer.New("let x 5; 1 + @;", lexer.WithLang(i18n.Chinese))
+	p := New(l, WithLang(i18n.Chinese))
+	p.ParseProgram()
+
+	expected := []string{
+		"1:14: 意外的字符 '@'",
+		"下一个词法单元应为 =，实际为 INT",
+	}
+	errs := p.Errors()
+	if len(errs) != len(expected) {
+		t.Fatalf("expected %d errors got=%q", len(expected), errs)
+	}
+	for i, msg := range expected {
+		if errs[i] != msg {
+			t.Errorf("errors[%d] expected=%q got=%q", i, msg, errs[i])
+		}
+	}
+}
+
+func TestIllegalTokenErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"12ab + 0x", []string{`1:1: invalid numeric literal "12ab"`, `1:8: invalid numeric literal "0x"`}},
+		{"let x = 1 @ 2 * 3; x++", []string{`1:11: unexpected character '@'`}},
+		{"let x = 99999999999999999999;", []string{`1:9: invalid numeric literal "99999999999999999999"`}},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		if errs := p.Errors(); !reflect.DeepEqual(errs, tt.expected) {
+			t.Errorf("%q errors = %q, want %q", tt.input, errs, tt.expected)
+		}
+	}
+}
+
+func TestParsingPostfixExpression(t *testing.T) {
+	postfixTests := []struct {
+		input    string
+		ident    string
+		operator string
+	}{
+		{"x++", "x", "++"},
+		{"x--;", "x", "--"},
+		{"n!", "n", "!"},
+		{"res?", "res", "?"},
+	}
+	for _, tt := range postfixTests {
+		p := New(lexer.New(tt.input))
+		prog := p.ParseProgram()
+		checkParseError(t, p)
+		AssertStmentCount(t, prog, 1)
+		stmt := AssertStmentType[*ast.ExpressionStatment](t, prog, 0)
+		exp := AssertExprType[*ast.PostfixExpression](t, stmt.Expression)
+		if exp.Operator != tt.operator {
+			t.Fatalf("exp.Operator is not %s got=%s", tt.operator, exp.Operator)
+		}
+		ident := AssertExprType[*ast.Identifier](t, exp.Left)
+		if ident.Value != tt.ident {
+			t.Fatalf("ident.Value is not %s got=%s", tt.ident, ident.Value)
+		}
+	}
+}
+
+func TestLetStatmentValues(t *testing.T) {
+	tests := []struct {
+		input    string
+		name     string
+		expected string
+	}{
+		{"let x = 5;", "x", "5"},
+		{"let y = true;", "y", "true"},
+		{"let foobar = y * (x + 1)", "foobar", "(y*(x+1))"},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		prog := p.ParseProgram()
+		checkParseError(t, p)
+		AssertStmentCount(t, prog, 1)
+		if !testLetStatment(t, prog.Statments[0], tt.name) {
+			return
+		}
+		let := prog.Statments[0].(*ast.LetStatment)
+		if let.Value.String() != tt.expected {
+			t.Fatalf("let.Value expected=%q got=%q", tt.expected, let.Value.String())
+		}
+	}
+}
+
+func TestParsingAssignExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		target   string
+		operator string
+		value    int64
+	}{
+		{"x = 5;", "x", "=", 5},
+		{"x += 5;", "x", "+=", 5},
+		{"x -= 5;", "x", "-=", 5},
+		{"x *= 5;", "x", "*=", 5},
+		{"x /= 5;", "x", "/=", 5},
+		{"x %= 5;", "x", "%=", 5},
+		{"arr[1] = 5;", "(arr[1])", "=", 5},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		prog := p.ParseProgram()
+		checkParseError(t, p)
+		AssertStmentCount(t, prog, 1)
+		stmt := AssertStmentType[*ast.ExpressionStatment](t, prog, 0)
+		exp := AssertExprType[*ast.AssignExpression](t, stmt.Expression)
+		if exp.Target.String() != tt.target {
+			t.Fatalf("exp.Target is not %s got=%s", tt.target, exp.Target)
+		}
+		if exp.Operator != tt.operator {
+			t.Fatalf("exp.Operator is not %s got=%s", tt.operator, exp.Operator)
+		}
+		if !testIntegerLiteral(t, exp.Value, tt.value) {
+			return
+		}
+	}
+}
+
+func TestInvalidAssignTarget(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1 = x", "cannot assign to 1"},
+		{"f() += 1", "cannot assign to f()"},
+		{"(a + b) = c", "cannot assign to (a+b)"},
+		{"3++", "cannot assign to 3"},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		errs := p.Errors()
+		if len(errs) != 1 || errs[0] != tt.expected {
+			t.Errorf("%q expected error %q got=%q", tt.input, tt.expected, errs)
+		}
+	}
+}
+
+func TestIfExpression(t *testing.T) {
+	tests := []struct {
+		input       string
+		condition   string
+		consequence string
+		alternative string
+	}{
+		{"if (x < y) { x }", "(x<y)", "x", ""},
+		{"if (x < y) { x } else { y; z }", "(x<y)", "x", "yz"},
+		{"if (a && b) { return 1; }", "(a&&b)", "return 1;", ""},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		prog := p.ParseProgram()
+		checkParseError(t, p)
+		AssertStmentCount(t, prog, 1)
+		stmt := AssertStmentType[*ast.ExpressionStatment](t, prog, 0)
+		exp := AssertExprType[*ast.IfExpression](t, stmt.Expression)
+		if exp.Condition.String() != tt.condition {
+			t.Fatalf("exp.Condition is not %s got=%s", tt.condition, exp.Condition)
+		}
+		if exp.Consequence.String() != tt.consequence {
+			t.Fatalf("exp.Consequence is not %s got=%s", tt.consequence, exp.Consequence)
+		}
+		if tt.alternative == "" {
+			if exp.Alternative != nil {
+				t.Fatalf("exp.Alternative was not nil got=%s", exp.Alternative)
+			}
+		} else if exp.Alternative == nil || exp.Alternative.String() != tt.alternative {
+			t.Fatalf("exp.Alternative is not %s got=%v", tt.alternative, exp.Alternative)
+		}
+	}
+}
+
+func TestLoopStatments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"while (i < 10) { i += 1; }", "while (i<10) (i+=1)"},
+		{"for (let i = 0; i < 10; i++) { f(i); }", "for (let i = 0; (i<10); (i++)) f(i)"},
+		{"for (i = 0; i < 10; i += 2) { }", "for ((i=0); (i<10); (i+=2)) "},
+		{"for (;;) { break; }", "for (; ; ) break;"},
+		{"for (x in xs) { continue; };", "for (x in xs) continue;"},
+		{"outer: for (x in xs) { for (y in ys) { break outer; } }", "outer: for (x in xs) for (y in ys) break outer;"},
+		{"l: while (true) { if (a) { continue l } }", "l: while true ifa continue l;"},
+		{"while (a) { break\nf() }", "while a break;f()"},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		prog := p.ParseProgram()
+		checkParseError(t, p)
+		AssertStmentCount(t, prog, 1)
+		if act := prog.String(); act != tt.expected {
+			t.Fatalf("%q expected=%q, got=%q", tt.input, tt.expected, act)
+		}
+	}
+
+	p := New(lexer.New("for (let i = 0; i < n; i++) { }"))
+	prog := p.ParseProgram()
+	checkParseError(t, p)
+	loop := AssertStmentType[*ast.ForStatment](t, prog, 0)
+	if _, ok := loop.Init.(*ast.LetStatment); !ok {
+		t.Fatalf("loop.Init is not *ast.LetStatment got=%T", loop.Init)
+	}
+	AssertExprType[*ast.InfixExpression](t, loop.Condition)
+	AssertExprType[*ast.PostfixExpression](t, loop.Post)
+}
+
+func TestFunctionLiteral(t *testing.T) {
+	tests := []struct {
+		input  string
+		params []string
+		body   string
+	}{
+		{"fn() {};", []string{}, ""},
+		{"fn(x) { x };", []string{"x"}, "x"},
+		{"fn(x, y, z) { return x + y; };", []string{"x", "y", "z"}, "return (x+y);"},
+		{"fn(x) { while (x) { break; } };", []string{"x"}, "while x break;"},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		prog := p.ParseProgram()
+		checkParseError(t, p)
+		AssertStmentCount(t, prog, 1)
+		stmt := AssertStmentType[*ast.ExpressionStatment](t, prog, 0)
+		fn := AssertExprType[*ast.FunctionLiteral](t, stmt.Expression)
+		if len(fn.Parameters) != len(tt.params) {
+			t.Fatalf("%q has %d parameters, want %d", tt.input, len(fn.Parameters), len(tt.params))
+		}
+		for i, name := range tt.params {
+			if fn.Parameters[i].Value != name {
+				t.Errorf("parameter %d is not %s got=%s", i, name, fn.Parameters[i].Value)
+			}
+		}
+		if fn.Body.String() != tt.body {
+			t.Errorf("fn.Body is not %q got=%q", tt.body, fn.Body)
+		}
+	}
+
+	p := New(lexer.New("add(fn(a, b) { a + b }, 2)(3)"))
+	prog := p.ParseProgram()
+	checkParseError(t, p)
+	if got, want := prog.String(), "add(fn(a, b) (a+b), 2)(3)"; got != want {
+		t.Errorf("program.String() wrong. got=%q, want=%q", got, want)
+	}
+}
+
+func TestTypeAnnotations(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x: int = 5;", "let x: int = 5;"},
+		{"let x = 5;", "let x = 5;"},
+		{"let f: fn(int, bool) -> int = g;", "let f: fn(int, bool) -> int = g;"},
+		{"let f: fn() = g;", "let f: fn() = g;"},
+		{"fn(a: int, b) -> bool { a }", "fn(a: int, b) -> bool a"},
+		{"fn(a, b) -> fn(int) -> int { a }", "fn(a, b) -> fn(int) -> int a"},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		prog := p.ParseProgram()
+		checkParseError(t, p)
+		if prog.String() != tt.expected {
+			t.Errorf("program.String() wrong. got=%q, want=%q", prog.String(), tt.expected)
+		}
+	}
+
+	p := New(lexer.New("fn(a, b: int, c) { }"))
+	prog := p.ParseProgram()
+	checkParseError(t, p)
+	stmt := AssertStmentType[*ast.ExpressionStatment](t, prog, 0)
+	fn := AssertExprType[*ast.FunctionLiteral](t, stmt.Expression)
+	if len(fn.ParamTypes) != 3 || fn.ParamType(0) != nil || fn.ParamType(2) != nil {
+		t.Fatalf("fn.ParamTypes wrong. got=%v", fn.ParamTypes)
+	}
+	typ, ok := fn.ParamType(1).(*ast.NamedType)
+	if !ok {
+		t.Fatalf("parameter type is not *ast.NamedType got=%T", fn.ParamType(1))
+	}
+	if typ.Name != "int" || typ.Pos().Column != 10 || typ.End().Column != 13 {
+		t.Errorf("parameter type wrong. got=%s at %s-%s", typ.Name, typ.Pos(), typ.End())
+	}
+
+	p = New(lexer.New("fn(a, b) { }"))
+	prog = p.ParseProgram()
+	checkParseError(t, p)
+	stmt = AssertStmentType[*ast.ExpressionStatment](t, prog, 0)
+	fn = AssertExprType[*ast.FunctionLiteral](t, stmt.Expression)
+	if fn.ParamTypes != nil || fn.ReturnType != nil {
+		t.Errorf("unannotated function has types %v -> %v", fn.ParamTypes, fn.ReturnType)
+	}
+}
+
+func TestLoopErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"break;", "break is not in a loop"},
+		{"if (a) { continue; }", "continue is not in a loop"},
+		{"while (a) { break outer; }", "undefined label outer"},
+		{"outer: while (a) { } for (;;) { continue outer; }", "undefined label outer"},
+		{"outer: x + 1;", "label outer must be followed by a loop"},
+		{"while (a) { x", "expected next token to be }, got EOF instead"},
+		{"for (let i = 0 i < 10; i++) { }", "expected next token to be ;, got IDENT instead"},
+		{"while (a) { fn() { break; } }", "break is not in a loop"},
+		{"outer: while (a) { fn() { while (b) { continue outer; } } }", "undefined label outer"},
+		{"fn(a b) { }", "expected next token to be ), got IDENT instead"},
+		{"fn(a, 1) { }", "expected next token to be IDENT, got INT instead"},
+		{"let x: 1 = 1;", "expected type, got INT"},
+		{"fn(a: int,) { }", "expected next token to be IDENT, got ) instead"},
+		{"fn(a) -> { }", "expected type, got {"},
+		{"let f: fn(int = g;", "expected next token to be ), got = instead"},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		errs := p.Errors()
+		if len(errs) == 0 || errs[0] != tt.expected {
+			t.Errorf("%q expected error %q got=%q", tt.input, tt.expected, errs)
+		}
+	}
+}
+
+func TestSpans(t *testing.T) {
+	input := "let x = (1 + 2) * 3;\nfoo(a, b[1])\nwhile (x) { x -= 1; }"
+	p := New(lexer.New(input))
+	prog := p.ParseProgram()
+	checkParseError(t, p)
+
+	source := func(n ast.Node) string {
+		return input[n.Pos().Offset:n.End().Offset]
+	}
+	let := AssertStmentType[*ast.LetStatment](t, prog, 0)
+	call := AssertStmentType[*ast.ExpressionStatment](t, prog, 1).Expression.(*ast.CallExpression)
+	loop := AssertStmentType[*ast.WhileStatment](t, prog, 2)
+	mul := let.Value.(*ast.InfixExpression)
+
+	tests := []struct {
+		node     ast.Node
+		expected string
+	}{
+		{prog, input},
+		{let, "let x = (1 + 2) * 3;"},
+		{let.Name, "x"},
+		{mul, "(1 + 2) * 3"},
+		{mul.Left, "(1 + 2)"},
+		{mul.Left.(*ast.InfixExpression).Left, "1"},
+		{mul.Right, "3"},
+		{call, "foo(a, b[1])"},
+		{call.Arguments[1], "b[1]"},
+		{loop, "while (x) { x -= 1; }"},
+		{loop.Body, "{ x -= 1; }"},
+		{loop.Body.Statments[0], "x -= 1;"},
+	}
+	for _, tt := range tests {
+		if act := source(tt.node); act != tt.expected {
+			t.Errorf("%T span covers %q, expected %q", tt.node, act, tt.expected)
+		}
+	}
+
+	if pos := call.Pos(); pos.Line != 2 || pos.Column != 1 {
+		t.Errorf("call.Pos() expected 2:1 got=%s", pos)
+	}
+	if end := mul.End(); end.Line != 1 || end.Column != 20 {
+		t.Errorf("mul.End() expected 1:20 got=%s", end)
+	}
+}
+
+func TestPathEnclosing(t *testing.T) {
+	input := "let x = (1 + 2) * 3;"
+	p := New(lexer.New(input))
+	prog := p.ParseProgram()
+	checkParseError(t, p)
+
+	// the cursor on "2"
+	path := ast.PathEnclosing(prog, strings.Index(input, "2"))
+	var types []string
+	for _, n := range path {
+		types = append(types, fmt.Sprintf("%T", n))
+	}
+	expected := "*ast.Program *ast.LetStatment *ast.InfixExpression *ast.InfixExpression *ast.IntegerLiteral"
+	if act := strings.Join(types, " "); act != expected {
+		t.Fatalf("expected=%q got=%q", expected, act)
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	input := `let x = -a * (b + c) ** 2 % 3;
+return !true == false;
+let add = fn(a, b) { return a + b; };
+x += y[1] << 2 | ~z;
+i++;
+s = "a\tb";
+f(1, g(2), h)[0];
+if (x < y && y >= z || !w) { x } else { y };
+outer: while (x) { if (y) { break outer; } continue; }
+for (let i = 0; i < 10; i++) { i }
+for (x in xs) { print(x) }
+`
+	p := New(lexer.New(input))
+	prog := p.ParseProgram()
+	checkParseError(t, p)
+
+	data, err := ast.MarshalJSON(prog)
+	if err != nil {
+		t.Fatalf("MarshalJSON failed: %v", err)
+	}
+	got, err := ast.UnmarshalJSON(data)
+	if err != nil {
+		t.Fatalf("UnmarshalJSON failed: %v", err)
+	}
+	if got.String() != prog.String() {
+		t.Errorf("String() changed in round trip.\ngot=%q\nwant=%q", got.String(), prog.String())
+	}
+	if !reflect.DeepEqual(got, prog) {
+		t.Errorf("program changed in round trip")
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := "// a\nlet x = 1; // b\nx + // c\n  2"
+	p := New(lexer.New(input, lexer.WithComments()))
+	prog := p.ParseProgram()
+	checkParseError(t, p)
+
+	if got, want := prog.String(), "let x = 1;(x+2)"; got != want {
+		t.Errorf("program.String() wrong. got=%q, want=%q", got, want)
+	}
+	expected := []string{"// a", "// b", "// c"}
+	if len(prog.Comments) != len(expected) {
+		t.Fatalf("program has %d comments, want %d", len(prog.Comments), len(expected))
+	}
+	for i, c := range prog.Comments {
+		if c.Token.Literal != expected[i] {
+			t.Errorf("comments[%d] wrong. got=%q, want=%q", i, c.Token.Literal, expected[i])
+		}
+		if src := input[c.Pos().Offset:c.End().Offset]; src != expected[i] {
+			t.Errorf("comments[%d] spans %q", i, src)
+		}
+	}
+}
+
+func TestParsingWholeTree(t *testing.T) {
+	input := "x += -a * (b + c)[0];"
+	p := New(lexer.New(input))
+	prog := p.ParseProgram()
+	checkParseError(t, p)
+
+	expected := &ast.Program{Statments: []ast.Statment{
+		&ast.ExpressionStatment{Expression: &ast.AssignExpression{
+			Target:   &ast.Identifier{Value: "x"},
+			Operator: "+=",
+			Value: &ast.InfixExpression{
+				Left: &ast.PrefixExpression{
+					Operator: "-",
+					Right:    &ast.Identifier{Value: "a"},
+				},
+				Operator: "*",
+				Right: &ast.IndexExpression{
+					Left: &ast.InfixExpression{
+						Left:     &ast.Identifier{Value: "b"},
+						Operator: "+",
+						Right:    &ast.Identifier{Value: "c"},
+					},
+					Index: &ast.IntegerLiteral{Value: 0},
+				},
+			},
+		}},
+	}}
+	if !ast.Equal(prog, expected, ast.IgnoreTokens()) {
+		t.Errorf("wrong tree. got=%s, want=%s", prog, expected)
+	}
+	if ast.Equal(prog, expected) {
+		t.Errorf("tree without tokens equals parsed tree when comparing tokens")
+	}
+}
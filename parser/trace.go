@@ -0,0 +1,92 @@
+package parser
+
+import (
+	"fmt"
+	"gointer/token"
+	"io"
+	"strings"
+)
+
+// WithTrace writes an indented BEGIN/END line to w for every parse
+// function entered and left, with the current and peek tokens, which
+// makes precedence problems in parseExpression easy to follow.
+func WithTrace(w io.Writer) Option {
+	return func(p *Parser) {
+		p.tracer = w
+	}
+}
+
+func (p *Parser) trace(name string) string {
+	if p.tracer == nil {
+		return name
+	}
+	p.tracePrint(fmt.Sprintf("BEGIN %s cur=%s peek=%s",
+		name, traceToken(p.curToken), traceToken(p.peekToken)))
+	p.traceLevel++
+	return name
+}
+
+// tracePrecedence is trace for functions that take a precedence.
+func (p *Parser) tracePrecedence(name string, precedence int) string {
+	if p.tracer == nil {
+		return name
+	}
+	return p.trace(fmt.Sprintf("%s(%s)", name, precedenceName(precedence)))
+}
+
+func (p *Parser) untrace(name string) {
+	if p.tracer == nil {
+		return
+	}
+	p.traceLevel--
+	p.tracePrint("END " + name)
+}
+
+func (p *Parser) tracePrint(msg string) {
+	fmt.Fprintf(p.tracer, "%s%s\n", strings.Repeat("\t", p.traceLevel), msg)
+}
+
+// traceToken prints operators as "+" and other tokens as IDENT("x").
+func traceToken(tok token.Token) string {
+	if string(tok.Type) == tok.Literal {
+		return fmt.Sprintf("%q", tok.Literal)
+	}
+	return fmt.Sprintf("%s(%q)", tok.Type, tok.Literal)
+}
+
+var precedenceNames = map[int]string{
+	LOWEST:       "LOWEST",
+	ASSIGNMENT:   "ASSIGNMENT",
+	LOGICALOR:    "LOGICALOR",
+	LOGICALAND:   "LOGICALAND",
+	BITOR:        "BITOR",
+	BITXOR:       "BITXOR",
+	BITAND:       "BITAND",
+	EQUALS:       "EQUALS",
+	LESSGRETATER: "LESSGRETATER",
+	SHIFT:        "SHIFT",
+	SUM:          "SUM",
+	PRODUCT:      "PRODUCT",
+	PREFIX:       "PREFIX",
+	POWER:        "POWER",
+	POSTFIX:      "POSTFIX",
+	CALL:         "CALL",
+	INDEX:        "INDEX",
+}
+
+// precedenceName names a precedence level, describing levels that
+// sit between two named ones, such as SUM+5 or POWER-1, relative to
+// the level below or above.
+func precedenceName(precedence int) string {
+	if name, ok := precedenceNames[precedence]; ok {
+		return name
+	}
+	base := precedence - precedence%10
+	if name, ok := precedenceNames[base]; ok {
+		if name, ok := precedenceNames[base+10]; ok && precedence == base+9 {
+			return name + "-1"
+		}
+		return fmt.Sprintf("%s+%d", name, precedence-base)
+	}
+	return fmt.Sprint(precedence)
+}
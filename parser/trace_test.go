@@ -0,0 +1,55 @@
+package parser
+
+import (
+	"gointer/lexer"
+	"strings"
+	"testing"
+)
+
+func TestTrace(t *testing.T) {
+	buf := strings.Builder{}
+	p := New(lexer.New("-a * b"), WithTrace(&buf))
+	p.ParseProgram()
+	checkParseError(t, p)
+
+	expected := `BEGIN parseStatment cur="-" peek=IDENT("a")
+	BEGIN parseExpressionStatment cur="-" peek=IDENT("a")
+		BEGIN parseExpression(LOWEST) cur="-" peek=IDENT("a")
+			BEGIN parsePrefixExpression cur="-" peek=IDENT("a")
+				BEGIN parseExpression(PREFIX) cur=IDENT("a") peek="*"
+					BEGIN parseIdentifier cur=IDENT("a") peek="*"
+					END parseIdentifier
+				END parseExpression(PREFIX)
+			END parsePrefixExpression
+			BEGIN parseInfixExpression cur="*" peek=IDENT("b")
+				BEGIN parseExpression(PRODUCT) cur=IDENT("b") peek=EOF("")
+					BEGIN parseIdentifier cur=IDENT("b") peek=EOF("")
+					END parseIdentifier
+				END parseExpression(PRODUCT)
+			END parseInfixExpression
+		END parseExpression(LOWEST)
+	END parseExpressionStatment
+END parseStatment
+`
+	if buf.String() != expected {
+		t.Fatalf("trace wrong expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
+
+func TestPrecedenceName(t *testing.T) {
+	tests := []struct {
+		precedence int
+		expected   string
+	}{
+		{SUM, "SUM"},
+		{LOWEST + 5, "LOWEST+5"},
+		{POWER - 1, "POWER-1"},
+		{ASSIGNMENT - 1, "ASSIGNMENT-1"},
+		{1000, "1000"},
+	}
+	for _, tt := range tests {
+		if act := precedenceName(tt.precedence); act != tt.expected {
+			t.Errorf("precedenceName(%d) expected=%q got=%q", tt.precedence, tt.expected, act)
+		}
+	}
+}
@@ -10,7 +10,7 @@ import (
 
 const PROMPT = ">>"
 
-func Start(in io.Reader, out io.Writer) {
+func Start(in io.Reader, out io.Writer, opts ...lexer.Option) {
 	scanner := bufio.NewScanner(in)
 	for {
 		_, _ = fmt.Fprint(out, PROMPT)
@@ -19,10 +19,13 @@ func Start(in io.Reader, out io.Writer) {
 			return
 		}
 		line := scanner.Text()
-		l := lexer.New(line)
+		l := lexer.New(line, opts...)
 		for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
 			fmt.Fprintln(out, tok)
 		}
+		for _, msg := range l.Errors() {
+			fmt.Fprintln(out, msg)
+		}
 	}
 
 }
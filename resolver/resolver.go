@@ -0,0 +1,253 @@
+// Package resolver binds every identifier of a program to the
+// declaration it refers to. It runs after parsing and reports names
+// that are used without being declared and names declared twice in
+// the same block.
+package resolver
+
+import (
+	"fmt"
+	"gointer/ast"
+	"gointer/i18n"
+	"slices"
+)
+
+type ObjKind int
+
+const (
+	Builtin ObjKind = iota
+	Var             // declared with let, or the variable of a for-in loop
+	Param           // function parameter
+)
+
+func (k ObjKind) String() string {
+	switch k {
+	case Builtin:
+		return "builtin"
+	case Var:
+		return "var"
+	case Param:
+		return "param"
+	}
+	return fmt.Sprintf("ObjKind(%d)", int(k))
+}
+
+// An Object is a named entity that identifiers can refer to.
+type Object struct {
+	Name string
+	Kind ObjKind
+	// Decl is the identifier that declares the object, nil for builtins.
+	Decl *ast.Identifier
+}
+
+// A Scope holds the objects declared in one block. Scopes form a tree
+// rooted at the universe scope of the builtins.
+type Scope struct {
+	Parent   *Scope
+	Children []*Scope
+	// Node is the Program, BlockStatment, FunctionLiteral, ForStatment
+	// or ForInStatment that opens the scope, nil for the universe.
+	Node ast.Node
+
+	objects map[string]*Object
+}
+
+func newScope(parent *Scope, node ast.Node) *Scope {
+	s := &Scope{Parent: parent, Node: node, objects: map[string]*Object{}}
+	if parent != nil {
+		parent.Children = append(parent.Children, s)
+	}
+	return s
+}
+
+// Lookup returns the object name refers to in s, searching the
+// enclosing scopes outwards, or nil if there is none.
+func (s *Scope) Lookup(name string) *Object {
+	for ; s != nil; s = s.Parent {
+		if obj, ok := s.objects[name]; ok {
+			return obj
+		}
+	}
+	return nil
+}
+
+// Names returns the names declared directly in s, sorted.
+func (s *Scope) Names() []string {
+	names := make([]string, 0, len(s.objects))
+	for name := range s.objects {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Info is the result of resolving a program.
+type Info struct {
+	Universe *Scope
+	// Defs maps declaring identifiers to the object they declare.
+	Defs map[*ast.Identifier]*Object
+	// Uses maps the other identifiers to the object they refer to.
+	// Undefined names are missing.
+	Uses map[*ast.Identifier]*Object
+	// Scopes maps the nodes that open a scope to it.
+	Scopes map[ast.Node]*Scope
+}
+
+// ObjectOf returns the object id declares or refers to, or nil.
+func (info *Info) ObjectOf(id *ast.Identifier) *Object {
+	if obj, ok := info.Defs[id]; ok {
+		return obj
+	}
+	return info.Uses[id]
+}
+
+// DefaultBuiltins are the functions every program can call.
+var DefaultBuiltins = []string{"len", "first", "last", "rest", "puts"}
+
+type resolver struct {
+	info     *Info
+	scope    *Scope
+	builtins []string
+	lang     i18n.Lang
+	errors   []string
+}
+
+type Option func(*resolver)
+
+// WithBuiltins replaces DefaultBuiltins with names.
+func WithBuiltins(names ...string) Option {
+	return func(r *resolver) {
+		r.builtins = names
+	}
+}
+
+// WithLang selects the language errors are reported in.
+func WithLang(lang i18n.Lang) Option {
+	return func(r *resolver) {
+		r.lang = lang
+	}
+}
+
+// Resolve resolves the identifiers of program and returns the result
+// along with the errors found, each prefixed with line:column.
+//
+// A let binds its name after its value is resolved, so that in
+// let x = x + 1 the value refers to the outer x, unless the value is a
+// function literal, which may call itself: let f = fn(n) { f(n - 1) }.
+func Resolve(program *ast.Program, opts ...Option) (*Info, []string) {
+	r := &resolver{
+		info: &Info{
+			Defs:   map[*ast.Identifier]*Object{},
+			Uses:   map[*ast.Identifier]*Object{},
+			Scopes: map[ast.Node]*Scope{},
+		},
+		builtins: DefaultBuiltins,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	r.info.Universe = newScope(nil, nil)
+	for _, name := range r.builtins {
+		r.info.Universe.objects[name] = &Object{Name: name, Kind: Builtin}
+	}
+	r.scope = r.info.Universe
+	r.resolve(program)
+	return r.info, r.errors
+}
+
+func (r *resolver) error(id *ast.Identifier, msg i18n.MessageID, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf("%s: %s", id.Pos(), i18n.Sprintf(r.lang, msg, args...)))
+}
+
+func (r *resolver) openScope(node ast.Node) {
+	r.scope = newScope(r.scope, node)
+	r.info.Scopes[node] = r.scope
+}
+
+func (r *resolver) closeScope() {
+	r.scope = r.scope.Parent
+}
+
+func (r *resolver) declare(id *ast.Identifier, kind ObjKind) {
+	if id == nil {
+		return
+	}
+	obj := &Object{Name: id.Value, Kind: kind, Decl: id}
+	r.info.Defs[id] = obj
+	if _, ok := r.scope.objects[id.Value]; ok {
+		r.error(id, i18n.Redeclared, id.Value)
+		return
+	}
+	r.scope.objects[id.Value] = obj
+}
+
+func (r *resolver) resolve(node ast.Node) {
+	switch n := node.(type) {
+	case nil:
+	case *ast.Program:
+		r.openScope(n)
+		r.stmts(n.Statments)
+		r.closeScope()
+	case *ast.BlockStatment:
+		if n == nil {
+			return
+		}
+		r.openScope(n)
+		r.stmts(n.Statments)
+		r.closeScope()
+	case *ast.LetStatment:
+		// the variable is in scope in its own value only if that is a
+		// function, which may call itself; otherwise let x = x + 1
+		// refers to the outer x
+		if _, ok := n.Value.(*ast.FunctionLiteral); ok {
+			r.declare(n.Name, Var)
+			r.resolve(n.Value)
+			break
+		}
+		r.resolve(n.Value)
+		r.declare(n.Name, Var)
+	case *ast.Identifier:
+		if obj := r.scope.Lookup(n.Value); obj != nil {
+			r.info.Uses[n] = obj
+		} else {
+			r.error(n, i18n.UndefinedName, n.Value)
+		}
+	case *ast.FunctionLiteral:
+		r.openScope(n)
+		for _, p := range n.Parameters {
+			r.declare(p, Param)
+		}
+		// the body shares the scope of the parameters
+		if n.Body != nil {
+			r.stmts(n.Body.Statments)
+		}
+		r.closeScope()
+	case *ast.WhileStatment:
+		r.resolve(n.Condition)
+		r.resolve(n.Body)
+	case *ast.ForStatment:
+		r.openScope(n)
+		r.resolve(n.Init)
+		r.resolve(n.Condition)
+		r.resolve(n.Post)
+		r.resolve(n.Body)
+		r.closeScope()
+	case *ast.ForInStatment:
+		r.resolve(n.Iterable)
+		r.openScope(n)
+		r.declare(n.Variable, Var)
+		r.resolve(n.Body)
+		r.closeScope()
+	case *ast.BreakStatment, *ast.ContinueStatment:
+		// labels are resolved by the parser
+	default:
+		for _, child := range ast.Children(n) {
+			r.resolve(child)
+		}
+	}
+}
+
+func (r *resolver) stmts(list []ast.Statment) {
+	for _, s := range list {
+		r.resolve(s)
+	}
+}
@@ -0,0 +1,150 @@
+package resolver
+
+import (
+	"gointer/ast"
+	"gointer/i18n"
+	"gointer/lexer"
+	"gointer/parser"
+	"reflect"
+	"testing"
+)
+
+func parse(t *testing.T, input string) *ast.Program {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if errs := p.Errors(); len(errs) != 0 {
+		t.Fatalf("parse errors in %q: %v", input, errs)
+	}
+	return program
+}
+
+func TestResolveErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let x = 1; x + 1", nil},
+		{"let f = fn(n) { f(n - 1) }", nil},
+		{"let add = fn(a, b) { a + b }; add(1, 2); len(puts)", nil},
+		{"y", []string{"1:1: undefined: y"}},
+		{"let x = 1;\nlet x = 2;", []string{"2:5: x redeclared in this block"}},
+		{"let x = 1; if (x) { let x = 2; x }", nil},
+		{"if (true) { let x = 2; }; x", []string{"1:27: undefined: x"}},
+		{"fn(a, a) { a }", []string{"1:7: a redeclared in this block"}},
+		{"fn(a) { let a = 1; }", []string{"1:13: a redeclared in this block"}},
+		{"fn(a) { if (a) { let a = 1; } }", nil},
+		{"fn(a) { b }; a", []string{"1:9: undefined: b", "1:14: undefined: a"}},
+		{"for (let i = 0; i < 3; i++) { i }; i", []string{"1:36: undefined: i"}},
+		{"for (x in xs) { x }", []string{"1:11: undefined: xs"}},
+		{"let xs = 1; for (x in xs) { let x = 2; }", nil},
+		{"let xs = 1; for (xs in xs) { xs }", nil},
+		{"outer: while (true) { break outer; }", nil},
+		{"x = 1", []string{"1:1: undefined: x"}},
+		{"let x = x;", []string{"1:9: undefined: x"}},
+		{"fn() { let y = y + 1; y }", []string{"1:16: undefined: y"}},
+		{"let x = 5; if (true) { let x = x + 1; x }", nil},
+	}
+	for _, tt := range tests {
+		_, errs := Resolve(parse(t, tt.input))
+		if !reflect.DeepEqual(errs, tt.expected) {
+			t.Errorf("Resolve(%q) errors = %q, want %q", tt.input, errs, tt.expected)
+		}
+	}
+}
+
+func TestResolveBuiltins(t *testing.T) {
+	program := parse(t, "print(len(x))")
+	_, errs := Resolve(program, WithBuiltins("print", "x"))
+	expected := []string{"1:7: undefined: len"}
+	if !reflect.DeepEqual(errs, expected) {
+		t.Errorf("errors = %q, want %q", errs, expected)
+	}
+}
+
+func TestResolveLetValue(t *testing.T) {
+	// the inner x + 1 refers to the outer x
+	program := parse(t, "let x = 5; if (true) { let x = x + 1; x }")
+	info, errs := Resolve(program)
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	at := func(offset int) *Object {
+		path := ast.PathEnclosing(program, offset)
+		return info.ObjectOf(path[len(path)-1].(*ast.Identifier))
+	}
+	outer, inner := at(4), at(27)
+	if outer == inner {
+		t.Fatalf("the inner let did not declare a new x")
+	}
+	if got := at(31); got != outer {
+		t.Errorf("x in the value of the inner let refers to %v, want the outer x", got.Decl.Pos())
+	}
+	if got := at(38); got != inner {
+		t.Errorf("x after the inner let refers to %v, want the inner x", got.Decl.Pos())
+	}
+}
+
+func TestResolveLang(t *testing.T) {
+	_, errs := Resolve(parse(t, "y"), WithLang(i18n.Chinese))
+	expected := []string{"1:1: 未定义: y"}
+	if !reflect.DeepEqual(errs, expected) {
+		t.Errorf("errors = %q, want %q", errs, expected)
+	}
+}
+
+func TestResolveInfo(t *testing.T) {
+	input := "let x = 1;\nlet f = fn(x) { x + len(y) };\nx"
+	program := parse(t, input)
+	info, errs := Resolve(program, WithBuiltins("len", "y"))
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+
+	// at returns the identifier starting at the byte offset
+	at := func(offset int) *ast.Identifier {
+		path := ast.PathEnclosing(program, offset)
+		id, ok := path[len(path)-1].(*ast.Identifier)
+		if !ok {
+			t.Fatalf("no identifier at %d, got %T", offset, path[len(path)-1])
+		}
+		return id
+	}
+	globalX := at(4)
+	paramX := at(22)
+
+	tests := []struct {
+		offset int
+		kind   ObjKind
+		decl   *ast.Identifier
+	}{
+		{4, Var, globalX},
+		{22, Param, paramX},
+		{27, Param, paramX},
+		{31, Builtin, nil},
+		{41, Var, globalX},
+	}
+	for _, tt := range tests {
+		obj := info.ObjectOf(at(tt.offset))
+		if obj == nil {
+			t.Errorf("no object for identifier at %d", tt.offset)
+			continue
+		}
+		if obj.Kind != tt.kind || obj.Decl != tt.decl {
+			t.Errorf("object at %d is %s declared at %v, want %s declared at %v",
+				tt.offset, obj.Kind, obj.Decl, tt.kind, tt.decl)
+		}
+	}
+
+	global := info.Scopes[program]
+	if global.Parent != info.Universe {
+		t.Errorf("program scope is not a child of the universe")
+	}
+	if got := global.Names(); !reflect.DeepEqual(got, []string{"f", "x"}) {
+		t.Errorf("program scope declares %v", got)
+	}
+	fn := program.Statments[1].(*ast.LetStatment).Value
+	if got := info.Scopes[fn].Names(); !reflect.DeepEqual(got, []string{"x"}) {
+		t.Errorf("function scope declares %v", got)
+	}
+}
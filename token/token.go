@@ -1,58 +1,181 @@
 package token
 
+import (
+	"fmt"
+	"sort"
+)
+
 type TokenType string
 
 type Token struct {
-	Type    TokenType
-	Literal string
+	Type    TokenType `json:"type"`
+	Literal string    `json:"literal"`
+	Pos     Position  `json:"pos"`
+	// End is the position just past the last character of the token.
+	End Position `json:"end"`
+}
+
+// Position is a location in the source. Offset counts bytes from the
+// start of the input, Line and Column count from 1 and Column counts
+// characters rather than bytes.
+type Position struct {
+	Offset int `json:"offset"`
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+func (p Position) IsValid() bool { return p.Line > 0 }
+
+func (p Position) String() string {
+	if !p.IsValid() {
+		return "-"
+	}
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
 }
 
 const (
-	ILLEGAL = "ILLEGAL"
-	EOF     = "EOF"
-	IDENT   = "IDENT"
-	INT     = "INT"
-	TRUE    = "true"
-	FALSE   = "false"
-	RETURN  = "return"
-	IF      = "if"
-	ELSE    = "else"
-
-	ASSIGN    = "="
+	ILLEGAL  = "ILLEGAL"
+	EOF      = "EOF"
+	IDENT    = "IDENT"
+	INT      = "INT"
+	STRING   = "STRING"
+	COMMENT  = "COMMENT"
+	TRUE     = "true"
+	FALSE    = "false"
+	RETURN   = "return"
+	IF       = "if"
+	ELSE     = "else"
+	WHILE    = "while"
+	FOR      = "for"
+	IN       = "in"
+	BREAK    = "break"
+	CONTINUE = "continue"
+
+	ASSIGN          = "="
+	PLUS_ASSIGN     = "+="
+	MINUS_ASSIGN    = "-="
+	ASTERISK_ASSIGN = "*="
+	SLASH_ASSIGN    = "/="
+	PERCENT_ASSIGN  = "%="
+
 	PLUS      = "+"
 	MINUS     = "-"
 	BANG      = "!"
+	INCR      = "++"
+	DECR      = "--"
+	QUESTION  = "?"
 	ASTERISK  = "*"
 	SLASH     = "/"
+	PERCENT   = "%"
+	POWER     = "**"
 	COMMA     = ","
 	SEMICOLON = ";"
+	COLON     = ":"
+	ARROW     = "->"
 	LT        = "<"
 	GT        = ">"
+	LT_EQ     = "<="
+	GT_EQ     = ">="
 	EQ        = "=="
 	NOT_EQ    = "!="
 
-	LPAREN = "("
-	RPAREN = ")"
-	LBRACE = "{"
-	RBRACE = "}"
+	AND = "&&"
+	OR  = "||"
+
+	BIT_AND = "&"
+	BIT_OR  = "|"
+	BIT_XOR = "^"
+	BIT_NOT = "~"
+	SHL     = "<<"
+	SHR     = ">>"
+
+	LPAREN   = "("
+	RPAREN   = ")"
+	LBRACE   = "{"
+	RBRACE   = "}"
+	LBRACKET = "["
+	RBRACKET = "]"
 
 	FUNCTION = "FUNCTION"
 	LET      = "LET"
 )
 
-var keyworkds = map[string]TokenType{
-	"fn":     FUNCTION,
-	"let":    LET,
-	"if":     IF,
-	"else":   ELSE,
-	"true":   TRUE,
-	"false":  FALSE,
-	"return": RETURN,
-}
+// Keywords maps identifier spellings to keyword token types. The
+// lexer consults one of these to tell keywords from identifiers, so a
+// dialect only has to change the spelling, never the token types.
+type Keywords map[string]TokenType
 
-func LookupIdent(ident string) TokenType {
-	if tok, ok := keyworkds[ident]; ok {
+// Lookup returns the keyword type of ident, or IDENT if ident is not
+// a keyword in k.
+func (k Keywords) Lookup(ident string) TokenType {
+	if tok, ok := k[ident]; ok {
 		return tok
 	}
 	return IDENT
 }
+
+// Spelling returns the identifier spelling of keyword type t in k.
+func (k Keywords) Spelling(t TokenType) (string, bool) {
+	for ident, tok := range k {
+		if tok == t {
+			return ident, true
+		}
+	}
+	return "", false
+}
+
+var keyworkds = Keywords{
+	"fn":       FUNCTION,
+	"let":      LET,
+	"if":       IF,
+	"else":     ELSE,
+	"true":     TRUE,
+	"false":    FALSE,
+	"return":   RETURN,
+	"while":    WHILE,
+	"for":      FOR,
+	"in":       IN,
+	"break":    BREAK,
+	"continue": CONTINUE,
+}
+
+// chineseKeywords is used for teaching alongside the Chinese book.
+var chineseKeywords = Keywords{
+	"函数": FUNCTION,
+	"令":  LET,
+	"如果": IF,
+	"否则": ELSE,
+	"真":  TRUE,
+	"假":  FALSE,
+	"返回": RETURN,
+	"当":  WHILE,
+	"对于": FOR,
+	"在":  IN,
+	"跳出": BREAK,
+	"继续": CONTINUE,
+}
+
+var dialects = map[string]Keywords{
+	"en": keyworkds,
+	"zh": chineseKeywords,
+}
+
+// Dialect returns the keyword table registered under name.
+func Dialect(name string) (Keywords, bool) {
+	kw, ok := dialects[name]
+	return kw, ok
+}
+
+// DialectNames returns the names accepted by Dialect in sorted order.
+func DialectNames() []string {
+	names := make([]string, 0, len(dialects))
+	for name := range dialects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func LookupIdent(ident string) TokenType {
+	return keyworkds.Lookup(ident)
+}
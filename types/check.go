@@ -0,0 +1,387 @@
+package types
+
+import (
+	"bytes"
+	"fmt"
+	"gointer/ast"
+	"gointer/format"
+	"gointer/i18n"
+	"gointer/resolver"
+	"slices"
+)
+
+// Info is the result of checking a program.
+type Info struct {
+	// Types maps the expressions of the program to their type.
+	Types map[ast.Expression]Type
+	// Objects maps the variables and parameters to their type.
+	Objects map[*resolver.Object]Type
+}
+
+// TypeOf returns the type of e, Any if it was not checked.
+func (info *Info) TypeOf(e ast.Expression) Type {
+	if t, ok := info.Types[e]; ok {
+		return t
+	}
+	return Any
+}
+
+type checker struct {
+	info     *Info
+	resolved *resolver.Info
+	lang     i18n.Lang
+	errors   []string
+	// assigned holds the variables that are the target of an
+	// assignment, whose type is any unless annotated.
+	assigned map[*resolver.Object]bool
+	// result is the result type of the enclosing function, nil at the
+	// top level.
+	result Type
+}
+
+type Option func(*checker)
+
+// WithLang selects the language errors are reported in.
+func WithLang(lang i18n.Lang) Option {
+	return func(c *checker) {
+		c.lang = lang
+	}
+}
+
+// Check checks the types of program, which resolved must be the
+// resolution of, and returns the result along with the errors found,
+// each prefixed with line:column.
+//
+// An unannotated let gives its variable the type of the value, unless
+// the variable is assigned to later on, in which case it has type any.
+func Check(program *ast.Program, resolved *resolver.Info, opts ...Option) (*Info, []string) {
+	c := newChecker(program, resolved, opts)
+	c.stmts(program.Statments)
+	return c.info, c.errors
+}
+
+func newChecker(program *ast.Program, resolved *resolver.Info, opts []Option) *checker {
+	c := &checker{
+		info: &Info{
+			Types:   map[ast.Expression]Type{},
+			Objects: map[*resolver.Object]Type{},
+		},
+		resolved: resolved,
+		assigned: map[*resolver.Object]bool{},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	ast.Inspect(program, func(n ast.Node) bool {
+		if a, ok := n.(*ast.AssignExpression); ok {
+			if id, ok := a.Target.(*ast.Identifier); ok {
+				if obj := resolved.ObjectOf(id); obj != nil {
+					c.assigned[obj] = true
+				}
+			}
+		}
+		return true
+	})
+	return c
+}
+
+func (c *checker) error(n ast.Node, msg i18n.MessageID, args ...any) {
+	c.errors = append(c.errors, fmt.Sprintf("%s: %s", n.Pos(), i18n.Sprintf(c.lang, msg, args...)))
+}
+
+// source returns the canonical source of e for messages.
+func source(e ast.Expression) string {
+	buf := bytes.Buffer{}
+	format.Fprint(&buf, e)
+	return string(bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+}
+
+// typeOf returns the type an annotation stands for, Any for none.
+func (c *checker) typeOf(t ast.TypeExpr) Type {
+	switch t := t.(type) {
+	case nil:
+		return Any
+	case *ast.NamedType:
+		if typ, ok := Universe[t.Name]; ok {
+			return typ
+		}
+		c.error(t, i18n.TypeUnknown, t.Name)
+		return Any
+	case *ast.FunctionType:
+		f := &Func{Params: make([]Type, len(t.Params)), Result: c.typeOf(t.Result)}
+		for i, p := range t.Params {
+			f.Params[i] = c.typeOf(p)
+		}
+		return f
+	}
+	panic(fmt.Sprintf("types: unexpected node type %T", t))
+}
+
+// signature returns the type of fn as given by its annotations.
+func (c *checker) signature(fn *ast.FunctionLiteral) *Func {
+	f := &Func{Params: make([]Type, len(fn.Parameters)), Result: c.typeOf(fn.ReturnType)}
+	for i := range fn.Parameters {
+		f.Params[i] = c.typeOf(fn.ParamType(i))
+	}
+	return f
+}
+
+func (c *checker) declare(id *ast.Identifier, t Type) {
+	c.info.Types[id] = t
+	if obj := c.resolved.Defs[id]; obj != nil {
+		c.info.Objects[obj] = t
+	}
+}
+
+// assignable reports an error with msg unless e of type t can be used
+// as a value of type want.
+func (c *checker) assignable(e ast.Expression, t, want Type, msg i18n.MessageID, args ...any) {
+	if !Compatible(t, want) {
+		c.error(e, msg, append([]any{source(e), t, want}, args...)...)
+	}
+}
+
+func (c *checker) stmts(list []ast.Statment) {
+	for _, s := range list {
+		c.stmt(s)
+	}
+}
+
+func (c *checker) stmt(s ast.Statment) {
+	switch s := s.(type) {
+	case nil:
+	case *ast.LetStatment:
+		c.let(s)
+	case *ast.ReturnStatment:
+		if s.ReturnValue == nil {
+			return
+		}
+		t := c.expr(s.ReturnValue)
+		if c.result != nil {
+			c.assignable(s.ReturnValue, t, c.result, i18n.TypeCannotReturn)
+		}
+	case *ast.ExpressionStatment:
+		c.expr(s.Expression)
+	case *ast.BlockStatment:
+		if s != nil {
+			c.stmts(s.Statments)
+		}
+	case *ast.WhileStatment:
+		c.expr(s.Condition)
+		c.stmt(s.Body)
+	case *ast.ForStatment:
+		c.stmt(s.Init)
+		c.expr(s.Condition)
+		c.expr(s.Post)
+		c.stmt(s.Body)
+	case *ast.ForInStatment:
+		c.expr(s.Iterable)
+		c.declare(s.Variable, Any)
+		c.stmt(s.Body)
+	case *ast.BreakStatment, *ast.ContinueStatment:
+	default:
+		panic(fmt.Sprintf("types: unexpected node type %T", s))
+	}
+}
+
+func (c *checker) let(s *ast.LetStatment) {
+	declared := c.typeOf(s.Type)
+	obj := c.resolved.Defs[s.Name]
+	if fn, ok := s.Value.(*ast.FunctionLiteral); ok && s.Type == nil && !c.assigned[obj] {
+		// known before the body is checked, so that it can recurse
+		declared = c.signature(fn)
+	}
+	c.declare(s.Name, declared)
+	t := c.expr(s.Value)
+	if s.Type != nil {
+		c.assignable(s.Value, t, declared, i18n.TypeCannotDeclare)
+	} else if !c.assigned[obj] {
+		c.declare(s.Name, t)
+	}
+}
+
+// block checks b and returns its type, the type of its last statement
+// if that is an expression, Any otherwise.
+func (c *checker) block(b *ast.BlockStatment) Type {
+	if b == nil || len(b.Statments) == 0 {
+		return Any
+	}
+	c.stmts(b.Statments)
+	if s, ok := b.Statments[len(b.Statments)-1].(*ast.ExpressionStatment); ok {
+		return c.info.TypeOf(s.Expression)
+	}
+	return Any
+}
+
+func (c *checker) expr(e ast.Expression) Type {
+	if e == nil {
+		return Any
+	}
+	t := c.exprType(e)
+	c.info.Types[e] = t
+	return t
+}
+
+func (c *checker) exprType(e ast.Expression) Type {
+	switch e := e.(type) {
+	case *ast.IntegerLiteral:
+		return Int
+	case *ast.StringLiteral:
+		return String
+	case *ast.Boolean:
+		return Bool
+	case *ast.Identifier:
+		if t, ok := c.info.Objects[c.resolved.Uses[e]]; ok {
+			return t
+		}
+		return Any
+	case *ast.PrefixExpression:
+		t := c.expr(e.Right)
+		switch e.Operator {
+		case "!":
+			return Bool
+		case "-", "~":
+			if t != Any && t != Int {
+				c.error(e, i18n.TypeInvalidOp, e.Operator, source(e.Right), t)
+			}
+			return Int
+		}
+		return Any
+	case *ast.InfixExpression:
+		return c.binary(e, e.Operator, c.expr(e.Left), e.Right, c.expr(e.Right))
+	case *ast.PostfixExpression:
+		t := c.expr(e.Left)
+		switch e.Operator {
+		case "++", "--":
+			if t != Any && t != Int {
+				c.error(e, i18n.TypeInvalidOp, e.Operator, source(e.Left), t)
+			}
+			return Int
+		}
+		return Any
+	case *ast.AssignExpression:
+		target := c.expr(e.Target)
+		t := c.expr(e.Value)
+		if op := e.Operator[:len(e.Operator)-1]; op != "" {
+			t = c.binary(e, op, target, e.Value, t)
+		}
+		c.assignable(e.Value, t, target, i18n.TypeCannotAssign)
+		return target
+	case *ast.IndexExpression:
+		c.expr(e.Left)
+		c.expr(e.Index)
+		return Any
+	case *ast.CallExpression:
+		return c.call(e)
+	case *ast.IfExpression:
+		c.expr(e.Condition)
+		cons := c.block(e.Consequence)
+		if e.Alternative == nil {
+			return Any
+		}
+		if alt := c.block(e.Alternative); alt == cons {
+			return cons
+		}
+		return Any
+	case *ast.FunctionLiteral:
+		f := c.signature(e)
+		for i, p := range e.Parameters {
+			c.declare(p, f.Params[i])
+		}
+		result := c.result
+		c.result = f.Result
+		t := c.block(e.Body)
+		c.result = result
+		if e.ReturnType != nil && len(e.Body.Statments) != 0 {
+			if s, ok := e.Body.Statments[len(e.Body.Statments)-1].(*ast.ExpressionStatment); ok {
+				c.assignable(s.Expression, t, f.Result, i18n.TypeCannotReturn)
+			}
+		}
+		return f
+	}
+	panic(fmt.Sprintf("types: unexpected node type %T", e))
+}
+
+// operands lists the types the arithmetic, bitwise and ordering
+// operators are defined on.
+var operands = map[string][]Type{
+	"+": {Int, String}, "-": {Int}, "*": {Int}, "/": {Int}, "%": {Int}, "**": {Int},
+	"&": {Int}, "|": {Int}, "^": {Int}, "<<": {Int}, ">>": {Int},
+	"<": {Int, String}, ">": {Int, String}, "<=": {Int, String}, ">=": {Int, String},
+}
+
+// binary returns the type of applying op to operands of type l and r,
+// reporting errors at e. right is the right operand.
+func (c *checker) binary(e ast.Expression, op string, l Type, right ast.Expression, r Type) Type {
+	switch op {
+	case "==", "!=":
+		// values of any two types can be compared, those of different
+		// types are never equal
+		return Bool
+	case "&&", "||":
+		if l == Bool && r == Bool {
+			return Bool
+		}
+		return Any
+	}
+	defined, ok := operands[op]
+	if !ok {
+		return Any
+	}
+	if l != Any && r != Any && l != r {
+		c.error(e, i18n.TypeMismatch, source(e), l, r)
+		return Any
+	}
+	t := l
+	if t == Any {
+		t = r
+	}
+	if t != Any && !slices.Contains(defined, t) {
+		operand := right
+		if l != Any {
+			operand = leftOf(e)
+		}
+		c.error(e, i18n.TypeInvalidOp, op, source(operand), t)
+		return Any
+	}
+	switch op {
+	case "<", ">", "<=", ">=":
+		return Bool
+	}
+	return t
+}
+
+// leftOf returns the left operand of an infix or assign expression.
+func leftOf(e ast.Expression) ast.Expression {
+	switch e := e.(type) {
+	case *ast.InfixExpression:
+		return e.Left
+	case *ast.AssignExpression:
+		return e.Target
+	}
+	return e
+}
+
+func (c *checker) call(e *ast.CallExpression) Type {
+	t := c.expr(e.Function)
+	args := make([]Type, len(e.Arguments))
+	for i, a := range e.Arguments {
+		args[i] = c.expr(a)
+	}
+	switch f := t.(type) {
+	case *Func:
+		if len(args) != len(f.Params) {
+			c.error(e, i18n.TypeArgCount, source(e.Function), len(args), len(f.Params))
+			return f.Result
+		}
+		for i, a := range e.Arguments {
+			c.assignable(a, args[i], f.Params[i], i18n.TypeCannotPass, source(e.Function))
+		}
+		return f.Result
+	case Basic:
+		if f != Any {
+			c.error(e, i18n.TypeNotFunc, source(e.Function), f)
+		}
+	}
+	return Any
+}
@@ -0,0 +1,129 @@
+package types
+
+import (
+	"gointer/ast"
+	"gointer/i18n"
+	"gointer/lexer"
+	"gointer/parser"
+	"gointer/resolver"
+	"reflect"
+	"testing"
+)
+
+func check(t *testing.T, input string, opts ...Option) (*ast.Program, *Info, []string) {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if errs := p.Errors(); len(errs) != 0 {
+		t.Fatalf("parse errors in %q: %v", input, errs)
+	}
+	resolved, errs := resolver.Resolve(program, resolver.WithBuiltins("len", "puts", "xs"))
+	if len(errs) != 0 {
+		t.Fatalf("resolve errors in %q: %v", input, errs)
+	}
+	info, errs := Check(program, resolved, opts...)
+	return program, info, errs
+}
+
+func TestCheckErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let x = 1; let y = x + 2 * 3; y < 4", nil},
+		{"true + 1", []string{"1:1: invalid operation: true + 1 (mismatched types bool and int)"}},
+		{`"a" + "b"; "a" < "b"`, nil},
+		{"true * false", []string{"1:1: invalid operation: operator * not defined on true (type bool)"}},
+		{`let s = "a"; s - 1`, []string{"1:14: invalid operation: s - 1 (mismatched types string and int)"}},
+		{"-true", []string{"1:1: invalid operation: operator - not defined on true (type bool)"}},
+		{"1 == true", nil},
+		{`let x = 5; x == "a"; x != "a"`, nil},
+		{"let x: int = 5; let y: bool = x == true; y", nil},
+		{"let b = 1 == true; b + 1", []string{"1:20: invalid operation: b + 1 (mismatched types bool and int)"}},
+		{"let b = 1 < 2; b + 1", []string{"1:16: invalid operation: b + 1 (mismatched types bool and int)"}},
+		{"let f = fn(a) { a }; f(true) + f(1)", nil},
+		{"len(xs) + puts(1)", nil},
+		{"let x: int = true;", []string{"1:14: cannot use true (type bool) as int value in variable declaration"}},
+		{"let x: any = true; x + 1", nil},
+		{"let x = 1; x = true", nil},
+		{"let x: int = 1; x = true", []string{"1:21: cannot use true (type bool) as int value in assignment"}},
+		{"let x: int = 1; x += true", []string{"1:17: invalid operation: x += true (mismatched types int and bool)"}},
+		{"let x: bool = true; x++", []string{"1:21: invalid operation: operator ++ not defined on x (type bool)"}},
+		{"let add = fn(a: int, b: int) -> int { a + b }; add(1, true)",
+			[]string{"1:55: cannot use true (type bool) as int value in argument to add"}},
+		{"let add = fn(a: int, b: int) -> int { a + b }; add(1)",
+			[]string{"1:48: wrong number of arguments in call to add: have 1, want 2"}},
+		{"let add = fn(a: int, b: int) -> int { a + b }; add(1, 2) + true",
+			[]string{"1:48: invalid operation: add(1, 2) + true (mismatched types int and bool)"}},
+		{"fn(a: int) -> bool { a }", []string{"1:22: cannot use a (type int) as bool value in return"}},
+		{"fn(a: int) -> bool { return a; }", []string{"1:29: cannot use a (type int) as bool value in return"}},
+		{"fn(a: int) -> bool { if (a < 0) { return true; } false }", nil},
+		{"let f = fn(n: int) -> int { if (n < 2) { 1 } else { n * f(n - 1) } }; f(true)",
+			[]string{"1:73: cannot use true (type bool) as int value in argument to f"}},
+		{"let x = 5; x(1)", []string{"1:12: invalid operation: cannot call non-function x (type int)"}},
+		{"let x: float = 5;", []string{"1:8: undefined type float"}},
+		{"let g: fn(int) -> int = fn(a: bool) -> int { 1 };",
+			[]string{"1:25: cannot use fn(a: bool) -> int {\n\t1;\n} (type fn(bool) -> int) as fn(int) -> int value in variable declaration"}},
+		{"let apply = fn(f: fn(int) -> int, x: int) -> int { f(x) }; apply(fn(x) { x }, 1)", nil},
+		{"let y = if (true) { 1 } else { 2 }; y + false",
+			[]string{"1:37: invalid operation: y + false (mismatched types int and bool)"}},
+		{`let y = if (true) { 1 } else { "a" }; y + 1`, nil},
+		{"for (let i = 0; i < 10; i++) { i + true }", []string{"1:32: invalid operation: i + true (mismatched types int and bool)"}},
+		{"for (x in xs) { x + 1 }", nil},
+	}
+	for _, tt := range tests {
+		_, _, errs := check(t, tt.input)
+		if !reflect.DeepEqual(errs, tt.expected) {
+			t.Errorf("Check(%q) errors = %q, want %q", tt.input, errs, tt.expected)
+		}
+	}
+}
+
+func TestCheckTypes(t *testing.T) {
+	program, info, errs := check(t, `let f = fn(a: int, b) -> int { a }; let s = "x"; f(1, s) < 2`)
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	stmt := program.Statments[2].(*ast.ExpressionStatment)
+	if got := info.TypeOf(stmt.Expression); got != Bool {
+		t.Errorf("comparison has type %s, want bool", got)
+	}
+	let := program.Statments[0].(*ast.LetStatment)
+	if got, want := info.TypeOf(let.Value).String(), "fn(int, any) -> int"; got != want {
+		t.Errorf("function has type %s, want %s", got, want)
+	}
+	let = program.Statments[1].(*ast.LetStatment)
+	if got := info.TypeOf(let.Name); got != String {
+		t.Errorf("s has type %s, want string", got)
+	}
+}
+
+func TestCompatible(t *testing.T) {
+	intFn := &Func{Params: []Type{Int}, Result: Int}
+	tests := []struct {
+		a, b     Type
+		expected bool
+	}{
+		{Int, Int, true},
+		{Int, Bool, false},
+		{Any, Bool, true},
+		{String, Any, true},
+		{intFn, &Func{Params: []Type{Any}, Result: Int}, true},
+		{intFn, &Func{Params: []Type{Bool}, Result: Int}, false},
+		{intFn, &Func{Params: []Type{Int, Int}, Result: Int}, false},
+		{intFn, Int, false},
+	}
+	for _, tt := range tests {
+		if got := Compatible(tt.a, tt.b); got != tt.expected {
+			t.Errorf("Compatible(%s, %s) = %t, want %t", tt.a, tt.b, got, tt.expected)
+		}
+	}
+}
+
+func TestCheckLang(t *testing.T) {
+	_, _, errs := check(t, "true + 1", WithLang(i18n.Chinese))
+	expected := []string{"1:1: 无效运算: true + 1 (类型 bool 和 int 不匹配)"}
+	if !reflect.DeepEqual(errs, expected) {
+		t.Errorf("errors = %q, want %q", errs, expected)
+	}
+}
@@ -0,0 +1,502 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"gointer/ast"
+	"gointer/i18n"
+	"gointer/resolver"
+)
+
+// A scheme is the type of a let bound function, generic in vars.
+type scheme struct {
+	vars []*Var
+	t    Type
+}
+
+type inferrer struct {
+	*checker
+	// level is the number of lets being inferred. Variables created
+	// deeper than the current level are generalized when a let ends.
+	level   int
+	nextVar int
+	schemes map[*resolver.Object]*scheme
+}
+
+// Infer infers the types of program, which resolved must be the
+// resolution of, with Hindley-Milner type inference, and returns the
+// result along with the errors found, each prefixed with line:column.
+//
+// Unlike Check, Infer gives unannotated parameters the type their uses
+// require, so fn(a, b) { a - b } has type fn(int, int) -> int. A let
+// bound function literal is generic in the types it does not
+// constrain: after let id = fn(x) { x }, id(1) and id(true) are both
+// valid. Variables that are assigned to are never generic. Builtins,
+// index expressions, for-in variables and the value of an if without
+// else have type Any, which unifies with every type.
+func Infer(program *ast.Program, resolved *resolver.Info, opts ...Option) (*Info, []string) {
+	c := newChecker(program, resolved, opts)
+	in := &inferrer{checker: c, schemes: map[*resolver.Object]*scheme{}}
+	in.stmts(program.Statments)
+	for e, t := range in.info.Types {
+		in.info.Types[e] = resolve(t)
+	}
+	for obj, t := range in.info.Objects {
+		in.info.Objects[obj] = resolve(t)
+	}
+	return in.info, in.errors
+}
+
+var (
+	errMismatch  = errors.New("mismatched types")
+	errRecursive = errors.New("recursive type")
+)
+
+func (in *inferrer) fresh() *Var {
+	in.nextVar++
+	return &Var{id: in.nextVar, level: in.level}
+}
+
+// resolve returns t with its bound variables replaced by their types.
+func resolve(t Type) Type {
+	switch t := prune(t).(type) {
+	case *Func:
+		f := &Func{Params: make([]Type, len(t.Params)), Result: resolve(t.Result)}
+		for i, p := range t.Params {
+			f.Params[i] = resolve(p)
+		}
+		return f
+	default:
+		return t
+	}
+}
+
+// unify makes a and b the same type by binding type variables.
+func (in *inferrer) unify(a, b Type) error {
+	a, b = prune(a), prune(b)
+	if a == b {
+		return nil
+	}
+	if v, ok := a.(*Var); ok {
+		return in.bind(v, b)
+	}
+	if v, ok := b.(*Var); ok {
+		return in.bind(v, a)
+	}
+	if a == Any || b == Any {
+		return nil
+	}
+	fa, ok := a.(*Func)
+	if !ok {
+		return errMismatch
+	}
+	fb, ok := b.(*Func)
+	if !ok || len(fa.Params) != len(fb.Params) {
+		return errMismatch
+	}
+	for i := range fa.Params {
+		if err := in.unify(fa.Params[i], fb.Params[i]); err != nil {
+			return err
+		}
+	}
+	return in.unify(fa.Result, fb.Result)
+}
+
+// bind binds the unbound variable v to t, which is not v itself. A
+// variable bound to Any makes the values of its type unchecked, as
+// they would be without inference.
+func (in *inferrer) bind(v *Var, t Type) error {
+	if w, ok := t.(*Var); ok {
+		w.ordered = w.ordered || v.ordered
+		w.level = min(w.level, v.level)
+		v.bound = w
+		return nil
+	}
+	if v.ordered && t != Int && t != String && t != Any {
+		return errMismatch
+	}
+	if occurs(v, t) {
+		return errRecursive
+	}
+	var lower func(t Type)
+	lower = func(t Type) {
+		switch t := prune(t).(type) {
+		case *Var:
+			t.level = min(t.level, v.level)
+		case *Func:
+			for _, p := range t.Params {
+				lower(p)
+			}
+			lower(t.Result)
+		}
+	}
+	lower(t)
+	v.bound = t
+	return nil
+}
+
+// occurs reports whether v appears in t.
+func occurs(v *Var, t Type) bool {
+	switch t := prune(t).(type) {
+	case *Var:
+		return t == v
+	case *Func:
+		for _, p := range t.Params {
+			if occurs(v, p) {
+				return true
+			}
+		}
+		return occurs(v, t.Result)
+	}
+	return false
+}
+
+// ordered restricts t to int and string.
+func (in *inferrer) ordered(t Type) error {
+	switch t := prune(t).(type) {
+	case *Var:
+		t.ordered = true
+		return nil
+	case Basic:
+		if t == Any || t == Int || t == String {
+			return nil
+		}
+	}
+	return errMismatch
+}
+
+// generalize returns the scheme of t, generic in the variables created
+// by the let that just ended.
+func (in *inferrer) generalize(t Type) *scheme {
+	s := &scheme{t: t}
+	var collect func(t Type)
+	collect = func(t Type) {
+		switch t := prune(t).(type) {
+		case *Var:
+			if t.level > in.level {
+				s.vars = append(s.vars, t)
+			}
+		case *Func:
+			for _, p := range t.Params {
+				collect(p)
+			}
+			collect(t.Result)
+		}
+	}
+	collect(t)
+	return s
+}
+
+// instantiate returns the type of s with fresh variables for its
+// generic ones.
+func (in *inferrer) instantiate(s *scheme) Type {
+	if len(s.vars) == 0 {
+		return s.t
+	}
+	fresh := map[*Var]*Var{}
+	for _, v := range s.vars {
+		w := in.fresh()
+		w.ordered = v.ordered
+		fresh[v] = w
+	}
+	var subst func(t Type) Type
+	subst = func(t Type) Type {
+		switch t := prune(t).(type) {
+		case *Var:
+			if w, ok := fresh[t]; ok {
+				return w
+			}
+			return t
+		case *Func:
+			f := &Func{Params: make([]Type, len(t.Params)), Result: subst(t.Result)}
+			for i, p := range t.Params {
+				f.Params[i] = subst(p)
+			}
+			return f
+		default:
+			return t
+		}
+	}
+	return subst(s.t)
+}
+
+// annotated returns the type of an annotation, a fresh variable for
+// none.
+func (in *inferrer) annotated(t ast.TypeExpr) Type {
+	if t == nil {
+		return in.fresh()
+	}
+	return in.typeOf(t)
+}
+
+// declare gives the object id declares the monomorphic type t.
+func (in *inferrer) declare(id *ast.Identifier, t Type) {
+	in.checker.declare(id, t)
+	if obj := in.resolved.Defs[id]; obj != nil {
+		in.schemes[obj] = &scheme{t: t}
+	}
+}
+
+// mismatch reports err, a failed unification, at e with msg and the
+// resolved types of args.
+func (in *inferrer) mismatch(e ast.Expression, err error, msg i18n.MessageID, args ...any) {
+	if err == errRecursive {
+		in.error(e, i18n.TypeRecursive, source(e))
+		return
+	}
+	for i, a := range args {
+		if t, ok := a.(Type); ok {
+			args[i] = formatType(t, false)
+		}
+	}
+	in.error(e, msg, args...)
+}
+
+func (in *inferrer) stmts(list []ast.Statment) {
+	for _, s := range list {
+		in.stmt(s)
+	}
+}
+
+func (in *inferrer) stmt(s ast.Statment) {
+	switch s := s.(type) {
+	case nil:
+	case *ast.LetStatment:
+		in.let(s)
+	case *ast.ReturnStatment:
+		if s.ReturnValue == nil {
+			return
+		}
+		t := in.expr(s.ReturnValue)
+		if in.result == nil {
+			return
+		}
+		if err := in.unify(t, in.result); err != nil {
+			in.mismatch(s.ReturnValue, err, i18n.TypeCannotReturn, source(s.ReturnValue), t, in.result)
+		}
+	case *ast.ExpressionStatment:
+		in.expr(s.Expression)
+	case *ast.BlockStatment:
+		if s != nil {
+			in.stmts(s.Statments)
+		}
+	case *ast.WhileStatment:
+		in.expr(s.Condition)
+		in.stmt(s.Body)
+	case *ast.ForStatment:
+		in.stmt(s.Init)
+		in.expr(s.Condition)
+		in.expr(s.Post)
+		in.stmt(s.Body)
+	case *ast.ForInStatment:
+		// ranging over a string yields its characters, as strings
+		t := Type(Any)
+		if prune(in.expr(s.Iterable)) == String {
+			t = String
+		}
+		in.declare(s.Variable, t)
+		in.stmt(s.Body)
+	case *ast.BreakStatment, *ast.ContinueStatment:
+	default:
+		panic(fmt.Sprintf("types: unexpected node type %T", s))
+	}
+}
+
+func (in *inferrer) let(s *ast.LetStatment) {
+	obj := in.resolved.Defs[s.Name]
+	in.level++
+	t := in.annotated(s.Type)
+	// bound before the value is inferred, so that it can recurse
+	in.declare(s.Name, t)
+	vt := in.expr(s.Value)
+	if err := in.unify(vt, t); err != nil {
+		in.mismatch(s.Value, err, i18n.TypeCannotDeclare, source(s.Value), vt, t)
+	}
+	in.level--
+	if _, ok := s.Value.(*ast.FunctionLiteral); ok && obj != nil && !in.assigned[obj] {
+		in.schemes[obj] = in.generalize(t)
+	}
+}
+
+// block infers the types in b and returns its type, the type of its
+// last statement if that is an expression.
+func (in *inferrer) block(b *ast.BlockStatment) Type {
+	if b == nil || len(b.Statments) == 0 {
+		return in.fresh()
+	}
+	in.stmts(b.Statments)
+	if s, ok := b.Statments[len(b.Statments)-1].(*ast.ExpressionStatment); ok {
+		return in.info.TypeOf(s.Expression)
+	}
+	return in.fresh()
+}
+
+func (in *inferrer) expr(e ast.Expression) Type {
+	if e == nil {
+		return Any
+	}
+	t := in.exprType(e)
+	in.info.Types[e] = t
+	return t
+}
+
+func (in *inferrer) exprType(e ast.Expression) Type {
+	switch e := e.(type) {
+	case *ast.IntegerLiteral:
+		return Int
+	case *ast.StringLiteral:
+		return String
+	case *ast.Boolean:
+		return Bool
+	case *ast.Identifier:
+		if s, ok := in.schemes[in.resolved.Uses[e]]; ok {
+			return in.instantiate(s)
+		}
+		return Any
+	case *ast.PrefixExpression:
+		t := in.expr(e.Right)
+		switch e.Operator {
+		case "!":
+			return Bool
+		case "-", "~":
+			if err := in.unify(t, Int); err != nil {
+				in.mismatch(e, err, i18n.TypeInvalidOp, e.Operator, source(e.Right), t)
+			}
+			return Int
+		}
+		return Any
+	case *ast.InfixExpression:
+		return in.binary(e, e.Operator, e.Left, in.expr(e.Left), e.Right, in.expr(e.Right))
+	case *ast.PostfixExpression:
+		t := in.expr(e.Left)
+		switch e.Operator {
+		case "++", "--":
+			if err := in.unify(t, Int); err != nil {
+				in.mismatch(e, err, i18n.TypeInvalidOp, e.Operator, source(e.Left), t)
+			}
+			return Int
+		}
+		return Any
+	case *ast.AssignExpression:
+		target := in.expr(e.Target)
+		t := in.expr(e.Value)
+		if op := e.Operator[:len(e.Operator)-1]; op != "" {
+			t = in.binary(e, op, e.Target, target, e.Value, t)
+		}
+		if err := in.unify(t, target); err != nil {
+			in.mismatch(e.Value, err, i18n.TypeCannotAssign, source(e.Value), t, target)
+		}
+		return target
+	case *ast.IndexExpression:
+		in.expr(e.Left)
+		in.expr(e.Index)
+		return Any
+	case *ast.CallExpression:
+		return in.call(e)
+	case *ast.IfExpression:
+		in.expr(e.Condition)
+		cons := in.block(e.Consequence)
+		if e.Alternative == nil {
+			return Any
+		}
+		alt := in.block(e.Alternative)
+		if err := in.unify(cons, alt); err != nil {
+			in.mismatch(e, err, i18n.TypeBranches, cons, alt)
+		}
+		return cons
+	case *ast.FunctionLiteral:
+		f := &Func{Params: make([]Type, len(e.Parameters)), Result: in.annotated(e.ReturnType)}
+		for i, p := range e.Parameters {
+			f.Params[i] = in.annotated(e.ParamType(i))
+			in.declare(p, f.Params[i])
+		}
+		result := in.result
+		in.result = f.Result
+		t := in.block(e.Body)
+		in.result = result
+		if n := len(e.Body.Statments); n != 0 {
+			if s, ok := e.Body.Statments[n-1].(*ast.ExpressionStatment); ok {
+				if err := in.unify(t, f.Result); err != nil {
+					in.mismatch(s.Expression, err, i18n.TypeCannotReturn, source(s.Expression), t, f.Result)
+				}
+			}
+		}
+		return f
+	}
+	panic(fmt.Sprintf("types: unexpected node type %T", e))
+}
+
+// binary returns the type of applying op to left and right, of type l
+// and r, reporting errors at e.
+func (in *inferrer) binary(e ast.Expression, op string, left ast.Expression, l Type, right ast.Expression, r Type) Type {
+	switch op {
+	case "==", "!=":
+		// values of any two types can be compared, so the operands
+		// constrain nothing
+		return Bool
+	case "&&", "||":
+		return Bool
+	}
+	defined, ok := operands[op]
+	if !ok {
+		return Any
+	}
+	if len(defined) == 1 {
+		// int only
+		for _, operand := range []struct {
+			e ast.Expression
+			t Type
+		}{{left, l}, {right, r}} {
+			if err := in.unify(operand.t, Int); err != nil {
+				in.mismatch(e, err, i18n.TypeInvalidOp, op, source(operand.e), operand.t)
+				return Int
+			}
+		}
+		return Int
+	}
+	if err := in.unify(l, r); err != nil {
+		in.mismatch(e, err, i18n.TypeMismatch, source(e), l, r)
+		return Any
+	}
+	if err := in.ordered(l); err != nil {
+		in.mismatch(e, err, i18n.TypeInvalidOp, op, source(left), l)
+		return Any
+	}
+	switch op {
+	case "<", ">", "<=", ">=":
+		return Bool
+	}
+	return l
+}
+
+func (in *inferrer) call(e *ast.CallExpression) Type {
+	t := in.expr(e.Function)
+	args := make([]Type, len(e.Arguments))
+	for i, a := range e.Arguments {
+		args[i] = in.expr(a)
+	}
+	switch f := prune(t).(type) {
+	case *Func:
+		if len(args) != len(f.Params) {
+			in.error(e, i18n.TypeArgCount, source(e.Function), len(args), len(f.Params))
+			return f.Result
+		}
+		for i, a := range e.Arguments {
+			if err := in.unify(args[i], f.Params[i]); err != nil {
+				in.mismatch(a, err, i18n.TypeCannotPass, source(a), args[i], f.Params[i], source(e.Function))
+			}
+		}
+		return f.Result
+	case *Var:
+		result := in.fresh()
+		if err := in.unify(f, &Func{Params: args, Result: result}); err != nil {
+			in.mismatch(e.Function, err, i18n.TypeNotFunc, source(e.Function), f)
+		}
+		return result
+	case Basic:
+		if f != Any {
+			in.error(e, i18n.TypeNotFunc, source(e.Function), f)
+		}
+	}
+	return Any
+}
@@ -0,0 +1,122 @@
+package types
+
+import (
+	"gointer/ast"
+	"gointer/lexer"
+	"gointer/parser"
+	"gointer/resolver"
+	"reflect"
+	"testing"
+)
+
+func infer(t *testing.T, input string) (*ast.Program, *Info, []string) {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if errs := p.Errors(); len(errs) != 0 {
+		t.Fatalf("parse errors in %q: %v", input, errs)
+	}
+	resolved, errs := resolver.Resolve(program, resolver.WithBuiltins("len", "puts", "xs"))
+	if len(errs) != 0 {
+		t.Fatalf("resolve errors in %q: %v", input, errs)
+	}
+	info, errs := Infer(program, resolved)
+	return program, info, errs
+}
+
+func TestInferSignatures(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x = 1", "int"},
+		{"let sub = fn(a, b) { a - b }", "fn(int, int) -> int"},
+		{"let id = fn(x) { x }", "fn('a) -> 'a"},
+		{"let konst = fn(x, y) { x }", "fn('a, 'b) -> 'a"},
+		{"let add = fn(a, b) { a + b }", "fn('a, 'a) -> 'a where 'a: int|string"},
+		{"let less = fn(a, b) { a < b }", "fn('a, 'a) -> bool where 'a: int|string"},
+		{"let apply = fn(f, x) { f(x) }", "fn(fn('a) -> 'b, 'a) -> 'b"},
+		{"let compose = fn(f, g) { fn(x) { f(g(x)) } }", "fn(fn('a) -> 'b, fn('c) -> 'a) -> fn('c) -> 'b"},
+		{"let fact = fn(n) { if (n < 2) { 1 } else { n * fact(n - 1) } }", "fn(int) -> int"},
+		{"let loop = fn(n) { loop(n) }", "fn('a) -> 'b"},
+		{"let sign = fn(n) { if (n < 0) { return -1; } 1 }", "fn(int) -> int"},
+		{"let greet = fn(name: string) { name }", "fn(string) -> string"},
+		{"let f: fn(int) -> int = fn(x) { x }", "fn(int) -> int"},
+		{"let show = fn(x) { puts(x) }", "fn('a) -> any"},
+		{"let x = 1; x = x + 1", "int"},
+	}
+	for _, tt := range tests {
+		program, info, errs := infer(t, tt.input)
+		if len(errs) != 0 {
+			t.Errorf("Infer(%q) errors: %q", tt.input, errs)
+			continue
+		}
+		let := program.Statments[0].(*ast.LetStatment)
+		if got := Format(info.TypeOf(let.Name)); got != tt.expected {
+			t.Errorf("Infer(%q) type = %q, want %q", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestInferErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let id = fn(x) { x }; id(1) + 2; id(true) && false", nil},
+		{"let add = fn(a, b) { a + b }; add(1, 2); add(\"a\", \"b\")", nil},
+		{"true + 1", []string{"1:1: invalid operation: true + 1 (mismatched types bool and int)"}},
+		{"let sub = fn(a, b) { a - b }; sub(1, true)",
+			[]string{"1:38: cannot use true (type bool) as int value in argument to sub"}},
+		{"let add = fn(a, b) { a + b }; add(true, false)",
+			[]string{
+				"1:35: cannot use true (type bool) as int|string value in argument to add",
+				"1:41: cannot use false (type bool) as int|string value in argument to add",
+			}},
+		{"let f = fn(x) { x + 1 }; f(1, 2)",
+			[]string{"1:26: wrong number of arguments in call to f: have 2, want 1"}},
+		{"fn(x) { if (x) { 1 } else { true } }",
+			[]string{"1:9: if branches have mismatched types int and bool"}},
+		{"fn(f) { f(f) }", []string{"1:9: recursive type in f"}},
+		{"let s = fn(x) { x < 1 }; s(\"a\")", []string{"1:28: cannot use \"a\" (type string) as int value in argument to s"}},
+		{"let x = 1; x = true", []string{"1:16: cannot use true (type bool) as int value in assignment"}},
+		{"let f = fn(x) { x(1) }; f(2)", []string{"1:27: cannot use 2 (type int) as fn(int) -> 'a value in argument to f"}},
+		{"fn(x) { x(1); x + 1 }", []string{"1:15: invalid operation: x + 1 (mismatched types fn(int) -> 'a and int)"}},
+		{"let n = 5; n(1)", []string{"1:12: invalid operation: cannot call non-function n (type int)"}},
+		{"let f = fn(a: bool) { a }; f(1)", []string{"1:30: cannot use 1 (type int) as bool value in argument to f"}},
+		{"fn(x) -> int { x && true }", []string{"1:16: cannot use x && true (type bool) as int value in return"}},
+		{"let g = fn(x) { x }; let h = g; h(1); h(true)", []string{"1:41: cannot use true (type bool) as int value in argument to h"}},
+		{"for (x in xs) { x + 1; x && true }", nil},
+		{`1 == true; let x = 5; x != "a"`, nil},
+		{`let eq = fn(a, b) { a == b }; eq(1, "a") && eq(true, true)`, nil},
+		{`let s = 0; for (c in "ab") { s += c }`, []string{"1:30: invalid operation: s += c (mismatched types int and string)"}},
+		{`let s = ""; for (c in "ab") { s += c }; s`, nil},
+		{`let f = fn(t: string) { for (c in t) { c - 1 } }`, []string{"1:40: invalid operation: operator - not defined on c (type string)"}},
+		{"let f = fn(xs) { len(xs) + xs[0] }; f(1)", nil},
+	}
+	for _, tt := range tests {
+		_, _, errs := infer(t, tt.input)
+		if !reflect.DeepEqual(errs, tt.expected) {
+			t.Errorf("Infer(%q) errors = %q, want %q", tt.input, errs, tt.expected)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	a, b := &Var{id: 1}, &Var{id: 2, ordered: true}
+	bound := &Var{id: 3, bound: Int}
+	tests := []struct {
+		t        Type
+		expected string
+	}{
+		{Int, "int"},
+		{bound, "int"},
+		{&Func{Params: []Type{a, bound}, Result: a}, "fn('a, int) -> 'a"},
+		{&Func{Params: []Type{b, a}, Result: b}, "fn('a, 'b) -> 'a where 'a: int|string"},
+	}
+	for _, tt := range tests {
+		if got := Format(tt.t); got != tt.expected {
+			t.Errorf("Format(%s) = %q, want %q", tt.t, got, tt.expected)
+		}
+	}
+}
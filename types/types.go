@@ -0,0 +1,177 @@
+// Package types checks the optional type annotations of a program.
+//
+// Check is gradual: everything without an annotation or a type that
+// follows from literals and operators has type any, which is compatible
+// with every other type, so unannotated programs always pass. Mistakes
+// such as true + 1 are reported before the program runs.
+//
+// Infer goes further and infers the types of unannotated parameters and
+// functions with Hindley-Milner type inference.
+package types
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A Type is the type of a value.
+type Type interface {
+	String() string
+}
+
+// Basic is a predeclared type.
+type Basic int
+
+const (
+	// Any is the type of values the checker knows nothing about.
+	Any Basic = iota
+	Int
+	Bool
+	String
+)
+
+func (b Basic) String() string {
+	switch b {
+	case Any:
+		return "any"
+	case Int:
+		return "int"
+	case Bool:
+		return "bool"
+	case String:
+		return "string"
+	}
+	return fmt.Sprintf("Basic(%d)", int(b))
+}
+
+// Func is the type of a function.
+type Func struct {
+	Params []Type
+	Result Type
+}
+
+func (f *Func) String() string {
+	params := make([]string, 0, len(f.Params))
+	for _, p := range f.Params {
+		params = append(params, p.String())
+	}
+	return fmt.Sprintf("fn(%s) -> %s", strings.Join(params, ", "), f.Result)
+}
+
+// Var is a type variable of inference, a type that is not known yet.
+// Once unified with another type it is bound to it.
+type Var struct {
+	id    int
+	level int
+	// ordered restricts the variable to the types + and < are defined
+	// on, int and string.
+	ordered bool
+	bound   Type
+}
+
+func (v *Var) String() string {
+	if v.bound != nil {
+		return v.bound.String()
+	}
+	return fmt.Sprintf("t%d", v.id)
+}
+
+// Universe maps the type names usable in annotations to their types.
+var Universe = map[string]Type{
+	"any":    Any,
+	"int":    Int,
+	"bool":   Bool,
+	"string": String,
+}
+
+// Compatible reports whether a value of type a may be used where b is
+// expected. Any is compatible with every type in both directions, and
+// function types are compatible if their parameters and results are.
+func Compatible(a, b Type) bool {
+	a, b = prune(a), prune(b)
+	if a == Any || b == Any {
+		return true
+	}
+	switch a := a.(type) {
+	case Basic, *Var:
+		return a == b
+	case *Func:
+		b, ok := b.(*Func)
+		if !ok || len(a.Params) != len(b.Params) {
+			return false
+		}
+		for i := range a.Params {
+			if !Compatible(a.Params[i], b.Params[i]) {
+				return false
+			}
+		}
+		return Compatible(a.Result, b.Result)
+	}
+	return false
+}
+
+// prune returns the type t is bound to, following bound variables.
+func prune(t Type) Type {
+	for {
+		v, ok := t.(*Var)
+		if !ok || v.bound == nil {
+			return t
+		}
+		t = v.bound
+	}
+}
+
+// Format returns t as written in annotations, with its unbound type
+// variables named 'a, 'b and so on in order of appearance. Variables
+// restricted to int and string are listed in a where clause:
+//
+//	fn('a, 'a) -> 'a where 'a: int|string
+func Format(t Type) string {
+	return formatType(t, true)
+}
+
+// formatType formats t as Format does if where is set, otherwise it writes
+// the restricted variables as int|string, for messages.
+func formatType(t Type, where bool) string {
+	names := map[*Var]string{}
+	var ordered []string
+	var format func(t Type) string
+	format = func(t Type) string {
+		switch t := prune(t).(type) {
+		case *Var:
+			if t.ordered && !where {
+				return "int|string"
+			}
+			name, ok := names[t]
+			if !ok {
+				name = "'" + string(rune('a'+len(names)%26))
+				if n := len(names) / 26; n > 0 {
+					name += fmt.Sprint(n)
+				}
+				names[t] = name
+				if t.ordered {
+					ordered = append(ordered, name)
+				}
+			}
+			return name
+		case *Func:
+			params := make([]string, 0, len(t.Params))
+			for _, p := range t.Params {
+				params = append(params, format(p))
+			}
+			return fmt.Sprintf("fn(%s) -> %s", strings.Join(params, ", "), format(t.Result))
+		default:
+			return t.String()
+		}
+	}
+	s := format(t)
+	for i, name := range ordered {
+		if i == 0 {
+			s += " where "
+		} else {
+			s += ", "
+		}
+		s += name + ": int|string"
+	}
+	return s
+}
@@ -0,0 +1,257 @@
+package vet
+
+import (
+	"bytes"
+	"gointer/ast"
+	"gointer/format"
+	"gointer/i18n"
+	"gointer/resolver"
+	"strconv"
+)
+
+func init() {
+	Register(&Check{
+		ID:       "unused",
+		Severity: Warning,
+		Doc:      "variables that are declared and never used",
+		Run:      checkUnused,
+	})
+	Register(&Check{
+		ID:       "shadow",
+		Severity: Warning,
+		Doc:      "declarations that hide a variable or builtin of an enclosing scope",
+		Run:      checkShadow,
+	})
+	Register(&Check{
+		ID:       "unreachable",
+		Severity: Warning,
+		Doc:      "statements after return, break or continue",
+		Run:      checkUnreachable,
+	})
+	Register(&Check{
+		ID:       "selfcompare",
+		Severity: Warning,
+		Doc:      "comparisons of an expression with itself",
+		Run:      checkSelfCompare,
+	})
+	Register(&Check{
+		ID:       "constcond",
+		Severity: Warning,
+		Doc:      "if conditions that are always true or always false",
+		Run:      checkConstCond,
+	})
+	Register(&Check{
+		ID:       "divzero",
+		Severity: Error,
+		Doc:      "division or remainder by the literal 0",
+		Run:      checkDivZero,
+	})
+	Register(&Check{
+		ID:       "missingelse",
+		Severity: Warning,
+		Doc:      "functions ending in an if that returns but has no else",
+		Run:      checkMissingElse,
+	})
+}
+
+// inspect calls f for every node of the program.
+func (p *Pass) inspect(f func(ast.Node)) {
+	ast.Inspect(p.Program, func(n ast.Node) bool {
+		if n != nil {
+			f(n)
+		}
+		return true
+	})
+}
+
+// source returns the canonical source of e for messages.
+func source(e ast.Expression) string {
+	buf := bytes.Buffer{}
+	format.Fprint(&buf, e)
+	return string(bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+}
+
+func checkUnused(p *Pass) {
+	used := map[*resolver.Object]bool{}
+	for _, obj := range p.Info.Uses {
+		used[obj] = true
+	}
+	p.inspect(func(n ast.Node) {
+		id, ok := n.(*ast.Identifier)
+		if !ok {
+			return
+		}
+		obj := p.Info.Defs[id]
+		if obj == nil || obj.Kind != resolver.Var || used[obj] || obj.Name == "_" {
+			return
+		}
+		p.report(id.Pos(), i18n.VetUnused, obj.Name)
+	})
+}
+
+func checkShadow(p *Pass) {
+	p.inspect(func(n ast.Node) {
+		scope, ok := p.Info.Scopes[n]
+		if !ok {
+			return
+		}
+		for _, name := range scope.Names() {
+			obj := scope.Lookup(name)
+			outer := scope.Parent.Lookup(name)
+			switch {
+			case outer == nil:
+			case outer.Decl != nil && outer.Decl.Pos().Offset > obj.Decl.Pos().Offset:
+				// declared after the inner one, as in
+				// let f = fn(x) { x }; let x = 1;
+			case outer.Kind == resolver.Builtin:
+				p.report(obj.Decl.Pos(), i18n.VetShadowBuiltin, name)
+			default:
+				p.report(obj.Decl.Pos(), i18n.VetShadow, name, outer.Decl.Pos())
+			}
+		}
+	})
+}
+
+func checkUnreachable(p *Pass) {
+	check := func(list []ast.Statment) {
+		for i, s := range list[:max(len(list)-1, 0)] {
+			switch s.(type) {
+			case *ast.ReturnStatment, *ast.BreakStatment, *ast.ContinueStatment:
+				p.report(list[i+1].Pos(), i18n.VetUnreachable)
+				return
+			}
+		}
+	}
+	p.inspect(func(n ast.Node) {
+		switch n := n.(type) {
+		case *ast.Program:
+			check(n.Statments)
+		case *ast.BlockStatment:
+			check(n.Statments)
+		}
+	})
+}
+
+var comparisons = map[string]bool{
+	"==": true, "!=": true, "<": true, ">": true, "<=": true, ">=": true,
+}
+
+func checkSelfCompare(p *Pass) {
+	p.inspect(func(n ast.Node) {
+		e, ok := n.(*ast.InfixExpression)
+		if !ok || !comparisons[e.Operator] || !pure(e.Left) {
+			return
+		}
+		if ast.Equal(e.Left, e.Right, ast.IgnoreTokens()) {
+			p.report(e.Token.Pos, i18n.VetSelfCompare, source(e.Left))
+		}
+	})
+}
+
+// pure reports whether evaluating e twice gives the same value, which
+// calls and assignments may not.
+func pure(e ast.Expression) bool {
+	pure := true
+	ast.Inspect(e, func(n ast.Node) bool {
+		switch n.(type) {
+		case *ast.CallExpression, *ast.AssignExpression, *ast.PostfixExpression:
+			pure = false
+		}
+		return pure
+	})
+	return pure
+}
+
+func checkConstCond(p *Pass) {
+	p.inspect(func(n ast.Node) {
+		e, ok := n.(*ast.IfExpression)
+		if !ok || e.Condition == nil {
+			return
+		}
+		if v, ok := constTruth(e.Condition); ok {
+			p.report(e.Condition.Pos(), i18n.VetConstCond, strconv.FormatBool(v))
+		}
+	})
+}
+
+// constTruth returns whether e is truthy if that does not depend on
+// any variable. Everything but false and null is truthy.
+func constTruth(e ast.Expression) (bool, bool) {
+	switch e := e.(type) {
+	case *ast.Boolean:
+		return e.Value, true
+	case *ast.IntegerLiteral, *ast.StringLiteral, *ast.FunctionLiteral:
+		return true, true
+	case *ast.PrefixExpression:
+		if e.Operator != "!" {
+			return false, false
+		}
+		v, ok := constTruth(e.Right)
+		return !v, ok
+	}
+	return false, false
+}
+
+func checkDivZero(p *Pass) {
+	isZero := func(e ast.Expression) bool {
+		lit, ok := e.(*ast.IntegerLiteral)
+		return ok && lit.Value == 0
+	}
+	p.inspect(func(n ast.Node) {
+		switch e := n.(type) {
+		case *ast.InfixExpression:
+			if (e.Operator == "/" || e.Operator == "%") && isZero(e.Right) {
+				p.report(e.Token.Pos, i18n.VetDivZero)
+			}
+		case *ast.AssignExpression:
+			if (e.Operator == "/=" || e.Operator == "%=") && isZero(e.Value) {
+				p.report(e.Token.Pos, i18n.VetDivZero)
+			}
+		}
+	})
+}
+
+func checkMissingElse(p *Pass) {
+	var check func(b *ast.BlockStatment)
+	check = func(b *ast.BlockStatment) {
+		if b == nil || len(b.Statments) == 0 {
+			return
+		}
+		stmt, ok := b.Statments[len(b.Statments)-1].(*ast.ExpressionStatment)
+		if !ok {
+			return
+		}
+		e, ok := stmt.Expression.(*ast.IfExpression)
+		if !ok {
+			return
+		}
+		if e.Alternative == nil {
+			if returns(e.Consequence) {
+				p.report(e.Pos(), i18n.VetMissingElse)
+			}
+			return
+		}
+		check(e.Consequence)
+		check(e.Alternative)
+	}
+	p.inspect(func(n ast.Node) {
+		if fn, ok := n.(*ast.FunctionLiteral); ok {
+			check(fn.Body)
+		}
+	})
+}
+
+// returns reports whether b always ends in a return statement.
+func returns(b *ast.BlockStatment) bool {
+	if b == nil || len(b.Statments) == 0 {
+		return false
+	}
+	switch s := b.Statments[len(b.Statments)-1].(type) {
+	case *ast.ReturnStatment:
+		return true
+	case *ast.ExpressionStatment:
+		e, ok := s.Expression.(*ast.IfExpression)
+		return ok && returns(e.Consequence) && returns(e.Alternative)
+	}
+	return false
+}
@@ -0,0 +1,209 @@
+// Package vet runs checks over a resolved program and reports code that
+// is legal but probably wrong. Checks are registered by ID and can be
+// disabled by the caller or with a comment in the source:
+//
+//	let x = 1; // vet:ignore unused
+//
+// A vet:ignore comment silences the listed checks, or all of them if
+// none are listed, on its own line. A comment on a line of its own
+// silences them on the line after it too:
+//
+//	// vet:ignore unused
+//	let y = 2;
+package vet
+
+import (
+	"fmt"
+	"gointer/ast"
+	"gointer/i18n"
+	"gointer/resolver"
+	"gointer/token"
+	"slices"
+	"strings"
+)
+
+type Severity int
+
+const (
+	Warning Severity = iota
+	Error
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Warning:
+		return "warning"
+	case Error:
+		return "error"
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// A Check finds one kind of problem.
+type Check struct {
+	// ID names the check in reports, flags and vet:ignore comments.
+	ID       string
+	Severity Severity
+	// Doc is a one line description of what the check reports.
+	Doc string
+	Run func(*Pass)
+}
+
+// A Diagnostic is a problem reported by a check.
+type Diagnostic struct {
+	Pos      token.Position
+	Check    string
+	Severity Severity
+	Message  string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s: %s: %s (%s)", d.Pos, d.Severity, d.Message, d.Check)
+}
+
+// A Pass is the input of one check over one program.
+type Pass struct {
+	Program *ast.Program
+	Info    *resolver.Info
+	// Lang is the language messages should be reported in.
+	Lang i18n.Lang
+
+	check *Check
+	diags []Diagnostic
+}
+
+// Report reports a problem at pos.
+func (p *Pass) Report(pos token.Position, msg string) {
+	p.diags = append(p.diags, Diagnostic{
+		Pos:      pos,
+		Check:    p.check.ID,
+		Severity: p.check.Severity,
+		Message:  msg,
+	})
+}
+
+func (p *Pass) report(pos token.Position, id i18n.MessageID, args ...any) {
+	p.Report(pos, i18n.Sprintf(p.Lang, id, args...))
+}
+
+var checks = map[string]*Check{}
+
+// Register adds c to the checks run by Run. It panics if a check with
+// the same ID is already registered.
+func Register(c *Check) {
+	if _, ok := checks[c.ID]; ok {
+		panic("vet: check " + c.ID + " registered twice")
+	}
+	checks[c.ID] = c
+}
+
+// Checks returns the registered checks sorted by ID.
+func Checks() []*Check {
+	list := make([]*Check, 0, len(checks))
+	for _, c := range checks {
+		list = append(list, c)
+	}
+	slices.SortFunc(list, func(a, b *Check) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+	return list
+}
+
+// Lookup returns the registered check with the given ID.
+func Lookup(id string) (*Check, bool) {
+	c, ok := checks[id]
+	return c, ok
+}
+
+type config struct {
+	disabled map[string]bool
+	lang     i18n.Lang
+}
+
+type Option func(*config)
+
+// Disable turns off the checks with the given IDs.
+func Disable(ids ...string) Option {
+	return func(c *config) {
+		for _, id := range ids {
+			c.disabled[id] = true
+		}
+	}
+}
+
+// WithLang selects the language diagnostics are reported in.
+func WithLang(lang i18n.Lang) Option {
+	return func(c *config) {
+		c.lang = lang
+	}
+}
+
+// Run runs the enabled checks over program, which info must be the
+// resolution of, and returns their diagnostics sorted by position.
+func Run(program *ast.Program, info *resolver.Info, opts ...Option) []Diagnostic {
+	cfg := config{disabled: map[string]bool{}}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	ignored := ignoredLines(program)
+
+	var diags []Diagnostic
+	for _, c := range Checks() {
+		if cfg.disabled[c.ID] {
+			continue
+		}
+		pass := &Pass{Program: program, Info: info, Lang: cfg.lang, check: c}
+		c.Run(pass)
+		for _, d := range pass.diags {
+			if ids := ignored[d.Pos.Line]; slices.Contains(ids, d.Check) || slices.Contains(ids, "*") {
+				continue
+			}
+			diags = append(diags, d)
+		}
+	}
+	slices.SortStableFunc(diags, func(a, b Diagnostic) int {
+		return a.Pos.Offset - b.Pos.Offset
+	})
+	return diags
+}
+
+// ignoredLines maps lines to the check IDs silenced on them by
+// vet:ignore comments, "*" standing for all checks.
+func ignoredLines(program *ast.Program) map[int][]string {
+	lines := map[int][]string{}
+	for _, c := range program.Comments {
+		text := strings.TrimSpace(strings.TrimPrefix(c.Token.Literal, "//"))
+		rest, ok := strings.CutPrefix(text, "vet:ignore")
+		if !ok || rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+			continue
+		}
+		ids := strings.FieldsFunc(rest, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t'
+		})
+		if len(ids) == 0 {
+			ids = []string{"*"}
+		}
+		line := c.Pos().Line
+		lines[line] = append(lines[line], ids...)
+		if !trailing(program, c) {
+			lines[line+1] = append(lines[line+1], ids...)
+		}
+	}
+	return lines
+}
+
+// trailing reports whether code comes before c on its line.
+func trailing(program *ast.Program, c *ast.Comment) bool {
+	before := func(p token.Position) bool {
+		return p.Line == c.Pos().Line && p.Column < c.Pos().Column
+	}
+	found := false
+	ast.Inspect(program, func(n ast.Node) bool {
+		if found || n == nil {
+			return false
+		}
+		found = before(n.Pos()) || before(n.End())
+		return !found
+	})
+	return found
+}
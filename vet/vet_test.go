@@ -0,0 +1,156 @@
+package vet
+
+import (
+	"gointer/ast"
+	"gointer/i18n"
+	"gointer/lexer"
+	"gointer/parser"
+	"gointer/resolver"
+	"reflect"
+	"testing"
+)
+
+func run(t *testing.T, input string, opts ...Option) []string {
+	t.Helper()
+	p := parser.New(lexer.New(input, lexer.WithComments()))
+	program := p.ParseProgram()
+	if errs := p.Errors(); len(errs) != 0 {
+		t.Fatalf("parse errors in %q: %v", input, errs)
+	}
+	info, errs := resolver.Resolve(program, resolver.WithBuiltins("puts", "len"))
+	if len(errs) != 0 {
+		t.Fatalf("resolve errors in %q: %v", input, errs)
+	}
+	var got []string
+	for _, d := range Run(program, info, opts...) {
+		got = append(got, d.String())
+	}
+	return got
+}
+
+func TestChecks(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let x = 1; puts(x)", nil},
+		{"let x = 1;", []string{"1:5: warning: x declared and not used (unused)"}},
+		{"let f = fn(a, b) { 1 }; f(1)", nil},
+		{"let _ = 1;", nil},
+		{"let xs = 1; for (x in xs) { puts(x) }", nil},
+		{"let xs = 1; for (x in xs) { }", []string{"1:18: warning: x declared and not used (unused)"}},
+
+		{"let x = 1; puts(x); if (x) { let x = 2; puts(x) }",
+			[]string{"1:34: warning: declaration of x shadows declaration at 1:5 (shadow)"}},
+		{"let len = 1; puts(len)",
+			[]string{"1:5: warning: declaration of len shadows builtin (shadow)"}},
+		{"let f = fn(x) { x }; let x = 1; puts(f(x))", nil},
+
+		{"let f = fn(x) { return x; puts(x); puts(x) }; f(1)",
+			[]string{"1:27: warning: unreachable code (unreachable)"}},
+		{"while (true) { break; puts(1) }",
+			[]string{"1:23: warning: unreachable code (unreachable)"}},
+		{"let f = fn(x) { if (x) { return 1; } puts(x) }; f(1)", nil},
+
+		{"let a = 1; puts(a == a); puts(a + 1 < a + 1)", []string{
+			"1:19: warning: comparison of a with itself (selfcompare)",
+			"1:37: warning: comparison of a + 1 with itself (selfcompare)",
+		}},
+		{"let a = 1; puts(a == 1); puts(len(a) == len(a))", nil},
+
+		{"if (true) { 1 }; if (!1) { 2 }", []string{
+			"1:5: warning: condition is always true (constcond)",
+			"1:22: warning: condition is always false (constcond)",
+		}},
+		{"let x = 1; if (x) { 1 }; while (true) { break }", nil},
+
+		{"let x = 1; puts(x / 0); x %= 0; puts(x / 1)", []string{
+			"1:19: error: division by zero (divzero)",
+			"1:27: error: division by zero (divzero)",
+		}},
+
+		{"let f = fn(x) { if (x) { return 1; } }; f(1)",
+			[]string{"1:17: warning: if without else, the function returns no value when the condition is false (missingelse)"}},
+		{"let f = fn(x) { if (x) { 1 } else { if (x) { return 2; } } }; f(1)",
+			[]string{"1:37: warning: if without else, the function returns no value when the condition is false (missingelse)"}},
+		{"let f = fn(x) { if (x) { return 1; } else { return 2; } }; f(1)", nil},
+		{"let f = fn(x) { if (x) { puts(x) } }; f(1)", nil},
+	}
+	for _, tt := range tests {
+		if got := run(t, tt.input); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("vet %q\ngot=%q\nwant=%q", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestDisable(t *testing.T) {
+	input := "let x = 1 / 0;"
+	expected := []string{"1:11: error: division by zero (divzero)"}
+	if got := run(t, input, Disable("unused")); !reflect.DeepEqual(got, expected) {
+		t.Errorf("got=%q, want=%q", got, expected)
+	}
+	if got := run(t, input, Disable("unused", "divzero")); got != nil {
+		t.Errorf("got=%q, want none", got)
+	}
+}
+
+func TestIgnoreComments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let x = 1 / 0; // vet:ignore", nil},
+		{"let x = 1 / 0; // vet:ignore unused", []string{"1:11: error: division by zero (divzero)"}},
+		{"let x = 1 / 0; //vet:ignore unused,divzero", nil},
+		{"// vet:ignore divzero unused\nlet x = 1 / 0;", nil},
+		{"// vet:ignore\n\nlet x = 1;", []string{"3:5: warning: x declared and not used (unused)"}},
+		{"let x = 2; // vet:ignore unused\nlet y = 1;", []string{"2:5: warning: y declared and not used (unused)"}},
+		{"let f = fn() { // vet:ignore unused\n\tlet y = 1;\n};", []string{"2:6: warning: y declared and not used (unused)"}},
+		{"let f = fn() {\n\t// vet:ignore unused\n\tlet y = 1;\n};\nf();", nil},
+		{"let x = 1; // vet:ignored", []string{"1:5: warning: x declared and not used (unused)"}},
+	}
+	for _, tt := range tests {
+		if got := run(t, tt.input); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("vet %q\ngot=%q\nwant=%q", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestRegister(t *testing.T) {
+	c := &Check{
+		ID:  "testident",
+		Doc: "identifiers named foo",
+		Run: func(p *Pass) {
+			ast.Inspect(p.Program, func(n ast.Node) bool {
+				if id, ok := n.(*ast.Identifier); ok && id.Value == "foo" {
+					p.Report(id.Pos(), "foo")
+				}
+				return true
+			})
+		},
+	}
+	Register(c)
+	defer delete(checks, c.ID)
+
+	if got, ok := Lookup("testident"); !ok || got != c {
+		t.Fatalf("Lookup did not find the registered check")
+	}
+	expected := []string{"1:5: warning: foo (testident)"}
+	if got := run(t, "let foo = 1; puts(1)", Disable("unused")); !reflect.DeepEqual(got, expected) {
+		t.Errorf("got=%q, want=%q", got, expected)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("registering a check twice did not panic")
+		}
+	}()
+	Register(&Check{ID: "testident"})
+}
+
+func TestLang(t *testing.T) {
+	expected := []string{"1:5: warning: x 已声明但未使用 (unused)"}
+	if got := run(t, "let x = 1;", WithLang(i18n.Chinese)); !reflect.DeepEqual(got, expected) {
+		t.Errorf("got=%q, want=%q", got, expected)
+	}
+}
@@ -0,0 +1,370 @@
+// Package vm runs compiled programs on a stack machine.
+//
+// Each call pushes a frame whose locals sit on the stack just above the
+// arguments, which are the first locals. Locals that closures capture
+// hold a cell instead of their value.
+package vm
+
+import (
+	"fmt"
+	"gointer/code"
+	"gointer/compiler"
+	"gointer/i18n"
+	"gointer/object"
+	"gointer/token"
+	"io"
+	"os"
+)
+
+const (
+	StackSize = 2048
+	MaxFrames = 1024
+)
+
+// An Error is a runtime error, reported at the position of the source
+// the failing instruction was compiled from.
+type Error struct {
+	Pos token.Position
+	Msg string
+}
+
+func (e *Error) Error() string { return fmt.Sprintf("%s: %s", e.Pos, e.Msg) }
+
+type frame struct {
+	cl *object.Closure
+	ip int
+	// base is the stack index of the first local.
+	base int
+}
+
+type VM struct {
+	constants []object.Object
+	globals   []object.Object
+
+	stack []object.Object
+	// sp is the index of the next free slot, the top is stack[sp-1].
+	sp int
+
+	frames []*frame
+	// result is the value of the last statement run outside of
+	// functions, or the value the program returned.
+	result object.Object
+
+	out  io.Writer
+	lang i18n.Lang
+}
+
+type Option func(*VM)
+
+// WithOutput sets where builtins such as puts write to, os.Stdout by
+// default.
+func WithOutput(w io.Writer) Option {
+	return func(vm *VM) {
+		vm.out = w
+	}
+}
+
+// WithLang selects the language runtime errors are reported in.
+func WithLang(lang i18n.Lang) Option {
+	return func(vm *VM) {
+		vm.lang = lang
+	}
+}
+
+// New returns a VM ready to run bytecode.
+func New(bytecode *compiler.Bytecode, opts ...Option) *VM {
+	main := &object.Closure{Fn: &object.CompiledFunction{
+		Instructions: bytecode.Instructions,
+		Lines:        bytecode.Lines,
+	}}
+	globals := make([]object.Object, bytecode.NumGlobals)
+	for i := range globals {
+		globals[i] = object.NULL
+	}
+	vm := &VM{
+		constants: bytecode.Constants,
+		globals:   globals,
+		stack:     make([]object.Object, StackSize),
+		frames:    []*frame{{cl: main}},
+		result:    object.NULL,
+		out:       os.Stdout,
+	}
+	for _, opt := range opts {
+		opt(vm)
+	}
+	return vm
+}
+
+// Result returns the value of the program: the value it returned, or
+// else the value of its last statement, null unless that is an
+// expression.
+func (vm *VM) Result() object.Object {
+	return vm.result
+}
+
+func (vm *VM) frame() *frame {
+	return vm.frames[len(vm.frames)-1]
+}
+
+// error returns err as an Error at the instruction at ip.
+func (vm *VM) error(ip int, err error) error {
+	msg := err.Error()
+	if e, ok := err.(*object.Error); ok {
+		msg = e.Localize(vm.lang)
+	}
+	return &Error{Pos: vm.frame().cl.Fn.Lines.Lookup(ip), Msg: msg}
+}
+
+func (vm *VM) push(o object.Object) error {
+	if vm.sp >= StackSize {
+		return object.Errorf(i18n.StackOverflow)
+	}
+	vm.stack[vm.sp] = o
+	vm.sp++
+	return nil
+}
+
+func (vm *VM) pop() object.Object {
+	vm.sp--
+	o := vm.stack[vm.sp]
+	vm.stack[vm.sp] = nil
+	return o
+}
+
+// Run runs the program to its end.
+func (vm *VM) Run() error {
+	for {
+		f := vm.frame()
+		ins := f.cl.Fn.Instructions
+		if f.ip >= len(ins) {
+			return nil
+		}
+		ip := f.ip
+		op := code.Opcode(ins[ip])
+		f.ip++
+		done, err := vm.step(f, op, ins)
+		if err != nil {
+			return vm.error(ip, err)
+		}
+		if done {
+			return nil
+		}
+	}
+}
+
+// step runs the instruction op of f, whose ip is at its operands. It
+// reports whether the program is done.
+func (vm *VM) step(f *frame, op code.Opcode, ins code.Instructions) (bool, error) {
+	u16 := func() int {
+		v := int(code.ReadUint16(ins[f.ip:]))
+		f.ip += 2
+		return v
+	}
+	u8 := func() int {
+		v := int(code.ReadUint8(ins[f.ip:]))
+		f.ip++
+		return v
+	}
+
+	switch op {
+	case code.OpConstant:
+		return false, vm.push(vm.constants[u16()])
+	case code.OpPop:
+		vm.pop()
+	case code.OpResult:
+		vm.result = vm.pop()
+	case code.OpDup:
+		return false, vm.push(vm.stack[vm.sp-1])
+	case code.OpTrue:
+		return false, vm.push(object.TRUE)
+	case code.OpFalse:
+		return false, vm.push(object.FALSE)
+	case code.OpNull:
+		return false, vm.push(object.NULL)
+
+	case code.OpAdd, code.OpSub, code.OpMul, code.OpDiv, code.OpMod, code.OpPow,
+		code.OpBitAnd, code.OpBitOr, code.OpBitXor, code.OpShl, code.OpShr,
+		code.OpEqual, code.OpNotEqual, code.OpGreaterThan, code.OpGreaterEqual,
+		code.OpLessThan, code.OpLessEqual:
+		right := vm.pop()
+		left := vm.pop()
+		result, err := object.Binary(operators[op], left, right)
+		if err != nil {
+			return false, err
+		}
+		return false, vm.push(result)
+	case code.OpMinus, code.OpBang, code.OpBitNot:
+		result, err := object.Unary(operators[op], vm.pop())
+		if err != nil {
+			return false, err
+		}
+		return false, vm.push(result)
+
+	case code.OpJump:
+		f.ip = u16()
+	case code.OpJumpNotTruthy:
+		target := u16()
+		if !object.IsTruthy(vm.pop()) {
+			f.ip = target
+		}
+	case code.OpJumpTruthy:
+		target := u16()
+		if object.IsTruthy(vm.pop()) {
+			f.ip = target
+		}
+
+	case code.OpGetGlobal:
+		return false, vm.push(vm.globals[u16()])
+	case code.OpSetGlobal:
+		vm.globals[u16()] = vm.pop()
+	case code.OpGetLocal:
+		return false, vm.push(vm.stack[f.base+u8()])
+	case code.OpSetLocal:
+		vm.stack[f.base+u8()] = vm.pop()
+	case code.OpNewCell:
+		vm.stack[f.base+u8()] = &object.Cell{Value: object.NULL}
+	case code.OpGetCell:
+		cell, err := expect[*object.Cell](op, vm.stack[f.base+u8()])
+		if err != nil {
+			return false, err
+		}
+		return false, vm.push(cell.Value)
+	case code.OpSetCell:
+		cell, err := expect[*object.Cell](op, vm.stack[f.base+u8()])
+		if err != nil {
+			return false, err
+		}
+		cell.Value = vm.pop()
+	case code.OpGetFree:
+		return false, vm.push(f.cl.Free[u8()].Value)
+	case code.OpSetFree:
+		f.cl.Free[u8()].Value = vm.pop()
+	case code.OpFreeCell:
+		return false, vm.push(f.cl.Free[u8()])
+	case code.OpGetBuiltin:
+		return false, vm.push(object.Builtins[u8()])
+
+	case code.OpIndex:
+		index := vm.pop()
+		left := vm.pop()
+		result, err := object.Index(left, index)
+		if err != nil {
+			return false, err
+		}
+		return false, vm.push(result)
+	case code.OpIter:
+		it, err := object.Iterate(vm.pop())
+		if err != nil {
+			return false, err
+		}
+		return false, vm.push(it)
+	case code.OpIterNext:
+		target := u16()
+		it, err := expect[*object.Iterator](op, vm.stack[vm.sp-1])
+		if err != nil {
+			return false, err
+		}
+		v, ok := it.Next()
+		if !ok {
+			f.ip = target
+			return false, nil
+		}
+		return false, vm.push(v)
+
+	case code.OpClosure:
+		fn, err := expect[*object.CompiledFunction](op, vm.constants[u16()])
+		if err != nil {
+			return false, err
+		}
+		free := make([]*object.Cell, u8())
+		for i := range free {
+			if free[i], err = expect[*object.Cell](op, vm.stack[vm.sp-len(free)+i]); err != nil {
+				return false, err
+			}
+		}
+		for range free {
+			vm.pop()
+		}
+		return false, vm.push(&object.Closure{Fn: fn, Free: free})
+	case code.OpCall:
+		return false, vm.call(u8())
+	case code.OpReturnValue, code.OpReturn:
+		result := object.Object(object.NULL)
+		if op == code.OpReturnValue {
+			result = vm.pop()
+		}
+		if len(vm.frames) == 1 {
+			vm.result = result
+			return true, nil
+		}
+		vm.frames = vm.frames[:len(vm.frames)-1]
+		// the arguments, the locals and the function itself
+		for vm.sp > f.base-1 {
+			vm.pop()
+		}
+		return false, vm.push(result)
+
+	default:
+		panic(fmt.Sprintf("vm: unknown opcode %d", op))
+	}
+	return false, nil
+}
+
+// expect returns o, an operand of op, as a T. Anything else means the
+// compiler laid out the stack or the constants wrong, which is reported
+// as an internal error rather than a crash.
+func expect[T object.Object](op code.Opcode, o object.Object) (T, error) {
+	v, ok := o.(T)
+	if !ok {
+		found := "nil"
+		if o != nil {
+			found = string(o.Type())
+		}
+		name := fmt.Sprintf("opcode %d", op)
+		if def, err := code.Lookup(byte(op)); err == nil {
+			name = def.Name
+		}
+		return v, object.Errorf(i18n.BadOperand, found, name)
+	}
+	return v, nil
+}
+
+// call calls the function below the n arguments on top of the stack.
+func (vm *VM) call(n int) error {
+	callee := vm.stack[vm.sp-1-n]
+	switch callee := callee.(type) {
+	case *object.Closure:
+		fn := callee.Fn
+		if n != fn.NumParameters {
+			name := fn.Name
+			if name == "" {
+				name = "function"
+			}
+			return object.Errorf(i18n.WrongArgCount, name, n, fn.NumParameters)
+		}
+		if len(vm.frames) >= MaxFrames || vm.sp-n+fn.NumLocals > StackSize {
+			return object.Errorf(i18n.StackOverflow)
+		}
+		base := vm.sp - n
+		vm.frames = append(vm.frames, &frame{cl: callee, base: base})
+		// the locals that are not arguments start out null
+		for vm.sp < base+fn.NumLocals {
+			vm.stack[vm.sp] = object.NULL
+			vm.sp++
+		}
+		return nil
+	case *object.Builtin:
+		args := make([]object.Object, n)
+		copy(args, vm.stack[vm.sp-n:vm.sp])
+		result, err := callee.Fn(vm.out, args...)
+		if err != nil {
+			return err
+		}
+		for range n + 1 {
+			vm.pop()
+		}
+		return vm.push(result)
+	default:
+		return object.Errorf(i18n.NotCallable, callee.Type())
+	}
+}
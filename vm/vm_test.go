@@ -0,0 +1,128 @@
+package vm
+
+import (
+	"bytes"
+	"gointer/code"
+	"gointer/compiler"
+	"gointer/i18n"
+	"gointer/lexer"
+	"gointer/object"
+	"gointer/parser"
+	"gointer/resolver"
+	"testing"
+)
+
+// run runs input and returns its result, or the runtime error, along
+// with what it printed.
+func run(t *testing.T, input string, opts ...Option) (string, string) {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if errs := p.Errors(); len(errs) != 0 {
+		t.Fatalf("parse errors in %q: %v", input, errs)
+	}
+	info, errs := resolver.Resolve(program)
+	if len(errs) != 0 {
+		t.Fatalf("resolve errors in %q: %v", input, errs)
+	}
+	bytecode, errs := compiler.Compile(program, info)
+	if len(errs) != 0 {
+		t.Fatalf("compile errors in %q: %v", input, errs)
+	}
+	var out bytes.Buffer
+	vm := New(bytecode, append([]Option{WithOutput(&out)}, opts...)...)
+	if err := vm.Run(); err != nil {
+		return err.Error(), out.String()
+	}
+	return vm.Result().Inspect(), out.String()
+}
+
+func TestLocalizedErrors(t *testing.T) {
+	got, _ := run(t, "1 / 0", WithLang(i18n.Chinese))
+	if expected := "1:1: 整数除以零"; got != expected {
+		t.Errorf("error = %q, want %q", got, expected)
+	}
+}
+
+func TestUnsetVariables(t *testing.T) {
+	// the compiler never reads a variable before setting it, but the VM
+	// must not crash if it does
+	fn := &object.CompiledFunction{
+		Instructions: concat(code.Make(code.OpGetLocal, 1), code.Make(code.OpReturnValue)),
+		NumLocals:    2,
+	}
+	bytecode := &compiler.Bytecode{
+		Instructions: concat(
+			code.Make(code.OpGetGlobal, 0),
+			code.Make(code.OpClosure, 0, 0),
+			code.Make(code.OpCall, 0),
+			code.Make(code.OpEqual),
+			code.Make(code.OpResult),
+		),
+		Constants:  []object.Object{fn},
+		NumGlobals: 1,
+	}
+	vm := New(bytecode)
+	if err := vm.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if got := vm.Result(); got != object.TRUE {
+		t.Errorf("result = %s, want true", got.Inspect())
+	}
+}
+
+func TestBadOperands(t *testing.T) {
+	// the compiler never emits these, but the VM must report them
+	// instead of crashing
+	tests := []struct {
+		constants []object.Object
+		ins       code.Instructions
+		expected  string
+	}{
+		{
+			[]object.Object{&object.Integer{Value: 1}},
+			concat(code.Make(code.OpConstant, 0), code.Make(code.OpIterNext, 0)),
+			"internal error: INTEGER operand of OpIterNext",
+		},
+		{
+			[]object.Object{&object.Integer{Value: 1}},
+			concat(code.Make(code.OpClosure, 0, 0)),
+			"internal error: INTEGER operand of OpClosure",
+		},
+		{
+			[]object.Object{&object.CompiledFunction{}},
+			concat(code.Make(code.OpTrue), code.Make(code.OpClosure, 0, 1)),
+			"internal error: BOOLEAN operand of OpClosure",
+		},
+		{
+			nil,
+			concat(code.Make(code.OpGetCell, 0)),
+			"internal error: nil operand of OpGetCell",
+		},
+		{
+			nil,
+			concat(code.Make(code.OpTrue), code.Make(code.OpTrue), code.Make(code.OpSetCell, 0)),
+			"internal error: BOOLEAN operand of OpSetCell",
+		},
+	}
+	for _, tt := range tests {
+		vm := New(&compiler.Bytecode{Instructions: tt.ins, Constants: tt.constants})
+		err := vm.Run()
+		e, ok := err.(*Error)
+		if !ok {
+			t.Errorf("%s: error = %v, want an *Error", tt.ins, err)
+			continue
+		}
+		if e.Msg != tt.expected {
+			t.Errorf("%s: error = %q, want %q", tt.ins, e.Msg, tt.expected)
+		}
+	}
+}
+
+func concat(ins ...[]byte) code.Instructions {
+	var out code.Instructions
+	for _, i := range ins {
+		out = append(out, i...)
+	}
+	return out
+}